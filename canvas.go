@@ -1,19 +1,21 @@
 package canvas
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
+	"html"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"io/ioutil"
 	"math"
 	"strings"
 
 	"golang.org/x/image/draw"
 	"golang.org/x/image/math/f64"
-	"golang.org/x/image/vector"
 )
 
 const mmPerPt = 0.3527777777777778
@@ -27,12 +29,13 @@ type Canvas struct {
 	layers []layer
 	fonts  map[*Font]bool
 	drawState
-	stack []drawState
+	stack  []drawState
+	groups []groupStart
 }
 
 // New returns a new Canvas of given width and height in mm.
 func New(w, h float64) *Canvas {
-	return &Canvas{w, h, []layer{}, map[*Font]bool{}, defaultDrawState, nil}
+	return &Canvas{w, h, []layer{}, map[*Font]bool{}, defaultDrawState, nil, nil}
 }
 
 // PushState saves the current draw state, so that it can be popped later on.
@@ -68,12 +71,46 @@ func (c *Canvas) ComposeView(m Matrix) {
 func (c *Canvas) SetFillColor(col color.Color) {
 	r, g, b, a := col.RGBA()
 	c.fillColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.fillGradient = nil
+	c.fillPattern = nil
 }
 
 // SetStrokeColor sets the color to be used for stroking operations.
 func (c *Canvas) SetStrokeColor(col color.Color) {
 	r, g, b, a := col.RGBA()
 	c.strokeColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.strokeGradient = nil
+	c.strokePattern = nil
+}
+
+// SetFillGradient sets the gradient to be used for filling operations, replacing the fill color. The gradient is specified in the coordinate system of the drawn path, ie. it is transformed by the position and view of DrawPath.
+func (c *Canvas) SetFillGradient(gradient Gradient) {
+	c.fillGradient = gradient
+	c.fillPattern = nil
+}
+
+// SetStrokeGradient sets the gradient to be used for stroking operations, replacing the stroke color. The gradient is specified in the coordinate system of the drawn path, ie. it is transformed by the position and view of DrawPath.
+func (c *Canvas) SetStrokeGradient(gradient Gradient) {
+	c.strokeGradient = gradient
+	c.strokePattern = nil
+}
+
+// SetFillPattern sets the pattern to be used for filling operations, replacing the fill color or gradient. The pattern is specified in the coordinate system of the drawn path, ie. it is transformed by the position and view of DrawPath. The contents of the pattern must be drawn before writing the canvas.
+func (c *Canvas) SetFillPattern(pattern *Pattern) {
+	for font := range pattern.fonts {
+		c.fonts[font] = true
+	}
+	c.fillPattern = pattern
+	c.fillGradient = nil
+}
+
+// SetStrokePattern sets the pattern to be used for stroking operations, replacing the stroke color or gradient. The pattern is specified in the coordinate system of the drawn path, ie. it is transformed by the position and view of DrawPath. The contents of the pattern must be drawn before writing the canvas.
+func (c *Canvas) SetStrokePattern(pattern *Pattern) {
+	for font := range pattern.fonts {
+		c.fonts[font] = true
+	}
+	c.strokePattern = pattern
+	c.strokeGradient = nil
 }
 
 // SetStrokeWidth sets the width in mm for stroking operations.
@@ -97,14 +134,33 @@ func (c *Canvas) SetDashes(dashOffset float64, dashes ...float64) {
 	c.dashes = dashes
 }
 
+// SetClipPath sets the clipping path in the current view, only the parts of subsequent drawing operations that fall inside the path will be visible. The fill rule of the path is determined by FillRule. The clipping path is intersected with any clipping path that was set before, use PushState and PopState to restore a previous clipping path.
+func (c *Canvas) SetClipPath(path *Path) {
+	clip := clipPath{path: path.Transform(c.m), fillRule: FillRule}
+	c.clips = append(c.clips[:len(c.clips):len(c.clips)], clip)
+}
+
+// ResetClipPath removes the clipping path so that drawing operations are no longer clipped. Masks set by SetMask remain.
+func (c *Canvas) ResetClipPath() {
+	var clips []clipPath
+	for _, clip := range c.clips {
+		if clip.mask != nil {
+			clips = append(clips, clip)
+		}
+	}
+	c.clips = clips
+}
+
 // DrawPath draws a path at position (x,y) using the current draw state.
 func (c *Canvas) DrawPath(x, y float64, path *Path) {
-	if c.fillColor.A == 0 && (c.strokeColor.A == 0 || c.strokeWidth == 0.0) {
+	if !c.hasFill() && !c.hasStroke() {
 		return
 	}
 	if !path.Empty() {
-		path = path.Transform(Identity.Translate(x, y).Mul(c.m))
+		m := Identity.Translate(x, y).Mul(c.m)
+		path = path.Transform(m)
 		c.drawState.fillRule = FillRule
+		c.drawState.gradientM = m
 		c.layers = append(c.layers, pathLayer{path, c.drawState})
 	}
 }
@@ -115,7 +171,7 @@ func (c *Canvas) DrawText(x, y float64, text *Text) {
 		for font := range text.fonts {
 			c.fonts[font] = true
 		}
-		c.layers = append(c.layers, textLayer{text, Identity.Translate(x, y).Mul(c.m)})
+		c.layers = append(c.layers, textLayer{text, Identity.Translate(x, y).Mul(c.m), c.clips})
 	}
 }
 
@@ -128,13 +184,68 @@ const (
 	Lossy
 )
 
+// JPEGQuality is the quality of the JPEG encoding of Lossy images, ranging from 1 to 100. Higher values give better images but bigger files.
+var JPEGQuality = jpeg.DefaultQuality
+
+// JPEGImage is a decoded JPEG image that keeps its original data, so that it is embedded in SVG, PDF and EPS output without re-encoding, regardless of the image encoding.
+type JPEGImage struct {
+	image.Image
+	data []byte
+}
+
+// NewJPEGImage decodes a JPEG image from r and keeps the original data.
+func NewJPEGImage(r io.Reader) (*JPEGImage, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return &JPEGImage{img, data}, nil
+}
+
+// jpegData returns the original data of a JPEG image and whether it is grayscale. CMYK images are not passed through since their colors are often stored inverted.
+func jpegData(img image.Image) ([]byte, bool, bool) {
+	if jpg, ok := img.(*JPEGImage); ok {
+		switch jpg.Image.(type) {
+		case *image.Gray:
+			return jpg.data, true, true
+		case *image.YCbCr:
+			return jpg.data, false, true
+		}
+	}
+	return nil, false, false
+}
+
 // DrawImage draws an image at position (x,y), using an image encoding (Lossy or Lossless) and DPM (dots-per-millimeter). A higher DPM will draw a smaller image.
 func (c *Canvas) DrawImage(x, y float64, img image.Image, enc ImageEncoding, dpm float64) {
 	if img.Bounds().Size().Eq(image.Point{}) {
 		return
 	}
 	m := Identity.Translate(x, y).Mul(c.m).Scale(1/dpm, 1/dpm)
-	c.layers = append(c.layers, imageLayer{img, enc, m})
+	c.layers = append(c.layers, imageLayer{img, enc, m, c.clips})
+}
+
+// Link is the target of a clickable area, which is either an external URI or, when URI is empty, a destination within the document given by the zero-based page number and the position (X,Y) on that page that is shown at the top-left of the viewer.
+type Link struct {
+	URI  string
+	Page int
+	X, Y float64
+}
+
+// DrawLink adds a clickable area at position (x,y) that follows link, where area is the path that encloses the clickable area. Links are written to PDF, to EPS and PostScript as pdfmark annotations, and to SVG by wrapping the elements within the area in a link. Links to pages in SVG refer to the element with id "page" followed by the zero-based page number, which is set on the pages written by Document.WriteSVG.
+func (c *Canvas) DrawLink(x, y float64, area *Path, link Link) {
+	if !area.Empty() {
+		area = area.Transform(Identity.Translate(x, y).Mul(c.m))
+		c.layers = append(c.layers, linkLayer{area, link, c.clips})
+	}
+}
+
+// DrawLinkRect adds a clickable rectangle that follows link, see DrawLink.
+func (c *Canvas) DrawLinkRect(rect Rect, link Link) {
+	c.DrawLink(0.0, 0.0, rect.ToPath(), link)
 }
 
 ////////////////////////////////////////////////////////////////
@@ -150,23 +261,56 @@ func (c *Canvas) Fit(margin float64) {
 	for _, layer := range c.layers[1:] {
 		rect = rect.Add(layer.Bounds())
 	}
+	dx, dy := -rect.X+margin, -rect.Y+margin
+	clipCache := map[*Path]*Path{} // keep clipping paths shared between layers
 	for i, layer := range c.layers {
-		switch l := layer.(type) {
-		case pathLayer:
-			c.layers[i] = pathLayer{l.path.Translate(-rect.X+margin, -rect.Y+margin), l.drawState}
-		case textLayer:
-			c.layers[i] = textLayer{l.text, Identity.Translate(-rect.X+margin, -rect.Y+margin).Mul(l.m)}
-		case imageLayer:
-			c.layers[i] = imageLayer{l.img, l.enc, Identity.Translate(-rect.X+margin, -rect.Y+margin).Mul(l.m)}
-		}
+		c.layers[i] = translateLayer(layer, dx, dy, clipCache)
 	}
 	c.W = rect.W + 2*margin
 	c.H = rect.H + 2*margin
 }
 
+// translateLayer returns the layer translated by (dx,dy).
+func translateLayer(l layer, dx, dy float64, clipCache map[*Path]*Path) layer {
+	switch l := l.(type) {
+	case pathLayer:
+		l.gradientM = Identity.Translate(dx, dy).Mul(l.gradientM)
+		l.clips = translateClips(l.clips, dx, dy, clipCache)
+		return pathLayer{l.path.Translate(dx, dy), l.drawState}
+	case textLayer:
+		return textLayer{l.text, Identity.Translate(dx, dy).Mul(l.m), translateClips(l.clips, dx, dy, clipCache)}
+	case imageLayer:
+		return imageLayer{l.img, l.enc, Identity.Translate(dx, dy).Mul(l.m), translateClips(l.clips, dx, dy, clipCache)}
+	case linkLayer:
+		return linkLayer{l.area.Translate(dx, dy), l.link, translateClips(l.clips, dx, dy, clipCache)}
+	case symbolLayer:
+		return symbolLayer{l.symbol, Identity.Translate(dx, dy).Mul(l.m), translateClips(l.clips, dx, dy, clipCache)}
+	case textPathLayer:
+		l.m = Identity.Translate(dx, dy).Mul(l.m)
+		l.clips = translateClips(l.clips, dx, dy, clipCache)
+		return l
+	case groupLayer:
+		layers := make([]layer, len(l.layers))
+		for i, child := range l.layers {
+			layers[i] = translateLayer(child, dx, dy, clipCache)
+		}
+		return groupLayer{layers, l.opacity, l.blendMode}
+	}
+	return l
+}
+
 // WriteSVG writes the stored drawing operations in Canvas in the SVG file format.
 func (c *Canvas) WriteSVG(w io.Writer) {
-	fmt.Fprintf(w, `<svg version="1.1" width="%v" height="%v" viewBox="0 0 %v %v" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`, dec(c.W), dec(c.H), dec(c.W), dec(c.H))
+	c.writeSVG(w, "")
+}
+
+// writeSVG writes the canvas as an SVG file, where the root element gets the given id if not empty.
+func (c *Canvas) writeSVG(w io.Writer, id string) {
+	fmt.Fprintf(w, `<svg version="1.1" width="%v" height="%v" viewBox="0 0 %v %v" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"`, dec(c.W), dec(c.H), dec(c.W), dec(c.H))
+	if id != "" {
+		fmt.Fprintf(w, ` id="%s"`, id)
+	}
+	fmt.Fprintf(w, `>`)
 	if len(c.fonts) > 0 {
 		fmt.Fprintf(w, "<defs><style>")
 		for f := range c.fonts {
@@ -179,67 +323,323 @@ func (c *Canvas) WriteSVG(w io.Writer) {
 		}
 		fmt.Fprintf(w, "\n</style></defs>")
 	}
-	for _, l := range c.layers {
-		l.WriteSVG(w, c.H)
-	}
+	writeSVGLayers(newSVGWriter(w, c.H), c.layers)
 	fmt.Fprintf(w, "</svg>")
 }
 
-// WritePDF writes the stored drawing operations in Canvas in the PDF file format.
-func (c *Canvas) WritePDF(w io.Writer) error {
-	pdf := newPDFWriter(w)
-	pdfpage := pdf.NewPage(c.W, c.H)
-	for _, l := range c.layers {
-		l.WritePDF(pdfpage)
+// writeSVGLayers writes the layers, grouping the layers that have the same clipping paths.
+func writeSVGLayers(svg *svgWriter, layers []layer) {
+	layers = svgLinkLayers(layers)
+	clipGroups(layers, func(clips []clipPath, layers []layer) {
+		paths := []clipPath{}
+		for _, clip := range clips {
+			if clip.mask != nil {
+				fmt.Fprintf(svg, `<g mask="url(#%s)">`, svg.writeMask(clip.mask, clip.m))
+			} else {
+				paths = append(paths, clip)
+			}
+		}
+		if 0 < len(paths) {
+			fmt.Fprintf(svg, `<g clip-path="url(#%s)">`, svg.writeClipPaths(paths))
+		}
+		for _, l := range layers {
+			l.WriteSVG(svg)
+		}
+		if 0 < len(paths) {
+			fmt.Fprintf(svg, "</g>")
+		}
+		for i := len(paths); i < len(clips); i++ {
+			fmt.Fprintf(svg, "</g>")
+		}
+	})
+}
+
+// PDFOptions are the options for writing a canvas in the PDF file format.
+type PDFOptions struct {
+	// PDFA enables PDF/A-2b conformance, which is suitable for long-term archiving. It embeds an sRGB output intent, XMP metadata and a document ID, writes all fonts embedded with a ToUnicode map, and disables image interpolation.
+	// Writing returns an error naming the violation when the output cannot conform, which is checked for fonts that cannot be embedded and for text using glyphs that are missing from its font.
+	// Transparency is not checked, since the constructs that are written are all allowed by PDF/A-2: opacity, soft masks for masks and translucent gradients and images, and the standard blend modes, with transparency groups blending in DeviceRGB which matches the sRGB output intent. Transfer functions and other blend modes are never written.
+	PDFA bool
+}
+
+// WritePDF writes the stored drawing operations in Canvas in the PDF file format. Options may be passed to enable PDF/A conformance.
+func (c *Canvas) WritePDF(w io.Writer, opts ...PDFOptions) error {
+	pdfa := false
+	for _, opt := range opts {
+		pdfa = pdfa || opt.PDFA
 	}
+	pdf := newPDFWriter(w, pdfa)
+	c.writePDFPage(pdf)
 	return pdf.Close()
 }
 
+// writePDFPage writes the stored drawing operations as a new page of the PDF.
+func (c *Canvas) writePDFPage(pdf *pdfWriter) {
+	writePDFLayers(pdf.NewPage(c.W, c.H), c.layers)
+}
+
+// writePDFLayers writes the layers, applying the clipping paths to each group of layers that have the same clipping paths.
+func writePDFLayers(pdfpage *pdfPageWriter, layers []layer) {
+	clipGroups(layers, func(clips []clipPath, layers []layer) {
+		if 0 < len(clips) {
+			pdfpage.Push()
+			for _, clip := range clips {
+				if clip.mask != nil {
+					pdfpage.SetMask(clip.mask, clip.m)
+				} else {
+					pdfpage.Clip(clip.path.ToPDF(), clip.fillRule)
+				}
+			}
+		}
+		for _, l := range layers {
+			l.WritePDF(pdfpage)
+		}
+		if 0 < len(clips) {
+			pdfpage.Pop()
+		}
+	})
+}
+
 // WriteEPS writes the stored drawing operations in Canvas in the EPS file format.
-// Be aware that EPS does not support transparency of colors.
+// Be aware that EPS does not support transparency of colors. As for WriteSVG, errors returned by w are not reported.
 func (c *Canvas) WriteEPS(w io.Writer) {
+	_ = c.writeEPS(w)
+}
+
+// writeEPS writes the canvas as an EPS file and returns the first error returned by w.
+func (c *Canvas) writeEPS(w io.Writer) error {
 	eps := newEPSWriter(w, c.W, c.H)
-	for _, l := range c.layers {
-		eps.Write([]byte("\n"))
-		l.WriteEPS(eps)
-	}
+	c.writePS(eps)
+	return eps.Close()
 }
 
-// WriteImage writes the stored drawing operations in Canvas as a rasterized image with given DPM (dots-per-millimeter). Higher DPM will result in bigger images.
+// writePS writes the stored drawing operations as PostScript, which is shared between EPS and multi-page PostScript output.
+func (c *Canvas) writePS(eps *epsWriter) {
+	writeEPSLayers(eps, c.layers)
+}
+
+// writeEPSLayers writes the layers, applying the clipping paths to each group of layers that have the same clipping paths. Masks are not supported and are ignored.
+func writeEPSLayers(eps *epsWriter, layers []layer) {
+	clipGroups(layers, func(clips []clipPath, layers []layer) {
+		if 0 < len(clips) {
+			eps.Write([]byte("\n"))
+			eps.Push()
+			for _, clip := range clips {
+				if clip.mask == nil {
+					eps.Clip(clip.path.ToPS(), clip.fillRule)
+				}
+			}
+		}
+		for _, l := range layers {
+			eps.Write([]byte("\n"))
+			l.WriteEPS(eps)
+		}
+		if 0 < len(clips) {
+			eps.Pop()
+		}
+	})
+}
+
+// WriteImage writes the stored drawing operations in Canvas as a rasterized image with given DPM (dots-per-millimeter) on a white background. Higher DPM will result in bigger images.
 func (c *Canvas) WriteImage(dpm float64) *image.RGBA {
 	img := image.NewRGBA(image.Rect(0.0, 0.0, int(c.W*dpm+0.5), int(c.H*dpm+0.5)))
 	draw.Draw(img, img.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
-	for _, l := range c.layers {
-		l.WriteImage(img, dpm)
-	}
+	c.WriteImageTo(img, dpm)
 	return img
 }
 
+// WriteImageTo draws the stored drawing operations in Canvas onto an existing image with given DPM (dots-per-millimeter), with the top-left corner of the canvas at the top-left corner of the bounds of dst. The drawing is composited over the existing contents of dst, so that a newly allocated image results in a transparent background.
+func (c *Canvas) WriteImageTo(dst draw.Image, dpm float64) {
+	c.WriteImageRect(dst, dpm, Rect{0.0, 0.0, c.W, c.H}, dst.Bounds().Min)
+}
+
+// WriteImageRect draws the part of the Canvas within rect onto an existing image with given DPM (dots-per-millimeter), with the top-left corner of rect at offset in the pixel coordinates of dst. Only the pixels of dst that are covered by rect are changed, which allows rendering a canvas in tiles.
+func (c *Canvas) WriteImageRect(dst draw.Image, dpm float64, rect Rect, offset image.Point) {
+	bounds := image.Rect(offset.X, offset.Y, offset.X+int(rect.W*dpm+0.5), offset.Y+int(rect.H*dpm+0.5))
+	bounds = bounds.Intersect(dst.Bounds())
+	if bounds.Empty() {
+		return
+	}
+	if sub, ok := dst.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		if img, ok := sub.SubImage(bounds).(draw.Image); ok {
+			dst = img
+		} else {
+			dst = boundedImage{dst, bounds}
+		}
+	} else {
+		dst = boundedImage{dst, bounds}
+	}
+
+	// m maps canvas coordinates to pixel coordinates of dst
+	m := Identity.Translate(float64(offset.X), float64(offset.Y)).Scale(dpm, -dpm).Translate(-rect.X, -(rect.Y + rect.H))
+	writeImageLayers(dst, c.layers, m)
+}
+
+// writeImageLayers draws the layers onto dst, with m mapping canvas coordinates to pixel coordinates, applying the clipping paths to each group of layers that have the same clipping paths.
+func writeImageLayers(dst draw.Image, layers []layer, m Matrix) {
+	bounds := dst.Bounds()
+	clipGroups(layers, func(clips []clipPath, layers []layer) {
+		img := dst
+		if 0 < len(clips) {
+			img = image.NewRGBA(bounds)
+		}
+		for _, l := range layers {
+			l.WriteImage(img, m)
+		}
+		if 0 < len(clips) {
+			mask := clipMask(clips, bounds, m)
+			draw.DrawMask(dst, bounds, img, bounds.Min, mask, image.Point{}, draw.Over)
+		}
+	})
+}
+
+// layersBounds returns the union of the bounds of the layers.
+func layersBounds(layers []layer) Rect {
+	if len(layers) == 0 {
+		return Rect{}
+	}
+	bounds := layers[0].Bounds()
+	for _, l := range layers[1:] {
+		bounds = bounds.Add(l.Bounds())
+	}
+	return bounds
+}
+
+// boundedImage restricts drawing to an image to the given bounds, for images that have no SubImage method.
+type boundedImage struct {
+	draw.Image
+	bounds image.Rectangle
+}
+
+func (img boundedImage) Bounds() image.Rectangle {
+	return img.bounds
+}
+
 ////////////////////////////////////////////////////////////////
 
 type layer interface {
 	Bounds() Rect
-	WriteSVG(io.Writer, float64)
+	WriteSVG(*svgWriter)
 	WritePDF(*pdfPageWriter)
 	WriteEPS(*epsWriter)
-	WriteImage(*image.RGBA, float64)
+	WriteImage(draw.Image, Matrix)
+	clipPaths() []clipPath
+}
+
+// clipPath is a clipping path in canvas coordinates, or a mask when mask is set, with m mapping mask coordinates to canvas coordinates.
+type clipPath struct {
+	path     *Path
+	fillRule FillRuleType
+	mask     *Mask
+	m        Matrix
+}
+
+func translateClips(clips []clipPath, dx, dy float64, cache map[*Path]*Path) []clipPath {
+	if len(clips) == 0 {
+		return clips
+	}
+	translated := make([]clipPath, len(clips))
+	for i, clip := range clips {
+		if clip.mask != nil {
+			translated[i] = clipPath{mask: clip.mask, m: Identity.Translate(dx, dy).Mul(clip.m)}
+			continue
+		} else if _, ok := cache[clip.path]; !ok {
+			cache[clip.path] = clip.path.Translate(dx, dy)
+		}
+		translated[i] = clipPath{path: cache[clip.path], fillRule: clip.fillRule}
+	}
+	return translated
+}
+
+// clipGroups calls f for each run of consecutive layers that share the same clipping paths. Layers that are clipped by an empty path or masked by an empty mask are invisible and are skipped.
+func clipGroups(layers []layer, f func([]clipPath, []layer)) {
+	sameClips := func(a, b []clipPath) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	i := 0
+	for j := 1; j <= len(layers); j++ {
+		if j == len(layers) || !sameClips(layers[i].clipPaths(), layers[j].clipPaths()) {
+			clips := layers[i].clipPaths()
+			visible := true
+			for _, clip := range clips {
+				if clip.mask != nil && len(clip.mask.layers) == 0 || clip.mask == nil && clip.path.Empty() {
+					visible = false
+				}
+			}
+			if visible {
+				f(clips, layers[i:j])
+			}
+			i = j
+		}
+	}
+}
+
+// clipMask returns the alpha mask of the intersection of the clipping paths and masks over the given bounds, with m mapping canvas coordinates to pixel coordinates.
+func clipMask(clips []clipPath, bounds image.Rectangle, m Matrix) *image.Alpha {
+	m = Identity.Translate(-float64(bounds.Min.X), -float64(bounds.Min.Y)).Mul(m)
+	var mask *image.Alpha
+	for _, clip := range clips {
+		var clipMask *image.Alpha
+		if clip.mask != nil {
+			clipMask = clip.mask.alpha(bounds.Dx(), bounds.Dy(), m.Mul(clip.m))
+		} else {
+			ras := NewRasterizer(bounds.Dx(), bounds.Dy(), AntiAliasing)
+			ras.AddPath(clip.path, m)
+			clipMask = ras.Mask(clip.fillRule)
+		}
+		if mask == nil {
+			mask = clipMask
+		} else {
+			for k := range mask.Pix {
+				mask.Pix[k] = uint8(uint32(mask.Pix[k]) * uint32(clipMask.Pix[k]) / 255)
+			}
+		}
+	}
+	if mask == nil {
+		mask = image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	}
+	return mask
 }
 
 type drawState struct {
-	m                      Matrix
-	fillColor, strokeColor color.RGBA
-	strokeWidth            float64
-	strokeCapper           Capper
-	strokeJoiner           Joiner
-	dashOffset             float64
-	dashes                 []float64
-	fillRule               FillRuleType
+	m                            Matrix
+	fillColor, strokeColor       color.RGBA
+	fillGradient, strokeGradient Gradient
+	fillPattern, strokePattern   *Pattern
+	gradientM                    Matrix // maps gradient and pattern coordinates to canvas coordinates
+	strokeWidth                  float64
+	strokeCapper                 Capper
+	strokeJoiner                 Joiner
+	dashOffset                   float64
+	dashes                       []float64
+	fillRule                     FillRuleType
+	clips                        []clipPath
+}
+
+func (s drawState) hasFill() bool {
+	return s.fillGradient != nil || s.fillPattern != nil || s.fillColor.A != 0
+}
+
+func (s drawState) hasStroke() bool {
+	return (s.strokeGradient != nil || s.strokePattern != nil || s.strokeColor.A != 0) && 0.0 < s.strokeWidth
 }
 
 var defaultDrawState = drawState{
 	m:            Identity,
 	fillColor:    Black,
 	strokeColor:  Transparent,
+	gradientM:    Identity,
 	strokeWidth:  1.0,
 	strokeCapper: ButtCapper,
 	strokeJoiner: MiterJoiner,
@@ -257,7 +657,7 @@ type pathLayer struct {
 
 func (l pathLayer) Bounds() Rect {
 	bounds := l.path.Bounds()
-	if l.strokeColor.A != 0 && 0.0 < l.strokeWidth {
+	if l.hasStroke() {
 		bounds.X -= l.strokeWidth / 2.0
 		bounds.Y -= l.strokeWidth / 2.0
 		bounds.W += l.strokeWidth
@@ -266,12 +666,13 @@ func (l pathLayer) Bounds() Rect {
 	return bounds
 }
 
-func (l pathLayer) WriteSVG(w io.Writer, h float64) {
-	fill := l.fillColor.A != 0
-	stroke := l.strokeColor.A != 0 && 0.0 < l.strokeWidth
+func (l pathLayer) clipPaths() []clipPath {
+	return l.clips
+}
 
-	p := l.path.Transform(Identity.Translate(0.0, h).ReflectY())
-	fmt.Fprintf(w, `<path d="%s`, p.ToSVG())
+func (l pathLayer) WriteSVG(w *svgWriter) {
+	fill := l.hasFill()
+	stroke := l.hasStroke()
 
 	strokeUnsupported := false
 	if arcs, ok := l.strokeJoiner.(arcsJoiner); ok && math.IsNaN(arcs.limit) {
@@ -284,10 +685,21 @@ func (l pathLayer) WriteSVG(w io.Writer, h float64) {
 		}
 	}
 
+	fillPaint, strokePaint := "", ""
+	if fill {
+		fillPaint = l.svgPaint(w, l.fillColor, l.fillGradient, l.fillPattern)
+	}
+	if stroke {
+		strokePaint = l.svgPaint(w, l.strokeColor, l.strokeGradient, l.strokePattern)
+	}
+
+	p := l.path.Transform(Identity.Translate(0.0, w.height).ReflectY())
+	fmt.Fprintf(w, `<path d="%s`, p.ToSVG())
+
 	if !stroke {
 		if fill {
-			if l.fillColor != Black {
-				fmt.Fprintf(w, `" fill="%v`, cssColor(l.fillColor))
+			if fillPaint != "#000" {
+				fmt.Fprintf(w, `" fill="%v`, fillPaint)
 			}
 			if l.fillRule == EvenOdd {
 				fmt.Fprintf(w, `" fill-rule="evenodd`)
@@ -298,8 +710,8 @@ func (l pathLayer) WriteSVG(w io.Writer, h float64) {
 	} else {
 		style := &strings.Builder{}
 		if fill {
-			if l.fillColor != Black {
-				fmt.Fprintf(style, ";fill:%v", cssColor(l.fillColor))
+			if fillPaint != "#000" {
+				fmt.Fprintf(style, ";fill:%v", fillPaint)
 			}
 			if l.fillRule == EvenOdd {
 				fmt.Fprintf(style, ";fill-rule:evenodd")
@@ -308,7 +720,7 @@ func (l pathLayer) WriteSVG(w io.Writer, h float64) {
 			fmt.Fprintf(style, ";fill:none")
 		}
 		if stroke && !strokeUnsupported {
-			fmt.Fprintf(style, `;stroke:%v`, cssColor(l.strokeColor))
+			fmt.Fprintf(style, `;stroke:%v`, strokePaint)
 			if l.strokeWidth != 1.0 {
 				fmt.Fprintf(style, ";stroke-width:%v", dec(l.strokeWidth))
 			}
@@ -360,8 +772,8 @@ func (l pathLayer) WriteSVG(w io.Writer, h float64) {
 		}
 		p = p.Stroke(l.strokeWidth, l.strokeCapper, l.strokeJoiner)
 		fmt.Fprintf(w, `<path d="%s`, p.ToSVG())
-		if l.strokeColor != Black {
-			fmt.Fprintf(w, `" fill="%v`, cssColor(l.strokeColor))
+		if strokePaint != "#000" {
+			fmt.Fprintf(w, `" fill="%v`, strokePaint)
 		}
 		if l.fillRule == EvenOdd {
 			fmt.Fprintf(w, `" fill-rule="evenodd`)
@@ -370,9 +782,33 @@ func (l pathLayer) WriteSVG(w io.Writer, h float64) {
 	}
 }
 
+// svgPaint returns the SVG paint value for a color, gradient or pattern, writing out the gradient or pattern definition if needed.
+func (l pathLayer) svgPaint(w *svgWriter, col color.RGBA, gradient Gradient, pattern *Pattern) string {
+	if gradient != nil {
+		return fmt.Sprintf("url(#%s)", w.writeGradient(gradient, l.gradientM))
+	} else if pattern != nil {
+		return fmt.Sprintf("url(#%s)", w.writePattern(pattern, l.gradientM.Mul(pattern.M)))
+	}
+	return cssColor(col).String()
+}
+
+// strokeUnsupported returns true if the stroke cannot be expressed by the line caps and joins of PDF and PostScript, which don't support the arcs joiner, miter joiner (not clipped), or miter-clip joiner with non-bevel fallback.
+func (l pathLayer) strokeUnsupported() bool {
+	if _, ok := l.strokeJoiner.(arcsJoiner); ok {
+		return true
+	} else if miter, ok := l.strokeJoiner.(miterJoiner); ok {
+		if math.IsNaN(miter.limit) {
+			return true
+		} else if _, ok := miter.gapJoiner.(bevelJoiner); !ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (l pathLayer) WritePDF(w *pdfPageWriter) {
-	fill := l.fillColor.A != 0
-	stroke := l.strokeColor.A != 0 && 0.0 < l.strokeWidth
+	fill := l.hasFill()
+	stroke := l.hasStroke()
 
 	closed := false
 	data := l.path.ToPDF()
@@ -381,48 +817,19 @@ func (l pathLayer) WritePDF(w *pdfPageWriter) {
 		closed = true
 	}
 
-	differentAlpha := fill && stroke && l.fillColor.A != l.strokeColor.A
+	// paint fill and stroke separately when they require a different alpha, a gradient or a pattern
+	separate := fill && stroke && (l.fillColor.A != l.strokeColor.A || l.fillGradient != nil || l.strokeGradient != nil || l.fillPattern != nil || l.strokePattern != nil)
 
 	// TODO: (PDF) does not support connecting first and last dashes if path is closed
-	// PDFs don't support the arcs joiner, miter joiner (not clipped), or miter-clip joiner with non-bevel fallback
-	strokeUnsupported := false
-	if _, ok := l.strokeJoiner.(arcsJoiner); ok {
-		strokeUnsupported = true
-	} else if miter, ok := l.strokeJoiner.(miterJoiner); ok {
-		if math.IsNaN(miter.limit) {
-			strokeUnsupported = true
-		} else if _, ok := miter.gapJoiner.(bevelJoiner); !ok {
-			strokeUnsupported = true
-		}
-	}
+	strokeUnsupported := l.strokeUnsupported()
 
 	if !stroke || !strokeUnsupported {
 		if fill && !stroke {
-			w.SetFillColor(l.fillColor)
-			w.Write([]byte(" "))
-			w.Write([]byte(data))
-			w.Write([]byte(" f"))
-			if l.fillRule == EvenOdd {
-				w.Write([]byte("*"))
-			}
+			l.writePDFFill(w, data, l.fillColor, l.fillGradient, l.fillPattern)
 		} else if !fill && stroke {
-			w.SetStrokeColor(l.strokeColor)
-			w.SetLineWidth(l.strokeWidth)
-			w.SetLineCap(l.strokeCapper)
-			w.SetLineJoin(l.strokeJoiner)
-			w.SetDashes(l.dashOffset, l.dashes)
-			w.Write([]byte(" "))
-			w.Write([]byte(data))
-			if closed {
-				w.Write([]byte(" s"))
-			} else {
-				w.Write([]byte(" S"))
-			}
-			if l.fillRule == EvenOdd {
-				w.Write([]byte("*"))
-			}
+			l.writePDFStroke(w, data, closed)
 		} else if fill && stroke {
-			if !differentAlpha {
+			if !separate {
 				w.SetFillColor(l.fillColor)
 				w.SetStrokeColor(l.strokeColor)
 				w.SetLineWidth(l.strokeWidth)
@@ -440,41 +847,14 @@ func (l pathLayer) WritePDF(w *pdfPageWriter) {
 					w.Write([]byte("*"))
 				}
 			} else {
-				w.SetFillColor(l.fillColor)
-				w.Write([]byte(" "))
-				w.Write([]byte(data))
-				w.Write([]byte(" f"))
-				if l.fillRule == EvenOdd {
-					w.Write([]byte("*"))
-				}
-
-				w.SetStrokeColor(l.strokeColor)
-				w.SetLineWidth(l.strokeWidth)
-				w.SetLineCap(l.strokeCapper)
-				w.SetLineJoin(l.strokeJoiner)
-				w.SetDashes(l.dashOffset, l.dashes)
-				w.Write([]byte(" "))
-				w.Write([]byte(data))
-				if closed {
-					w.Write([]byte(" s"))
-				} else {
-					w.Write([]byte(" S"))
-				}
-				if l.fillRule == EvenOdd {
-					w.Write([]byte("*"))
-				}
+				l.writePDFFill(w, data, l.fillColor, l.fillGradient, l.fillPattern)
+				l.writePDFStroke(w, data, closed)
 			}
 		}
 	} else {
 		// stroke && strokeUnsupported
 		if fill {
-			w.SetFillColor(l.fillColor)
-			w.Write([]byte(" "))
-			w.Write([]byte(data))
-			w.Write([]byte(" f"))
-			if l.fillRule == EvenOdd {
-				w.Write([]byte("*"))
-			}
+			l.writePDFFill(w, data, l.fillColor, l.fillGradient, l.fillPattern)
 		}
 
 		// stroke settings unsupported by PDF, draw stroke explicitly
@@ -483,62 +863,260 @@ func (l pathLayer) WritePDF(w *pdfPageWriter) {
 			strokePath = strokePath.Dash(l.dashOffset, l.dashes...)
 		}
 		strokePath = strokePath.Stroke(l.strokeWidth, l.strokeCapper, l.strokeJoiner)
+		l.writePDFFill(w, strokePath.ToPDF(), l.strokeColor, l.strokeGradient, l.strokePattern)
+	}
+}
 
-		w.SetFillColor(l.strokeColor)
-		w.Write([]byte(" "))
-		w.Write([]byte(strokePath.ToPDF()))
-		w.Write([]byte(" f"))
-		if l.fillRule == EvenOdd {
-			w.Write([]byte("*"))
-		}
+func (l pathLayer) writePDFFill(w *pdfPageWriter, data string, col color.RGBA, gradient Gradient, pattern *Pattern) {
+	pushed := false
+	if gradient != nil {
+		pushed = w.SetFillGradient(gradient, l.gradientM, l.Bounds())
+	} else if pattern != nil {
+		w.SetFillPattern(pattern, l.gradientM.Mul(pattern.M))
+	} else {
+		w.SetFillColor(col)
+	}
+	w.Write([]byte(" "))
+	w.Write([]byte(data))
+	w.Write([]byte(" f"))
+	if l.fillRule == EvenOdd {
+		w.Write([]byte("*"))
+	}
+	if pushed {
+		w.Pop()
+	}
+}
+
+func (l pathLayer) writePDFStroke(w *pdfPageWriter, data string, closed bool) {
+	pushed := false
+	if l.strokeGradient != nil {
+		pushed = w.SetStrokeGradient(l.strokeGradient, l.gradientM, l.Bounds())
+	} else if l.strokePattern != nil {
+		w.SetStrokePattern(l.strokePattern, l.gradientM.Mul(l.strokePattern.M))
+	} else {
+		w.SetStrokeColor(l.strokeColor)
+	}
+	w.SetLineWidth(l.strokeWidth)
+	w.SetLineCap(l.strokeCapper)
+	w.SetLineJoin(l.strokeJoiner)
+	w.SetDashes(l.dashOffset, l.dashes)
+	w.Write([]byte(" "))
+	w.Write([]byte(data))
+	if closed {
+		w.Write([]byte(" s"))
+	} else {
+		w.Write([]byte(" S"))
+	}
+	if l.fillRule == EvenOdd {
+		w.Write([]byte("*"))
+	}
+	if pushed {
+		w.Pop()
 	}
 }
 
 func (l pathLayer) WriteEPS(w *epsWriter) {
-	// TODO: (EPS) test ellipse, rotations etc
-	// TODO: (EPS) add drawState support
-	w.SetColor(l.fillColor)
+	fill := l.hasFill()
+	stroke := l.hasStroke()
+	if !fill && !stroke {
+		return
+	}
+
+	w.Push()
 	w.Write([]byte(" "))
 	w.Write([]byte(l.path.ToPS()))
-	w.Write([]byte(" fill"))
+	if fill {
+		if l.fillRule == EvenOdd {
+			l.writeEPSPaint(w, l.fillColor, l.fillGradient, l.fillPattern, "eofill", "eoclip")
+		} else {
+			l.writeEPSPaint(w, l.fillColor, l.fillGradient, l.fillPattern, "fill", "clip")
+		}
+	}
+	if stroke {
+		if !l.strokeUnsupported() {
+			w.SetLineWidth(l.strokeWidth)
+			w.SetLineCap(l.strokeCapper)
+			w.SetLineJoin(l.strokeJoiner)
+			w.SetDashes(l.dashOffset, l.dashes)
+			l.writeEPSPaint(w, l.strokeColor, l.strokeGradient, l.strokePattern, "stroke", "strokepath clip")
+		} else {
+			// stroke settings unsupported by EPS, draw stroke explicitly
+			strokePath := l.path
+			if 0 < len(l.dashes) {
+				strokePath = strokePath.Dash(l.dashOffset, l.dashes...)
+			}
+			strokePath = strokePath.Stroke(l.strokeWidth, l.strokeCapper, l.strokeJoiner)
+			w.Write([]byte(" newpath "))
+			w.Write([]byte(strokePath.ToPS()))
+			l.writeEPSPaint(w, l.strokeColor, l.strokeGradient, l.strokePattern, "fill", "clip")
+		}
+	}
+	w.Pop()
 }
 
-func (l pathLayer) WriteImage(img *image.RGBA, dpm float64) {
-	// TODO: use fill rule (EvenOdd, NonZero) for rasterizer
-	w, h := img.Bounds().Size().X, img.Bounds().Size().Y
-	if l.fillColor.A != 0 {
-		ras := vector.NewRasterizer(w, h)
-		l.path.ToRasterizer(ras, dpm)
-		size := ras.Size()
-		ras.Draw(img, image.Rect(0, 0, size.X, size.Y), image.NewUniform(l.fillColor), image.Point{})
+// writeEPSPaint paints the current path with a color or pattern using op, or with a gradient by clipping with clipOp. The current path is preserved.
+func (l pathLayer) writeEPSPaint(w *epsWriter, col color.RGBA, gradient Gradient, pattern *Pattern, op, clipOp string) {
+	if gradient != nil {
+		m := l.gradientM
+		fmt.Fprintf(w, " gsave %v", clipOp)
+		fmt.Fprintf(w, " [%v %v %v %v %v %v] concat ", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+		w.writeVal(pdfGradientShading(gradient, l.Bounds().Transform(m.Inv()), false))
+		w.Write([]byte(" shfill grestore"))
+		return
+	} else if pattern != nil {
+		fmt.Fprintf(w, " gsave")
+		w.writePattern(pattern, l.gradientM.Mul(pattern.M))
+		fmt.Fprintf(w, " %v grestore", op)
+		return
 	}
-	if l.strokeColor.A != 0 && 0.0 < l.strokeWidth {
+	w.SetColor(col)
+	fmt.Fprintf(w, " gsave %v grestore", op)
+}
+
+func (l pathLayer) WriteImage(img draw.Image, m Matrix) {
+	bounds := img.Bounds()
+	rasM := Identity.Translate(-float64(bounds.Min.X), -float64(bounds.Min.Y)).Mul(m)
+	if l.hasFill() {
+		ras := NewRasterizer(bounds.Dx(), bounds.Dy(), AntiAliasing)
+		ras.AddPath(l.path, rasM)
+		ras.Draw(img, l.imagePaint(l.fillColor, l.fillGradient, l.fillPattern, m), l.fillRule)
+	}
+	if l.hasStroke() {
 		strokePath := l.path
 		if 0 < len(l.dashes) {
 			strokePath = strokePath.Dash(l.dashOffset, l.dashes...)
 		}
 		strokePath = strokePath.Stroke(l.strokeWidth, l.strokeCapper, l.strokeJoiner)
 
-		ras := vector.NewRasterizer(w, h)
-		strokePath.ToRasterizer(ras, dpm)
-		size := ras.Size()
-		ras.Draw(img, image.Rect(0, 0, size.X, size.Y), image.NewUniform(l.strokeColor), image.Point{})
+		ras := NewRasterizer(bounds.Dx(), bounds.Dy(), AntiAliasing)
+		ras.AddPath(strokePath, rasM)
+		ras.Draw(img, l.imagePaint(l.strokeColor, l.strokeGradient, l.strokePattern, m), NonZero)
+	}
+}
+
+// imagePaint returns the source image for rasterization of a color, gradient or pattern, with m mapping canvas coordinates to pixel coordinates.
+func (l pathLayer) imagePaint(col color.RGBA, gradient Gradient, pattern *Pattern, m Matrix) image.Image {
+	if gradient != nil {
+		return gradientImage{gradient, l.gradientM.Inv().Mul(m.Inv())}
+	} else if pattern != nil {
+		return newPatternImage(pattern, m.Mul(l.gradientM).Mul(pattern.M))
+	}
+	return image.NewUniform(col)
+}
+
+////////////////////////////////////////////////////////////////
+
+// svgLinkLayers returns the layers where the layers that lie within the area of a link are wrapped in that link, as SVG links are elements that contain the clickable content. Links that contain no layers remain, and are written as an invisible area.
+func svgLinkLayers(layers []layer) []layer {
+	within := func(r, q Rect) bool {
+		return q.X-Epsilon <= r.X && r.X+r.W <= q.X+q.W+Epsilon && q.Y-Epsilon <= r.Y && r.Y+r.H <= q.Y+q.H+Epsilon
+	}
+
+	var linked []layer
+	for i, l := range layers {
+		link, ok := l.(linkLayer)
+		if !ok {
+			continue
+		}
+		area := link.Bounds()
+		found := false
+		for j, content := range layers {
+			if _, ok := content.(linkLayer); ok || !within(content.Bounds(), area) {
+				continue
+			} else if linked == nil {
+				linked = append([]layer{}, layers...)
+			}
+			if _, ok := linked[j].(linkedLayer); !ok {
+				linked[j] = linkedLayer{content, link.link} // SVG links cannot be nested
+			}
+			found = true
+		}
+		if found {
+			linked[i] = nil
+		}
+	}
+	if linked == nil {
+		return layers
+	}
+
+	layers = linked[:0]
+	for _, l := range linked {
+		if l != nil {
+			layers = append(layers, l)
+		}
 	}
+	return layers
+}
+
+// svgLinkHref returns the target of the link in SVG, which is the URI or the page within the document.
+func svgLinkHref(link Link) string {
+	if link.URI == "" {
+		return fmt.Sprintf("#page%d", link.Page)
+	}
+	return html.EscapeString(link.URI)
+}
+
+// linkedLayer is a layer that is wrapped in a link when written to SVG.
+type linkedLayer struct {
+	layer
+	link Link
+}
+
+func (l linkedLayer) WriteSVG(w *svgWriter) {
+	fmt.Fprintf(w, `<a xlink:href="%s">`, svgLinkHref(l.link))
+	l.layer.WriteSVG(w)
+	fmt.Fprintf(w, `</a>`)
+}
+
+type linkLayer struct {
+	area  *Path
+	link  Link
+	clips []clipPath
+}
+
+func (l linkLayer) Bounds() Rect {
+	return l.area.Bounds()
+}
+
+func (l linkLayer) clipPaths() []clipPath {
+	return l.clips
+}
+
+func (l linkLayer) WriteSVG(w *svgWriter) {
+	// the area is invisible but still painted so that it receives pointer events, SVG 1.1 does not allow the transparent color
+	p := l.area.Transform(Identity.Translate(0.0, w.height).ReflectY())
+	fmt.Fprintf(w, `<a xlink:href="%s"><path d="%s" fill="#000" fill-opacity="0"/></a>`, svgLinkHref(l.link), p.ToSVG())
+}
+
+func (l linkLayer) WritePDF(w *pdfPageWriter) {
+	w.AddLink(l.area, l.link)
+}
+
+func (l linkLayer) WriteEPS(w *epsWriter) {
+	w.AddLink(l.area, l.link)
+}
+
+func (l linkLayer) WriteImage(img draw.Image, m Matrix) {
 }
 
 ////////////////////////////////////////////////////////////////
 
 type textLayer struct {
-	text *Text
-	m    Matrix
+	text  *Text
+	m     Matrix
+	clips []clipPath
 }
 
 func (l textLayer) Bounds() Rect {
 	return l.text.Bounds().Transform(l.m)
 }
 
-func (l textLayer) WriteSVG(w io.Writer, h float64) {
-	l.text.WriteSVG(w, h, l.m)
+func (l textLayer) clipPaths() []clipPath {
+	return l.clips
+}
+
+func (l textLayer) WriteSVG(w *svgWriter) {
+	l.text.WriteSVG(w, w.height, l.m)
 }
 
 func (l textLayer) WritePDF(w *pdfPageWriter) {
@@ -546,30 +1124,25 @@ func (l textLayer) WritePDF(w *pdfPageWriter) {
 }
 
 func (l textLayer) WriteEPS(w *epsWriter) {
-	// TODO: (EPS) write text natively
-	paths, colors := l.text.ToPaths()
-	for i, path := range paths {
-		state := defaultDrawState
-		state.fillColor = colors[i]
-		pathLayer{path.Transform(l.m), state}.WriteEPS(w)
-	}
+	l.text.WriteEPS(w, l.m)
 }
 
-func (l textLayer) WriteImage(img *image.RGBA, dpm float64) {
+func (l textLayer) WriteImage(img draw.Image, m Matrix) {
 	paths, colors := l.text.ToPaths()
 	for i, path := range paths {
 		state := defaultDrawState
 		state.fillColor = colors[i]
-		pathLayer{path.Transform(l.m), state}.WriteImage(img, dpm)
+		pathLayer{path.Transform(l.m), state}.WriteImage(img, m)
 	}
 }
 
 ////////////////////////////////////////////////////////////////
 
 type imageLayer struct {
-	img image.Image
-	enc ImageEncoding
-	m   Matrix
+	img   image.Image
+	enc   ImageEncoding
+	m     Matrix
+	clips []clipPath
 }
 
 func (l imageLayer) Bounds() Rect {
@@ -577,19 +1150,26 @@ func (l imageLayer) Bounds() Rect {
 	return Rect{0.0, 0.0, float64(size.X), float64(size.Y)}.Transform(l.m)
 }
 
-func (l imageLayer) WriteSVG(w io.Writer, h float64) {
+func (l imageLayer) clipPaths() []clipPath {
+	return l.clips
+}
+
+func (l imageLayer) WriteSVG(w *svgWriter) {
+	data, _, isJPEG := jpegData(l.img)
 	mimetype := "image/png"
-	if l.enc == Lossy {
-		mimetype = "image/jpg"
+	if l.enc == Lossy || isJPEG {
+		mimetype = "image/jpeg"
 	}
 
 	m := l.m.Translate(0.0, float64(l.img.Bounds().Size().Y))
 	fmt.Fprintf(w, `<image transform="%s" width="%d" height="%d" xlink:href="data:%s;base64,`,
-		m.ToSVG(h), l.img.Bounds().Size().X, l.img.Bounds().Size().Y, mimetype)
+		m.ToSVG(w.height), l.img.Bounds().Size().X, l.img.Bounds().Size().Y, mimetype)
 
 	encoder := base64.NewEncoder(base64.StdEncoding, w)
-	if l.enc == Lossy {
-		if err := jpeg.Encode(encoder, l.img, nil); err != nil {
+	if isJPEG {
+		encoder.Write(data)
+	} else if l.enc == Lossy {
+		if err := jpeg.Encode(encoder, l.img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
 			panic(err)
 		}
 	} else {
@@ -609,13 +1189,12 @@ func (l imageLayer) WritePDF(w *pdfPageWriter) {
 }
 
 func (l imageLayer) WriteEPS(w *epsWriter) {
-	// TODO: (EPS) write image
+	w.DrawImage(l.img, l.enc, l.m)
 }
 
-func (l imageLayer) WriteImage(img *image.RGBA, dpm float64) {
-	m := l.m.Scale(dpm, dpm)
-	h := float64(img.Bounds().Size().Y)
-	origin := l.m.Dot(Point{0, float64(l.img.Bounds().Size().Y)})
-	aff3 := f64.Aff3{m[0][0], -m[0][1], origin.X * dpm, -m[1][0], m[1][1], h - origin.Y*dpm}
+func (l imageLayer) WriteImage(img draw.Image, m Matrix) {
+	// map image pixels, with the origin in the top-left, to the pixels of img
+	m = m.Mul(l.m).Translate(0.0, float64(l.img.Bounds().Size().Y)).ReflectY()
+	aff3 := f64.Aff3{m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2]}
 	draw.CatmullRom.Transform(img, aff3, l.img, l.img.Bounds(), draw.Over, nil)
 }
@@ -3,8 +3,10 @@ package canvas
 import (
 	"bytes"
 	"image"
+	"image/color"
 	"io/ioutil"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -52,10 +54,96 @@ func TestCanvas(t *testing.T) {
 	c.WritePDF(buf)
 	ioutil.WriteFile("test/canvas.pdf", buf.Bytes(), 0644)
 	s = regexp.MustCompile(`stream\nx(.|\n)+\nendstream\n`).ReplaceAllString(buf.String(), "stream\n\nendstream\n") // remove embedded font
-	test.String(t, s, "%PDF-1.7\n1 0 obj\n<< /Subtype /TrueType /Filter /FlateDecode /Length 215980 >> stream\n\nendstream\nendobj\n5 0 obj\n<< /Type /Page /Contents 4 0 R /Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >> /MediaBox [0 0 60 93] /Parent 5 0 R /Resources << /Font << /F0 2 0 R >> /XObject << /Im0 3 0 R >> >> >>\nendobj\n6 0 obj\n<< /Type /Pages /Count 1 /Kids [5 0 R] >>\nendobj\n7 0 obj\n<< /Type /Catalog /Pages 6 0 R >>\nendobj\nxref\n0 8\n0000000000 65535 f\n0000000009 00000 n\n0000216083 00000 n\n0000227285 00000 n\n0000227491 00000 n\n0000227887 00000 n\n0000228103 00000 n\n0000228160 00000 n\ntrailer\n<< /Root 7 0 R /Size 7 >>\nstarxref\n228209\n%%EOF")
+	test.String(t, s, "%PDF-1.7\n2 0 obj\n<< /Type /XObject /Subtype /Image /BitsPerComponent 8 /ColorSpace /DeviceRGB /Filter /FlateDecode /Height 2 /Interpolate true /Length 25 /Width 2 >> stream\n\nendstream\nendobj\n6 0 obj\n<< /Type /Page /Contents 5 0 R /Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >> /MediaBox [0 0 60 93] /Parent 7 0 R /Resources << /Font << /F0 1 0 R >> /XObject << /Im0 2 0 R >> >> >>\nendobj\n7 0 obj\n<< /Type /Pages /Count 1 /Kids [6 0 R] >>\nendobj\n8 0 obj\n<< /Type /Catalog /Pages 7 0 R >>\nendobj\nxref\n0 9\n0000000000 65535 f\n0000003444 00000 n\n0000000009 00000 n\n0000000216 00000 n\n0000003014 00000 n\n0000003993 00000 n\n0000004389 00000 n\n0000004605 00000 n\n0000004662 00000 n\ntrailer\n<< /Root 8 0 R /Size 8 >>\nstarxref\n4711\n%%EOF")
 
 	buf.Reset()
 	c.WriteEPS(buf)
 	ioutil.WriteFile("test/canvas.eps", buf.Bytes(), 0644)
 	// TODO: test EPS when fully supported
 }
+
+func TestCanvasClip(t *testing.T) {
+	c := New(10, 10)
+	c.PushState()
+	c.SetClipPath(Rectangle(5.0, 10.0))
+	c.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	c.DrawPath(0.0, 0.0, Rectangle(10.0, 5.0))
+	c.PopState()
+	c.DrawPath(0.0, 0.0, Rectangle(2.0, 2.0))
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><clipPath id="c0"><path d="M0 10H5V0H0z"/></clipPath></defs><g clip-path="url(#c0)"><path d="M0 10H10V0H0z"/><path d="M0 10H10V5H0z"/></g><path d="M0 10H2V8H0z"/></svg>`)
+
+	pdfCompress = false
+	buf.Reset()
+	c.WritePDF(buf)
+	test.That(t, bytes.Contains(buf.Bytes(), []byte("q 0 0 m 5 0 l 5 10 l 0 10 l h W n 0 0 m 10 0 l 10 10 l 0 10 l f 0 0 m 10 0 l 10 5 l 0 5 l f Q 0 0 m 2 0 l 2 2 l 0 2 l f")), "clipped content stream")
+
+	img := c.WriteImage(1.0)
+	test.T(t, img.At(2, 5), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.At(7, 5), color.RGBA{255, 255, 255, 255})
+
+	c = New(10, 10)
+	c.SetClipPath(&Path{})
+	c.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	img = c.WriteImage(1.0)
+	test.T(t, img.At(5, 5), color.RGBA{255, 255, 255, 255})
+}
+
+func TestCanvasWriteImageTo(t *testing.T) {
+	c := New(10, 10)
+	c.SetFillColor(Red)
+	c.DrawPath(0.0, 0.0, Rectangle(5.0, 10.0))
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	c.WriteImageTo(nrgba, 1.0)
+	test.T(t, nrgba.At(2, 5), color.NRGBA{255, 0, 0, 255})
+	test.T(t, nrgba.At(7, 5), color.NRGBA{0, 0, 0, 0})
+
+	gray := image.NewGray(image.Rect(0, 0, 20, 20))
+	c.WriteImageTo(gray, 2.0)
+	test.T(t, gray.At(5, 5), color.Gray{76})
+	test.T(t, gray.At(15, 5), color.Gray{0})
+
+	rgba64 := image.NewRGBA64(image.Rect(0, 0, 10, 10))
+	c.WriteImageTo(rgba64, 1.0)
+	test.T(t, rgba64.At(2, 5), color.RGBA64{65535, 0, 0, 65535})
+
+	// render the right half of the canvas in a tile at an offset
+	tile := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	c.WriteImageRect(tile, 1.0, Rect{3.0, 0.0, 5.0, 10.0}, image.Point{5, 0})
+	test.T(t, tile.At(2, 5), color.RGBA{0, 0, 0, 0})
+	test.T(t, tile.At(6, 5), color.RGBA{255, 0, 0, 255})
+	test.T(t, tile.At(8, 5), color.RGBA{0, 0, 0, 0})
+}
+
+func TestCanvasLink(t *testing.T) {
+	c := New(10, 10)
+	c.DrawLinkRect(Rect{1, 2, 3, 4}, Link{URI: "https://example.com"})
+	c.DrawLinkRect(Rect{1, 2, 3, 4}, Link{Page: 1})
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><a xlink:href="https://example.com"><path d="M1 8H4V4H1z" fill="#000" fill-opacity="0"/></a><a xlink:href="#page1"><path d="M1 8H4V4H1z" fill="#000" fill-opacity="0"/></a></svg>`)
+
+	buf.Reset()
+	c.WriteEPS(buf)
+	test.That(t, strings.Contains(buf.String(), "/pdfmark where {pop} {userdict /pdfmark /cleartomark load put} ifelse"), "pdfmark definition")
+	test.That(t, strings.Contains(buf.String(), " [/Rect [1 2 4 6] /Border [0 0 0] /Action << /Subtype /URI /URI (https://example.com) >> /Subtype /Link /ANN pdfmark"), "URI link")
+	test.That(t, strings.Contains(buf.String(), " [/Rect [1 2 4 6] /Border [0 0 0] /Page 2 /View [/XYZ 0 0 0] /Subtype /Link /ANN pdfmark"), "page link")
+
+	// content within the link area is wrapped in the link
+	c = New(10, 10)
+	c.DrawPath(0, 0, Rectangle(10, 10))
+	c.DrawPath(1, 2, Rectangle(2, 2))
+	c.DrawLinkRect(Rect{1, 2, 3, 4}, Link{URI: "https://example.com/?a=1&b=(2)"})
+
+	buf.Reset()
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><path d="M0 10H10V0H0z"/><a xlink:href="https://example.com/?a=1&amp;b=(2)"><path d="M1 8H3V6H1z"/></a></svg>`)
+
+	buf.Reset()
+	c.WriteEPS(buf)
+	test.That(t, strings.Contains(buf.String(), "/URI (https://example.com/?a=1&b=\\(2\\))"), "escaped URI")
+}
@@ -0,0 +1,152 @@
+package canvas
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Metadata is the document information that is written to PDF files, both as the document information dictionary and as XMP metadata. Empty fields are omitted.
+type Metadata struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string
+	CreationDate time.Time
+}
+
+// Empty is true if no metadata is set.
+func (m Metadata) Empty() bool {
+	return m.Title == "" && m.Author == "" && m.Subject == "" && m.Keywords == "" && m.Creator == "" && m.CreationDate.IsZero()
+}
+
+// Outline is a bookmark in the outline of a PDF document, which jumps to position (X,Y) of the zero-based page Page. Bookmarks may contain nested bookmarks.
+type Outline struct {
+	Title string
+	Page  int
+	X, Y  float64
+
+	children []*Outline
+}
+
+// Add adds a nested bookmark and returns it.
+func (o *Outline) Add(title string, page int, x, y float64) *Outline {
+	child := &Outline{Title: title, Page: page, X: x, Y: y}
+	o.children = append(o.children, child)
+	return child
+}
+
+// Children returns the nested bookmarks.
+func (o *Outline) Children() []*Outline {
+	return o.children
+}
+
+// PageLabelStyle is the numbering style of page labels.
+type PageLabelStyle int
+
+// see PageLabelStyle
+const (
+	DecimalLabels     PageLabelStyle = iota // 1, 2, 3, ...
+	UpperRomanLabels                        // I, II, III, ...
+	LowerRomanLabels                        // i, ii, iii, ...
+	UpperLetterLabels                       // A, B, C, ..., AA, BB, ...
+	LowerLetterLabels                       // a, b, c, ..., aa, bb, ...
+	NoLabelNumbers                          // only the prefix
+)
+
+// PageLabel is the labelling of a range of pages, starting at the zero-based page Page and until the next page label. Pages are labelled by the prefix followed by the number in the given style, counting from Start.
+type PageLabel struct {
+	Page   int
+	Style  PageLabelStyle
+	Prefix string
+	Start  int
+}
+
+// Document is a sequence of pages, each being a Canvas with its own size. It allows for exporting to multi-page formats such as PDF and PostScript.
+type Document struct {
+	pages []*Canvas
+
+	Metadata Metadata
+
+	// PDFA enables PDF/A-2b conformance of the PDF output, see PDFOptions for what is written and checked.
+	PDFA bool
+
+	outlines   []*Outline
+	pageLabels []PageLabel
+}
+
+// NewDocument returns a new empty document.
+func NewDocument() *Document {
+	return &Document{}
+}
+
+// NewPage adds a new page of given width and height in mm to the document and returns its Canvas.
+func (d *Document) NewPage(w, h float64) *Canvas {
+	c := New(w, h)
+	d.pages = append(d.pages, c)
+	return c
+}
+
+// AddPage adds an existing Canvas as a page to the document.
+func (d *Document) AddPage(c *Canvas) {
+	d.pages = append(d.pages, c)
+}
+
+// Pages returns the canvases of all pages in order.
+func (d *Document) Pages() []*Canvas {
+	return d.pages
+}
+
+// AddOutline adds a top-level bookmark to the PDF outline that jumps to position (x,y) of the zero-based page, and returns it so that nested bookmarks can be added.
+func (d *Document) AddOutline(title string, page int, x, y float64) *Outline {
+	outline := &Outline{Title: title, Page: page, X: x, Y: y}
+	d.outlines = append(d.outlines, outline)
+	return outline
+}
+
+// Outlines returns the top-level bookmarks.
+func (d *Document) Outlines() []*Outline {
+	return d.outlines
+}
+
+// AddPageLabels labels the pages from the zero-based page onwards, until the next page labels, by the prefix followed by the page number in the given style counting from start. Pages before the first page labels are numbered in decimals starting at one.
+func (d *Document) AddPageLabels(page int, style PageLabelStyle, prefix string, start int) {
+	for i, label := range d.pageLabels {
+		if label.Page == page {
+			d.pageLabels[i] = PageLabel{page, style, prefix, start}
+			return
+		}
+	}
+	d.pageLabels = append(d.pageLabels, PageLabel{page, style, prefix, start})
+}
+
+// WritePDF writes the pages as a single multi-page PDF. Fonts and images are shared between pages.
+func (d *Document) WritePDF(w io.Writer) error {
+	pdf := newPDFWriter(w, d.PDFA)
+	pdf.metadata = d.Metadata
+	pdf.outlines = d.outlines
+	pdf.pageLabels = d.pageLabels
+	for _, c := range d.pages {
+		c.writePDFPage(pdf)
+	}
+	return pdf.Close()
+}
+
+// WritePS writes the pages as a single multi-page PostScript file.
+// Be aware that PostScript does not support transparency of colors.
+func (d *Document) WritePS(w io.Writer) error {
+	ps := newPSWriter(w, len(d.pages))
+	for _, c := range d.pages {
+		ps.NewPage(c.W, c.H)
+		c.writePS(ps)
+	}
+	return ps.Close()
+}
+
+// WriteSVG writes each page as a separate SVG file, as SVG does not support multiple pages. For each page, writer is called with the zero-based page number and should return the io.Writer to write that page to. Each page has the id "page" followed by its page number, which is the target of links to that page when the pages are combined into one document, such as an HTML page.
+func (d *Document) WriteSVG(writer func(page int) io.Writer) {
+	for i, c := range d.pages {
+		c.writeSVG(writer(i), fmt.Sprintf("page%d", i))
+	}
+}
@@ -0,0 +1,46 @@
+package canvas
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestDocument(t *testing.T) {
+	doc := NewDocument()
+	c := doc.NewPage(10, 20)
+	c.DrawPath(0, 0, Rectangle(5, 5))
+	c = New(30, 40)
+	c.DrawPath(0, 0, Circle(5))
+	doc.AddPage(c)
+	test.T(t, len(doc.Pages()), 2)
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, doc.WritePDF(buf))
+	test.That(t, strings.Contains(buf.String(), "/MediaBox [0 0 10 20] /Parent 5 0 R"), "first page")
+	test.That(t, strings.Contains(buf.String(), "/MediaBox [0 0 30 40] /Parent 5 0 R"), "second page")
+	test.That(t, strings.Contains(buf.String(), "5 0 obj\n<< /Type /Pages /Count 2 /Kids [2 0 R 4 0 R] >>"), "page tree")
+
+	buf.Reset()
+	doc.WritePS(buf)
+	s := buf.String()
+	test.That(t, strings.HasPrefix(s, "%!PS-Adobe-3.0\n%%Pages: 2\n"), "header")
+	test.That(t, strings.Contains(s, "%%Page: 1 1\n%%PageBoundingBox: 0 0 10 20\n<< /PageSize [10 20] >> setpagedevice"), "first page")
+	test.That(t, strings.Contains(s, "%%Page: 2 2\n%%PageBoundingBox: 0 0 30 40\n"), "second page")
+	test.T(t, strings.Count(s, "showpage"), 2)
+	test.That(t, strings.HasSuffix(s, "showpage\n%%EOF\n"), "trailer")
+
+	svgs := []*bytes.Buffer{}
+	doc.WriteSVG(func(page int) io.Writer {
+		test.T(t, page, len(svgs))
+		svgs = append(svgs, &bytes.Buffer{})
+		return svgs[page]
+	})
+	test.T(t, len(svgs), 2)
+	test.That(t, strings.HasPrefix(svgs[1].String(), `<svg version="1.1" width="30" height="40"`), "second page")
+	test.That(t, strings.Contains(svgs[1].String(), ` id="page1">`), "page id")
+}
@@ -1,9 +1,18 @@
 package canvas
 
 import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/binary"
 	"fmt"
+	"image"
 	"image/color"
+	"image/jpeg"
 	"io"
+	"math"
+	"sort"
+	"strings"
 )
 
 var psEllipseDef = `/ellipse {
@@ -36,23 +45,287 @@ xrad yrad scale
 savematrix setmatrix
 } def`
 
+// psPdfmarkDef defines pdfmark as a no-op for interpreters other than Distiller and Ghostscript, so that the link annotations are ignored when printing.
+var psPdfmarkDef = `
+/pdfmark where {pop} {userdict /pdfmark /cleartomark load put} ifelse`
+
+type epsState struct {
+	color      color.RGBA
+	lineWidth  float64
+	lineCap    int
+	lineJoin   int
+	miterLimit float64
+	dashes     []float64
+	font       string
+	fontSize   float64
+}
+
+var defaultEPSState = epsState{
+	color:      Black,
+	lineWidth:  1.0,
+	miterLimit: 10.0,
+	dashes:     []float64{0.0},
+}
+
+type epsFont struct {
+	name   string
+	subset *fontSubset
+}
+
+// epsWriter buffers the PostScript content so that the fonts it uses can be subsetted and written in the prolog once the content is known. Call Close to write out the file.
 type epsWriter struct {
-	io.Writer
-	color color.RGBA
+	*bytes.Buffer
+	w      io.Writer
+	header string
+	fonts  map[*Font]*epsFont
+	epsState
+	stack []epsState
+	page  int
+	links bool
 }
 
 func newEPSWriter(writer io.Writer, width, height float64) *epsWriter {
-	w := &epsWriter{
-		Writer: writer,
-		color:  Black,
+	// TODO: (EPS) generate and add preview
+	return &epsWriter{
+		Buffer:   &bytes.Buffer{},
+		w:        writer,
+		header:   fmt.Sprintf("%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width), dec(height)),
+		fonts:    map[*Font]*epsFont{},
+		epsState: defaultEPSState,
 	}
+}
 
-	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width), dec(height))
-	fmt.Fprintf(w, psEllipseDef)
+// newPSWriter returns a writer for a multi-page PostScript document with the given number of pages. Start each page with NewPage and finish the document with Close.
+func newPSWriter(writer io.Writer, pages int) *epsWriter {
+	return &epsWriter{
+		Buffer:   &bytes.Buffer{},
+		w:        writer,
+		header:   fmt.Sprintf("%%!PS-Adobe-3.0\n%%%%Pages: %d\n%%%%EndComments\n", pages),
+		fonts:    map[*Font]*epsFont{},
+		epsState: defaultEPSState,
+	}
+}
 
-	// TODO: (EPS) generate and add preview
+// NewPage ends the current page, if any, and starts a new page of given width and height.
+func (w *epsWriter) NewPage(width, height float64) {
+	if 0 < w.page {
+		fmt.Fprintf(w, " showpage")
+	}
+	w.page++
+	fmt.Fprintf(w, "\n%%%%Page: %d %d\n%%%%PageBoundingBox: 0 0 %v %v\n", w.page, w.page, dec(width), dec(height))
+	fmt.Fprintf(w, "<< /PageSize [%v %v] >> setpagedevice", dec(width), dec(height))
+	w.epsState = defaultEPSState // setpagedevice resets the graphics state
+}
+
+// Close ends the last page, if any, and writes the header, the prolog with the embedded fonts, and the content to the output.
+func (w *epsWriter) Close() error {
+	if 0 < w.page {
+		fmt.Fprintf(w, " showpage")
+	}
+	fmt.Fprintf(w, "\n%%%%EOF\n")
+
+	if _, err := io.WriteString(w.w, w.header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.w, psEllipseDef); err != nil {
+		return err
+	}
+	if w.links {
+		if _, err := io.WriteString(w.w, psPdfmarkDef); err != nil {
+			return err
+		}
+	}
+
+	fonts := make([]*Font, 0, len(w.fonts))
+	for font := range w.fonts {
+		fonts = append(fonts, font)
+	}
+	sort.Slice(fonts, func(i, j int) bool { return w.fonts[fonts[i]].name < w.fonts[fonts[j]].name })
+	for _, font := range fonts {
+		if err := w.writeFont(font, w.fonts[font]); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.WriteTo(w.w)
+	return err
+}
+
+// writeFont writes the used glyphs of a TrueType font as a Type 42 font. Since a PostScript string selects glyphs by byte, each block of 256 glyphs is defined as a separate font that shares the font program but has its own encoding.
+func (w *epsWriter) writeFont(font *Font, f *epsFont) error {
+	names := make([]string, len(f.subset.glyphs))
+	for i := range names {
+		names[i] = fmt.Sprintf("g%d", i)
+	}
+	names[0] = ".notdef"
 
-	return w
+	b, _, err := font.subset(f.subset.glyphs)
+	charStrings := make([]uint16, len(names))
+	for i := range charStrings {
+		charStrings[i] = uint16(i)
+	}
+	if err != nil {
+		// embed the entire font and refer to the original glyph indices
+		b = font.raw
+		copy(charStrings, f.subset.glyphs)
+	}
+
+	bounds, _, _, _, _, _ := font.pdfInfo()
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "\n%%%%BeginResource: font %s\n", f.name)
+	fmt.Fprintf(buf, "11 dict begin\n/FontType 42 def\n/FontName /%s def\n/PaintType 0 def\n", f.name)
+	fmt.Fprintf(buf, "/FontMatrix [1 0 0 1 0 0] def\n/FontBBox [%v %v %v %v] def\n", dec(bounds.X/1000.0), dec(bounds.Y/1000.0), dec((bounds.X+bounds.W)/1000.0), dec((bounds.Y+bounds.H)/1000.0))
+	fmt.Fprintf(buf, "/Encoding %s def\n", epsEncoding(names, 0))
+	fmt.Fprintf(buf, "/CharStrings %d dict dup begin", len(names))
+	for i, name := range names {
+		if i%8 == 0 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(buf, "/%s %d def", name, charStrings[i])
+	}
+	buf.WriteString("\nend def\n/sfnts [")
+	for _, s := range type42Strings(b) {
+		buf.WriteString("\n<")
+		for i := 0; i < len(s); i += 36 {
+			if 0 < i {
+				buf.WriteString("\n")
+			}
+			j := i + 36
+			if len(s) < j {
+				j = len(s)
+			}
+			fmt.Fprintf(buf, "%X", s[i:j])
+		}
+		buf.WriteString("00>") // the final byte of each string is ignored
+	}
+	buf.WriteString("\n] def\ncurrentdict end\n/" + f.name + " exch definefont pop\n")
+	for k := 1; k*256 < len(names); k++ {
+		fmt.Fprintf(buf, "/%s dup length dict begin {1 index /FID ne {def} {pop pop} ifelse} forall\n", f.name)
+		fmt.Fprintf(buf, "/Encoding %s def\n", epsEncoding(names, k))
+		fmt.Fprintf(buf, "currentdict end /%s.%d exch definefont pop\n", f.name, k)
+	}
+	buf.WriteString("%%EndResource")
+	_, err = buf.WriteTo(w.w)
+	return err
+}
+
+// epsEncoding returns the encoding array for the k-th block of 256 glyph names.
+func epsEncoding(names []string, k int) string {
+	sb := strings.Builder{}
+	sb.WriteString("[")
+	for c := 0; c < 256; c++ {
+		if c%16 == 0 {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(" ")
+		}
+		if i := k*256 + c; i < len(names) {
+			sb.WriteString("/" + names[i])
+		} else {
+			sb.WriteString("/.notdef")
+		}
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// type42Strings splits an SFNT font program into the strings of the sfnts array of a Type 42 font. Strings are limited to 65535 bytes and may only be split at table boundaries or at glyph boundaries within the glyf table.
+func type42Strings(b []byte) [][]byte {
+	const maxLen = 65534 // leave room for the ignored final byte
+
+	if len(b) <= maxLen || len(b) < 12 {
+		return [][]byte{b}
+	}
+
+	boundaries := []int{}
+	numTables := int(binary.BigEndian.Uint16(b[4:]))
+	var glyf, loca, head []byte
+	var glyfOffset int
+	for i := 0; i < numTables && 12+16*i+16 <= len(b); i++ {
+		rec := b[12+16*i:]
+		offset, length := int(binary.BigEndian.Uint32(rec[8:])), int(binary.BigEndian.Uint32(rec[12:]))
+		if len(b) < offset+length {
+			continue
+		}
+		boundaries = append(boundaries, offset)
+		switch string(rec[:4]) {
+		case "glyf":
+			glyf, glyfOffset = b[offset:offset+length], offset
+		case "loca":
+			loca = b[offset : offset+length]
+		case "head":
+			head = b[offset : offset+length]
+		}
+	}
+	if glyf != nil && loca != nil && 52 <= len(head) {
+		if binary.BigEndian.Uint16(head[50:]) == 0 {
+			for i := 0; i+2 <= len(loca); i += 2 {
+				boundaries = append(boundaries, glyfOffset+2*int(binary.BigEndian.Uint16(loca[i:])))
+			}
+		} else {
+			for i := 0; i+4 <= len(loca); i += 4 {
+				boundaries = append(boundaries, glyfOffset+int(binary.BigEndian.Uint32(loca[i:])))
+			}
+		}
+	}
+	boundaries = append(boundaries, len(b))
+	sort.Ints(boundaries)
+
+	strs := [][]byte{}
+	start, prev := 0, 0
+	for _, boundary := range boundaries {
+		if maxLen < boundary-start && start < prev {
+			strs = append(strs, b[start:prev])
+			start = prev
+		}
+		prev = boundary
+	}
+	return append(strs, b[start:])
+}
+
+// Push saves the graphics state, which can be restored with Pop.
+func (w *epsWriter) Push() {
+	fmt.Fprintf(w, " gsave")
+	w.stack = append(w.stack, w.epsState)
+}
+
+// Pop restores the graphics state saved by Push.
+func (w *epsWriter) Pop() {
+	fmt.Fprintf(w, " grestore")
+	w.epsState = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+// writePattern sets the current color to a pattern, where m maps pattern coordinates to user coordinates.
+func (w *epsWriter) writePattern(pattern *Pattern, m Matrix) {
+	// the graphics state of the pattern contents is unknown, invalid values make sure that every state is set when used
+	content := &epsWriter{
+		Buffer: &bytes.Buffer{},
+		fonts:  w.fonts,
+		epsState: epsState{
+			color:      color.RGBA{255, 255, 255, 0},
+			lineWidth:  -1.0,
+			lineCap:    -1,
+			lineJoin:   -1,
+			miterLimit: -1.0,
+		},
+	}
+	writeEPSLayers(content, pattern.layers)
+
+	fmt.Fprintf(w, " << /PatternType 1 /PaintType 1 /TilingType 1 /BBox [0 0 %v %v] /XStep %v /YStep %v /PaintProc { pop%s } >>", dec(pattern.W), dec(pattern.H), dec(pattern.W), dec(pattern.H), content.String())
+	fmt.Fprintf(w, " [%v %v %v %v %v %v] makepattern setpattern", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+}
+
+// Clip intersects the clipping path with the given path data. Use Push and Pop to restore the previous clipping path.
+func (w *epsWriter) Clip(data string, fillRule FillRuleType) {
+	fmt.Fprintf(w, " %v", data)
+	if fillRule == EvenOdd {
+		fmt.Fprintf(w, " eoclip newpath")
+	} else {
+		fmt.Fprintf(w, " clip newpath")
+	}
 }
 
 func (w *epsWriter) SetColor(color color.RGBA) {
@@ -61,3 +334,278 @@ func (w *epsWriter) SetColor(color color.RGBA) {
 		w.color = color
 	}
 }
+
+func (w *epsWriter) SetLineWidth(lineWidth float64) {
+	if lineWidth != w.lineWidth {
+		fmt.Fprintf(w, " %v setlinewidth", dec(lineWidth))
+		w.lineWidth = lineWidth
+	}
+}
+
+func (w *epsWriter) SetLineCap(capper Capper) {
+	var lineCap int
+	if _, ok := capper.(buttCapper); ok {
+		lineCap = 0
+	} else if _, ok := capper.(roundCapper); ok {
+		lineCap = 1
+	} else if _, ok := capper.(squareCapper); ok {
+		lineCap = 2
+	} else {
+		panic("EPS: line cap not support")
+	}
+	if lineCap != w.lineCap {
+		fmt.Fprintf(w, " %d setlinecap", lineCap)
+		w.lineCap = lineCap
+	}
+}
+
+func (w *epsWriter) SetLineJoin(joiner Joiner) {
+	var lineJoin int
+	var miterLimit float64
+	if _, ok := joiner.(bevelJoiner); ok {
+		lineJoin = 2
+	} else if _, ok := joiner.(roundJoiner); ok {
+		lineJoin = 1
+	} else if miter, ok := joiner.(miterJoiner); ok {
+		lineJoin = 0
+		if math.IsNaN(miter.limit) {
+			panic("EPS: line join not support")
+		} else {
+			miterLimit = miter.limit
+		}
+	} else {
+		panic("EPS: line join not support")
+	}
+	if lineJoin != w.lineJoin {
+		fmt.Fprintf(w, " %d setlinejoin", lineJoin)
+		w.lineJoin = lineJoin
+	}
+	if lineJoin == 0 && miterLimit != w.miterLimit {
+		fmt.Fprintf(w, " %v setmiterlimit", dec(miterLimit))
+		w.miterLimit = miterLimit
+	}
+}
+
+func (w *epsWriter) SetDashes(dashPhase float64, dashArray []float64) {
+	// TODO: connect the first and last dash if they coincide
+	if len(dashArray)%2 == 1 {
+		dashArray = append(dashArray, dashArray...)
+	}
+
+	// keep the dash phase positive as for PDF
+	if dashPhase < 0.0 {
+		totalLength := 0.0
+		for _, dash := range dashArray {
+			totalLength += dash
+		}
+		for dashPhase < 0.0 {
+			dashPhase += totalLength
+		}
+	}
+
+	dashes := append(dashArray, dashPhase)
+	equal := false
+	if len(dashes) == len(w.dashes) {
+		equal = true
+		for i, dash := range dashes {
+			if dash != w.dashes[i] {
+				equal = false
+				break
+			}
+		}
+	}
+
+	if !equal {
+		if len(dashes) == 1 {
+			fmt.Fprintf(w, " [] 0 setdash")
+			dashes[0] = 0.0
+		} else {
+			fmt.Fprintf(w, " [%v", dec(dashes[0]))
+			for _, dash := range dashes[1 : len(dashes)-1] {
+				fmt.Fprintf(w, " %v", dec(dash))
+			}
+			fmt.Fprintf(w, "] %v setdash", dec(dashes[len(dashes)-1]))
+		}
+		w.dashes = dashes
+	}
+}
+
+// WriteGlyphs shows the text at the origin using an embedded TrueType font, where advances holds the displacement after each rune. If outline is larger than zero, the glyphs are filled and stroked with a line width of outline.
+func (w *epsWriter) WriteGlyphs(font *Font, size float64, text string, advances []float64, outline float64) {
+	f, ok := w.fonts[font]
+	if !ok {
+		f = &epsFont{
+			name:   fmt.Sprintf("F%d", len(w.fonts)),
+			subset: newFontSubset(),
+		}
+		w.fonts[font] = f
+	}
+
+	runes := []rune(text)
+	indices := font.toIndices(text)
+	for i := range indices {
+		indices[i] = f.subset.Get(indices[i], originalText(runes[i]))
+	}
+
+	x := 0.0
+	if outline <= 0.0 {
+		fmt.Fprintf(w, " 0 0 moveto")
+	}
+	for i := 0; i < len(indices); {
+		block := int(indices[i] / 256)
+		name := f.name
+		if 0 < block {
+			name = fmt.Sprintf("%s.%d", f.name, block)
+		}
+		if name != w.font || size != w.fontSize {
+			fmt.Fprintf(w, " /%s %v selectfont", name, dec(size))
+			w.font = name
+			w.fontSize = size
+		}
+
+		j := i
+		for j < len(indices) && int(indices[j]/256) == block {
+			j++
+		}
+		if outline <= 0.0 {
+			w.WriteString(" ")
+			w.writeString(indices[i:j])
+			w.WriteString(" [")
+			for k := i; k < j; k++ {
+				if k != i {
+					w.WriteString(" ")
+				}
+				fmt.Fprintf(w, "%v", dec(advances[k]))
+			}
+			w.WriteString("] xshow")
+		} else {
+			for k := i; k < j; k++ {
+				fmt.Fprintf(w, " %v 0 moveto ", dec(x))
+				w.writeString(indices[k : k+1])
+				w.WriteString(" false charpath")
+				x += advances[k]
+			}
+		}
+		i = j
+	}
+	if 0.0 < outline {
+		w.SetLineWidth(outline)
+		w.WriteString(" gsave fill grestore stroke")
+	}
+}
+
+// AddLink adds a link annotation over the bounds of area as a pdfmark, which is used when converting to PDF. Destinations within the document refer to the one-based page number of the resulting PDF.
+func (w *epsWriter) AddLink(area *Path, link Link) {
+	w.links = true
+	bounds := area.Bounds()
+	fmt.Fprintf(w, " [/Rect [%v %v %v %v] /Border [0 0 0]", dec(bounds.X), dec(bounds.Y), dec(bounds.X+bounds.W), dec(bounds.Y+bounds.H))
+	if link.URI != "" {
+		fmt.Fprintf(w, " /Action << /Subtype /URI /URI %s >>", psString(link.URI))
+	} else {
+		fmt.Fprintf(w, " /Page %d /View [/XYZ %v %v 0]", link.Page+1, dec(link.X), dec(link.Y))
+	}
+	fmt.Fprintf(w, " /Subtype /Link /ANN pdfmark")
+}
+
+// psString returns s as a PostScript string literal, escaping non-printable characters to keep the file 7-bit clean.
+func psString(s string) string {
+	sb := strings.Builder{}
+	sb.WriteByte('(')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '(' || c == ')' || c == '\\' {
+			sb.WriteByte('\\')
+		} else if c < ' ' || '~' < c {
+			fmt.Fprintf(&sb, "\\%03o", c)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// writeString writes the glyph indices as a PostScript string, using the lower byte of each index as the character code. Non-printable characters are escaped to keep the file 7-bit clean.
+func (w *epsWriter) writeString(indices []uint16) {
+	w.WriteByte('(')
+	for _, index := range indices {
+		c := byte(index)
+		if c == '(' || c == ')' || c == '\\' {
+			w.WriteByte('\\')
+		} else if c < ' ' || '~' < c {
+			fmt.Fprintf(w, "\\%03o", c)
+			continue
+		}
+		w.WriteByte(c)
+	}
+	w.WriteByte(')')
+}
+
+// DrawImage draws an image with transformation matrix m, where the image spans from (0,0) to (width,height) in pixels. Lossless images are compressed with FlateDecode and lossy images with DCTDecode.
+func (w *epsWriter) DrawImage(img image.Image, enc ImageEncoding, m Matrix) {
+	size := img.Bounds().Size()
+	components := 3
+	if _, ok := img.(*image.Gray); ok {
+		components = 1
+	}
+
+	// TODO: (EPS) images have no alpha channel in PostScript
+	var b bytes.Buffer
+	filter := "FlateDecode"
+	if data, gray, ok := jpegData(img); ok {
+		filter = "DCTDecode"
+		if gray {
+			components = 1
+		}
+		b.Write(data)
+	} else if enc == Lossy {
+		filter = "DCTDecode"
+		if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+			panic(err)
+		}
+	} else {
+		zw := zlib.NewWriter(&b)
+		pixels := make([]byte, components)
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				R, G, B, _ := img.At(x, y).RGBA()
+				if components == 1 {
+					pixels[0] = byte(R >> 8)
+				} else {
+					pixels[0] = byte(R >> 8)
+					pixels[1] = byte(G >> 8)
+					pixels[2] = byte(B >> 8)
+				}
+				zw.Write(pixels)
+			}
+		}
+		zw.Close()
+	}
+
+	// the image is read within a procedure so that the remaining data up to EOD is discarded by flushfile before the interpreter continues
+	fmt.Fprintf(w, " gsave [%v %v %v %v %v %v] concat", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	fmt.Fprintf(w, " {/ImageData currentfile /ASCII85Decode filter def %d %d 8 [1 0 0 -1 0 %d] ImageData /%s filter", size.X, size.Y, size.Y, filter)
+	if components == 1 {
+		fmt.Fprintf(w, " image")
+	} else {
+		fmt.Fprintf(w, " false 3 colorimage")
+	}
+	fmt.Fprintf(w, " ImageData flushfile} exec\n")
+
+	data := make([]byte, ascii85.MaxEncodedLen(b.Len()))
+	data = data[:ascii85.Encode(data, b.Bytes())]
+	for i := 0; i < len(data); i += 76 {
+		j := i + 76
+		if len(data) < j {
+			j = len(data)
+		}
+		w.Write(data[i:j])
+		w.WriteString("\n")
+	}
+	w.WriteString("~> grestore")
+}
+
+// writeVal writes a PostScript value, which shares its syntax for dictionaries, arrays, names and numbers with PDF.
+func (w *epsWriter) writeVal(val interface{}) {
+	(&pdfWriter{w: w.Buffer}).writeVal(val)
+}
@@ -2,12 +2,69 @@ package canvas
 
 import (
 	"bytes"
+	"image"
+	"strings"
 	"testing"
+
+	"github.com/tdewolff/test"
 )
 
 func TestEPS(t *testing.T) {
-	w := &bytes.Buffer{}
-	eps := newEPSWriter(w, 100, 80)
+	buf := &bytes.Buffer{}
+	eps := newEPSWriter(buf, 100, 80)
 	eps.SetColor(Red)
-	//test.String(t, string(w.Bytes()), "")
+	eps.SetLineWidth(2.0)
+	eps.SetLineCap(RoundCapper)
+	eps.SetLineJoin(MiterClipJoiner(BevelJoiner, 4.0))
+	eps.SetDashes(-1.0, []float64{2.0, 3.0})
+	test.Error(t, eps.Close())
+	test.String(t, buf.String(), "%!PS-Adobe-3.0 EPSF-3.0\n%%BoundingBox: 0 0 100 80\n"+psEllipseDef+" 1 0 0 setrgbcolor 2 setlinewidth 1 setlinecap 4 setmiterlimit [2 3] 4 setdash\n%%EOF\n")
+}
+
+func TestEPSPath(t *testing.T) {
+	c := New(10, 10)
+	c.SetFillColor(Red)
+	c.SetStrokeColor(Blue)
+	c.SetStrokeWidth(0.5)
+	c.SetStrokeJoiner(BevelJoiner)
+	c.DrawPath(0.0, 0.0, Rectangle(2.0, 2.0))
+	c.SetFillColor(Transparent)
+	c.SetStrokeJoiner(ArcsJoiner)
+	c.DrawPath(0.0, 0.0, MustParseSVG("L2 0L2 2"))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, c.writeEPS(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "\n gsave 0 0 moveto 2 0 lineto 2 2 lineto 0 2 lineto closepath 1 0 0 setrgbcolor gsave fill grestore .5 setlinewidth 2 setlinejoin 0 0 1 setrgbcolor gsave stroke grestore grestore\n"), "fill and stroke")
+	test.That(t, strings.Contains(s, " newpath "), "explicit stroke for arcs joiner")
+}
+
+func TestEPSText(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("test/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(12.0, Black, FontRegular, FontNormal)
+
+	c := New(100, 100)
+	c.DrawText(10.0, 10.0, NewTextLine(face, "AB A", Left))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, c.writeEPS(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "%%BeginResource: font F0\n11 dict begin\n/FontType 42 def\n/FontName /F0 def\n"), "Type 42 font")
+	test.That(t, strings.Contains(s, "/CharStrings 4 dict dup begin\n/.notdef 0 def /g1 1 def /g2 2 def /g3 3 def\nend def\n"), "subsetted glyphs")
+	test.That(t, strings.Contains(s, "0 0 moveto /F0 4.2333333 selectfont (\\001\\002\\003\\001) [3.046875 3.09375 1.34375 3.046875] xshow grestore"), "glyphs")
+}
+
+func TestEPSImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	c := New(10, 10)
+	c.DrawImage(0.0, 0.0, img, Lossless, 1.0)
+	c.DrawImage(0.0, 0.0, img, Lossy, 1.0)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, c.writeEPS(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, " 2 2 8 [1 0 0 -1 0 2] ImageData /FlateDecode filter image ImageData flushfile} exec\n"), "lossless image")
+	test.That(t, strings.Contains(s, " 2 2 8 [1 0 0 -1 0 2] ImageData /DCTDecode filter image ImageData flushfile} exec\n"), "lossy image")
+	test.T(t, strings.Count(s, "~> grestore"), 2)
 }
@@ -29,7 +29,7 @@ const (
 
 // Font defines a font of type TTF or OTF which which a FontFace can be generated for use in text drawing operations.
 type Font struct {
-	// TODO: extend to fully read in sfnt data and read liga tables, generate Raw font data (base on used glyphs), etc
+	// TODO: extend to fully read in sfnt data and read liga tables, etc
 	name     string
 	mimetype string
 	raw      []byte
@@ -203,6 +203,16 @@ func (f *Font) substituteLigatures(s string) string {
 	return s
 }
 
+// originalText returns the text that the rune represents, which differs from the rune itself for ligatures.
+func originalText(r rune) string {
+	for _, stn := range commonLigatures {
+		if stn.dst == r {
+			return stn.src
+		}
+	}
+	return string(r)
+}
+
 func (f *Font) substituteTypography(s string, inSingleQuote, inDoubleQuote bool) (string, bool, bool) {
 	// TODO: typography substitution should maybe not be part of this package (or of Font)
 	if f.typography {
@@ -0,0 +1,954 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// fontSubset keeps track of the glyphs that are used of a font. Used glyphs are assigned new consecutive glyph indices in order of first use, where glyph index 0 (.notdef) is always included.
+type fontSubset struct {
+	glyphs  []uint16          // original glyph index by new glyph index
+	texts   []string          // text that a glyph represents by new glyph index
+	indices map[uint16]uint16 // new glyph index by original glyph index
+}
+
+func newFontSubset() *fontSubset {
+	return &fontSubset{
+		glyphs:  []uint16{0},
+		texts:   []string{""},
+		indices: map[uint16]uint16{0: 0},
+	}
+}
+
+// Get returns the new glyph index for the original glyph index, adding it to the subset if it has not been used before. The text is what the glyph represents, which is used for text extraction. When a glyph represents different texts, the first is kept.
+func (s *fontSubset) Get(glyph uint16, text string) uint16 {
+	if index, ok := s.indices[glyph]; ok {
+		return index
+	}
+	index := uint16(len(s.glyphs))
+	s.glyphs = append(s.glyphs, glyph)
+	s.texts = append(s.texts, text)
+	s.indices[glyph] = index
+	return index
+}
+
+// ToUnicode returns the ToUnicode CMap that maps the new glyph indices back to the text they represent.
+func (s *fontSubset) ToUnicode() []byte {
+	chars := []string{}
+	for i, text := range s.texts {
+		if text == "" {
+			continue
+		}
+		char := fmt.Sprintf("<%04X> <", i)
+		for _, c := range utf16.Encode([]rune(text)) {
+			char += fmt.Sprintf("%04X", c)
+		}
+		chars = append(chars, char+">")
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for 0 < len(chars) {
+		n := len(chars)
+		if 100 < n {
+			n = 100 // maximum number of entries per block
+		}
+		fmt.Fprintf(b, "%d beginbfchar\n%s\nendbfchar\n", n, strings.Join(chars[:n], "\n"))
+		chars = chars[n:]
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return b.Bytes()
+}
+
+// Tag returns the six uppercase letters that prefix the name of a subsetted font, it is derived from the used glyphs so that different subsets of the same font get different names.
+func (s *fontSubset) Tag() string {
+	h := fnv.New32a()
+	binary.Write(h, binary.BigEndian, s.glyphs)
+	v := h.Sum32()
+
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(v%26)
+		v /= 26
+	}
+	return string(tag)
+}
+
+// subset returns the font program with only the given glyphs, where the glyph at position i in glyphs becomes glyph index i. TrueType composite glyphs may require additional glyphs, which are appended to the list of glyphs that is returned.
+func (f *Font) subset(glyphs []uint16) ([]byte, []uint16, error) {
+	tables, err := sfntTables(f.raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, nil, fmt.Errorf("%s table missing", tag)
+		}
+	}
+	if len(tables["head"]) < 54 || len(tables["hhea"]) < 36 || len(tables["maxp"]) < 6 {
+		return nil, nil, fmt.Errorf("invalid font tables")
+	}
+
+	subset := map[string][]byte{}
+	var flavor uint32
+	if f.mimetype == "font/truetype" {
+		flavor = 0x00010000
+		if glyphs, err = subsetGlyf(tables, subset, glyphs); err != nil {
+			return nil, nil, err
+		}
+		for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+			if table, ok := tables[tag]; ok {
+				subset[tag] = table
+			}
+		}
+	} else if f.mimetype == "font/opentype" {
+		flavor = binary.BigEndian.Uint32([]byte("OTTO"))
+		cff, ok := tables["CFF "]
+		if !ok {
+			return nil, nil, fmt.Errorf("CFF table missing")
+		}
+		if subset["CFF "], err = subsetCFF(cff, glyphs); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		return nil, nil, fmt.Errorf("unsupported font format %s", f.mimetype)
+	}
+
+	// horizontal metrics, all glyphs get a full entry
+	hhea := append([]byte{}, tables["hhea"]...)
+	metrics := tables["hmtx"]
+	numberOfHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numberOfHMetrics == 0 || len(metrics) < 4*numberOfHMetrics {
+		return nil, nil, fmt.Errorf("invalid hmtx table")
+	}
+	hmtx := make([]byte, 4*len(glyphs))
+	for i, glyph := range glyphs {
+		if int(glyph) < numberOfHMetrics {
+			copy(hmtx[4*i:], metrics[4*int(glyph):4*int(glyph)+4])
+		} else {
+			copy(hmtx[4*i:], metrics[4*(numberOfHMetrics-1):4*(numberOfHMetrics-1)+2])
+			if j := 4*numberOfHMetrics + 2*(int(glyph)-numberOfHMetrics); j+2 <= len(metrics) {
+				copy(hmtx[4*i+2:], metrics[j:j+2])
+			}
+		}
+	}
+	binary.BigEndian.PutUint16(hhea[34:], uint16(len(glyphs)))
+	subset["hhea"] = hhea
+	subset["hmtx"] = hmtx
+
+	maxp := append([]byte{}, tables["maxp"]...)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(glyphs)))
+	subset["maxp"] = maxp
+
+	head := append([]byte{}, tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0) // checkSumAdjustment
+	if flavor == 0x00010000 {
+		binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat, the loca table has long offsets
+	}
+	subset["head"] = head
+
+	if post, ok := tables["post"]; ok && 32 <= len(post) {
+		post = append([]byte{}, post[:32]...)
+		binary.BigEndian.PutUint32(post, 0x00030000) // version 3 has no glyph names
+		subset["post"] = post
+	}
+	if os2, ok := tables["OS/2"]; ok {
+		subset["OS/2"] = os2
+	}
+
+	// glyphs are selected by index, a cmap table without mappings (only the required final segment) suffices
+	subset["cmap"] = []byte{
+		0, 0, 0, 1, // version, numTables
+		0, 3, 0, 1, 0, 0, 0, 12, // platformID, encodingID, offset
+		0, 4, 0, 24, 0, 0, // format, length, language
+		0, 2, 0, 2, 0, 0, 0, 0, // segCountX2, searchRange, entrySelector, rangeShift
+		0xFF, 0xFF, 0, 0, 0xFF, 0xFF, 0, 1, 0, 0, // endCode, reservedPad, startCode, idDelta, idRangeOffset
+	}
+	return writeSFNT(flavor, subset), glyphs, nil
+}
+
+// sfntTables returns the tables of an sfnt font file by tag.
+func sfntTables(b []byte) (map[string][]byte, error) {
+	if len(b) < 12 {
+		return nil, fmt.Errorf("invalid font file")
+	}
+	numTables := int(binary.BigEndian.Uint16(b[4:]))
+	if len(b) < 12+16*numTables {
+		return nil, fmt.Errorf("invalid font file")
+	}
+
+	tables := map[string][]byte{}
+	for i := 0; i < numTables; i++ {
+		entry := b[12+16*i:]
+		offset := binary.BigEndian.Uint32(entry[8:])
+		length := binary.BigEndian.Uint32(entry[12:])
+		if uint32(len(b)) < offset || uint32(len(b))-offset < length {
+			return nil, fmt.Errorf("invalid font table")
+		}
+		tables[string(entry[:4])] = b[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// writeSFNT writes an sfnt font file with the given tables and sets the checksum adjustment in the head table.
+func writeSFNT(flavor uint32, tables map[string][]byte) []byte {
+	tags := []string{}
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := uint16(len(tags))
+	entrySelector := uint16(math.Floor(math.Log2(float64(numTables))))
+	searchRange := uint16(1<<entrySelector) * 16
+
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, []uint32{flavor})
+	binary.Write(b, binary.BigEndian, []uint16{numTables, searchRange, entrySelector, numTables*16 - searchRange})
+
+	offset := uint32(12 + 16*len(tags))
+	headOffset := uint32(0)
+	for _, tag := range tags {
+		if tag == "head" {
+			headOffset = offset
+		}
+		b.WriteString(tag)
+		binary.Write(b, binary.BigEndian, []uint32{sfntChecksum(tables[tag]), offset, uint32(len(tables[tag]))})
+		offset += (uint32(len(tables[tag])) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		b.Write(tables[tag])
+		b.Write(make([]byte, (4-len(tables[tag])%4)%4))
+	}
+
+	data := b.Bytes()
+	if _, ok := tables["head"]; ok {
+		binary.BigEndian.PutUint32(data[headOffset+8:], 0xB1B0AFBA-sfntChecksum(data))
+	}
+	return data
+}
+
+func sfntChecksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 4 {
+		word := make([]byte, 4)
+		copy(word, b[i:])
+		sum += binary.BigEndian.Uint32(word)
+	}
+	return sum
+}
+
+// subsetGlyf writes the glyf and loca tables of the subset into subset and returns the list of glyphs including the components of composite glyphs.
+func subsetGlyf(tables, subset map[string][]byte, glyphs []uint16) ([]uint16, error) {
+	glyf, loca := tables["glyf"], tables["loca"]
+	if glyf == nil || loca == nil {
+		return nil, fmt.Errorf("glyf or loca table missing")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(tables["maxp"][4:]))
+	longOffsets := binary.BigEndian.Uint16(tables["head"][50:]) == 1
+	if !longOffsets && len(loca) < 2*(numGlyphs+1) || longOffsets && len(loca) < 4*(numGlyphs+1) {
+		return nil, fmt.Errorf("invalid loca table")
+	}
+	glyphData := func(glyph uint16) ([]byte, error) {
+		if numGlyphs <= int(glyph) {
+			return nil, fmt.Errorf("invalid glyph index %d", glyph)
+		}
+		var start, end uint32
+		if longOffsets {
+			start = binary.BigEndian.Uint32(loca[4*int(glyph):])
+			end = binary.BigEndian.Uint32(loca[4*int(glyph)+4:])
+		} else {
+			start = 2 * uint32(binary.BigEndian.Uint16(loca[2*int(glyph):]))
+			end = 2 * uint32(binary.BigEndian.Uint16(loca[2*int(glyph)+2:]))
+		}
+		if end < start || uint32(len(glyf)) < end {
+			return nil, fmt.Errorf("invalid glyph data")
+		}
+		return glyf[start:end], nil
+	}
+
+	indices := map[uint16]uint16{}
+	for i, glyph := range glyphs {
+		indices[glyph] = uint16(i)
+	}
+
+	b := &bytes.Buffer{}
+	offsets := []uint32{}
+	for i := 0; i < len(glyphs); i++ { // glyphs may grow
+		data, err := glyphData(glyphs[i])
+		if err != nil {
+			return nil, err
+		}
+		if 10 <= len(data) && int16(binary.BigEndian.Uint16(data)) < 0 {
+			// composite glyph, renumber its components
+			data = append([]byte{}, data...)
+			for j := 10; j+4 <= len(data); {
+				flags := binary.BigEndian.Uint16(data[j:])
+				component := binary.BigEndian.Uint16(data[j+2:])
+				index, ok := indices[component]
+				if !ok {
+					index = uint16(len(glyphs))
+					indices[component] = index
+					glyphs = append(glyphs, component)
+				}
+				binary.BigEndian.PutUint16(data[j+2:], index)
+
+				j += 4
+				if flags&0x0001 != 0 { // ARG_1_AND_2_ARE_WORDS
+					j += 4
+				} else {
+					j += 2
+				}
+				if flags&0x0008 != 0 { // WE_HAVE_A_SCALE
+					j += 2
+				} else if flags&0x0040 != 0 { // WE_HAVE_AN_X_AND_Y_SCALE
+					j += 4
+				} else if flags&0x0080 != 0 { // WE_HAVE_A_TWO_BY_TWO
+					j += 8
+				}
+				if flags&0x0020 == 0 { // MORE_COMPONENTS
+					break
+				}
+			}
+		}
+		offsets = append(offsets, uint32(b.Len()))
+		b.Write(data)
+		b.Write(make([]byte, (4-len(data)%4)%4))
+	}
+	offsets = append(offsets, uint32(b.Len()))
+
+	locaSubset := &bytes.Buffer{}
+	binary.Write(locaSubset, binary.BigEndian, offsets)
+	subset["glyf"] = b.Bytes()
+	subset["loca"] = locaSubset.Bytes()
+	return glyphs, nil
+}
+
+////////////////////////////////////////////////////////////////
+
+// cffDictEntry is an operator with its operands of a CFF DICT. Escaped operators (12 x) are stored as 1200+x.
+type cffDictEntry struct {
+	op       int
+	operands [][]byte // raw encoding of each operand
+}
+
+const (
+	cffCharset     = 15
+	cffEncoding    = 16
+	cffCharStrings = 17
+	cffPrivate     = 18
+	cffSubrs       = 19
+	cffROS         = 1230
+	cffFDArray     = 1236
+	cffFDSelect    = 1237
+)
+
+// subsetCFF returns the CFF table with only the given glyphs, where the glyph at position i in glyphs becomes glyph index i. Subroutines are kept entirely. CID-keyed fonts get an identity charset so that CIDs equal the new glyph indices.
+func subsetCFF(b []byte, glyphs []uint16) ([]byte, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("invalid CFF table")
+	}
+	pos := int(b[2]) // hdrSize
+	names, pos, err := cffReadIndex(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	topDicts, pos, err := cffReadIndex(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	strings, pos, err := cffReadIndex(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	globalSubrs, _, err := cffReadIndex(b, pos)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) != 1 || len(topDicts) != 1 {
+		return nil, fmt.Errorf("CFF font sets are not supported")
+	}
+
+	top, err := cffReadDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+	offset, ok := cffDictInt(top, cffCharStrings, 0)
+	if !ok {
+		return nil, fmt.Errorf("CFF CharStrings missing")
+	}
+	charStrings, _, err := cffReadIndex(b, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, glyph := range glyphs {
+		if len(charStrings) <= int(glyph) {
+			return nil, fmt.Errorf("invalid glyph index %d", glyph)
+		}
+	}
+	_, cidKeyed := cffDictInt(top, cffROS, 0)
+
+	// charset, CID-keyed fonts map the new glyph indices directly to CIDs
+	charset := &bytes.Buffer{}
+	charset.WriteByte(0) // format 0
+	if cidKeyed {
+		for i := 1; i < len(glyphs); i++ {
+			binary.Write(charset, binary.BigEndian, uint16(i))
+		}
+	} else {
+		charsetOffset, _ := cffDictInt(top, cffCharset, 0)
+		sids, err := cffReadCharset(b, charsetOffset, len(charStrings))
+		if err != nil {
+			return nil, err
+		}
+		for _, glyph := range glyphs[1:] {
+			binary.Write(charset, binary.BigEndian, sids[glyph])
+		}
+	}
+
+	subsetCharStrings := [][]byte{}
+	for _, glyph := range glyphs {
+		subsetCharStrings = append(subsetCharStrings, charStrings[glyph])
+	}
+
+	// private DICTs with their local subroutines, for CID-keyed fonts there is one per font DICT
+	privates, localSubrs := [][]byte{}, [][][]byte{}
+	fontDicts := [][]cffDictEntry{}
+	fds := make([]byte, len(charStrings))
+	fdSelect := &bytes.Buffer{}
+	if cidKeyed {
+		fdArrayOffset, ok := cffDictInt(top, cffFDArray, 0)
+		if !ok {
+			return nil, fmt.Errorf("CFF FDArray missing")
+		}
+		fdSelectOffset, ok := cffDictInt(top, cffFDSelect, 0)
+		if !ok {
+			return nil, fmt.Errorf("CFF FDSelect missing")
+		}
+		fdArray, _, err := cffReadIndex(b, fdArrayOffset)
+		if err != nil {
+			return nil, err
+		}
+		fds, err = cffReadFDSelect(b, fdSelectOffset, len(charStrings))
+		if err != nil {
+			return nil, err
+		}
+		fdSelect.WriteByte(0) // format 0
+		for _, glyph := range glyphs {
+			if len(fdArray) <= int(fds[glyph]) {
+				return nil, fmt.Errorf("invalid CFF FDSelect")
+			}
+			fdSelect.WriteByte(fds[glyph])
+		}
+		for _, fontDict := range fdArray {
+			dict, err := cffReadDict(fontDict)
+			if err != nil {
+				return nil, err
+			}
+			private, subrs, err := cffReadPrivate(b, dict)
+			if err != nil {
+				return nil, err
+			}
+			fontDicts = append(fontDicts, dict)
+			privates = append(privates, private)
+			localSubrs = append(localSubrs, subrs)
+		}
+	} else {
+		private, subrs, err := cffReadPrivate(b, top)
+		if err != nil {
+			return nil, err
+		}
+		privates = append(privates, private)
+		localSubrs = append(localSubrs, subrs)
+	}
+
+	// replace unused subroutines by empty ones, keeping their numbering
+	usedGlobalSubrs := map[int]bool{}
+	usedLocalSubrs := make([]map[int]bool, len(localSubrs))
+	for i := range usedLocalSubrs {
+		usedLocalSubrs[i] = map[int]bool{}
+	}
+	for _, glyph := range glyphs {
+		fd := int(fds[glyph])
+		if len(localSubrs) <= fd {
+			return nil, fmt.Errorf("invalid CFF FDSelect")
+		}
+		c := cffCharStringCalls{
+			globalSubrs: globalSubrs,
+			localSubrs:  localSubrs[fd],
+			usedGlobal:  usedGlobalSubrs,
+			usedLocal:   usedLocalSubrs[fd],
+		}
+		if _, err := c.parse(charStrings[glyph], 0); err != nil {
+			return nil, err
+		}
+	}
+	globalSubrs = cffPruneSubrs(globalSubrs, usedGlobalSubrs)
+	privateSubrs := [][]byte{}
+	for i, subrs := range localSubrs {
+		if subrs == nil {
+			privateSubrs = append(privateSubrs, nil)
+		} else {
+			privateSubrs = append(privateSubrs, cffWriteIndex(cffPruneSubrs(subrs, usedLocalSubrs[i])))
+		}
+	}
+
+	// layout: header, Name INDEX, Top DICT INDEX, String INDEX, Global Subr INDEX, charset, FDSelect, CharStrings INDEX, FDArray INDEX, Private DICTs and Subrs
+	// offsets are written as five byte integers, so that the size of the DICTs does not depend on their values
+	header := []byte{1, 0, 4, 4}
+	writeDicts := func(charsetOffset, fdSelectOffset, charStringsOffset, fdArrayOffset int, privateOffsets []int) ([]byte, []byte) {
+		fdArray := []byte{}
+		if cidKeyed {
+			items := [][]byte{}
+			for i, dict := range fontDicts {
+				private := cffDictEntry{cffPrivate, [][]byte{cffInt5(len(privates[i])), cffInt5(privateOffsets[i])}}
+				items = append(items, cffWriteDict(cffReplaceDict(dict, []int{cffPrivate}, private)))
+			}
+			fdArray = cffWriteIndex(items)
+		}
+
+		dict := cffReplaceDict(top, []int{cffCharset, cffEncoding, cffCharStrings, cffPrivate, cffFDArray, cffFDSelect},
+			cffDictEntry{cffCharset, [][]byte{cffInt5(charsetOffset)}},
+			cffDictEntry{cffCharStrings, [][]byte{cffInt5(charStringsOffset)}})
+		if cidKeyed {
+			dict = append(dict,
+				cffDictEntry{cffFDArray, [][]byte{cffInt5(fdArrayOffset)}},
+				cffDictEntry{cffFDSelect, [][]byte{cffInt5(fdSelectOffset)}})
+		} else {
+			dict = append(dict, cffDictEntry{cffPrivate, [][]byte{cffInt5(len(privates[0])), cffInt5(privateOffsets[0])}})
+		}
+		return cffWriteIndex([][]byte{cffWriteDict(dict)}), fdArray
+	}
+
+	topDict, fdArray := writeDicts(0, 0, 0, 0, make([]int, len(privates)))
+	charsetOffset := len(header) + len(cffWriteIndex(names)) + len(topDict) + len(cffWriteIndex(strings)) + len(cffWriteIndex(globalSubrs))
+	fdSelectOffset := charsetOffset + charset.Len()
+	charStringsOffset := fdSelectOffset + fdSelect.Len()
+	charStringsIndex := cffWriteIndex(subsetCharStrings)
+	fdArrayOffset := charStringsOffset + len(charStringsIndex)
+	privateOffsets := []int{}
+	offset = fdArrayOffset + len(fdArray)
+	for i := range privates {
+		privateOffsets = append(privateOffsets, offset)
+		offset += len(privates[i]) + len(privateSubrs[i])
+	}
+	topDict, fdArray = writeDicts(charsetOffset, fdSelectOffset, charStringsOffset, fdArrayOffset, privateOffsets)
+
+	out := &bytes.Buffer{}
+	out.Write(header)
+	out.Write(cffWriteIndex(names))
+	out.Write(topDict)
+	out.Write(cffWriteIndex(strings))
+	out.Write(cffWriteIndex(globalSubrs))
+	out.Write(charset.Bytes())
+	out.Write(fdSelect.Bytes())
+	out.Write(charStringsIndex)
+	out.Write(fdArray)
+	for i := range privates {
+		out.Write(privates[i])
+		out.Write(privateSubrs[i])
+	}
+	return out.Bytes(), nil
+}
+
+// cffReadPrivate returns the Private DICT referred to by dict and its local subroutines. The Subrs offset of the returned DICT points directly after the DICT itself.
+func cffReadPrivate(b []byte, dict []cffDictEntry) ([]byte, [][]byte, error) {
+	var entry *cffDictEntry
+	for i := range dict {
+		if dict[i].op == cffPrivate {
+			entry = &dict[i]
+		}
+	}
+	if entry == nil || len(entry.operands) != 2 {
+		return nil, nil, fmt.Errorf("CFF Private DICT missing")
+	}
+	size, offset := cffInt(entry.operands[0]), cffInt(entry.operands[1])
+	if offset < 0 || size < 0 || len(b) < offset+size {
+		return nil, nil, fmt.Errorf("invalid CFF Private DICT")
+	}
+	private, err := cffReadDict(b[offset : offset+size])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subrsOffset, ok := cffDictInt(private, cffSubrs, 0)
+	if !ok {
+		return cffWriteDict(private), nil, nil
+	}
+	subrs, _, err := cffReadIndex(b, offset+subrsOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+	private = cffReplaceDict(private, []int{cffSubrs})
+	size = len(cffWriteDict(private)) + 6 // Subrs operand and operator
+	private = append(private, cffDictEntry{cffSubrs, [][]byte{cffInt5(size)}})
+	return cffWriteDict(private), subrs, nil
+}
+
+// cffCharStringCalls finds the subroutines that are called by Type 2 charstrings.
+type cffCharStringCalls struct {
+	globalSubrs, localSubrs [][]byte
+	usedGlobal, usedLocal   map[int]bool
+	stack                   []int // the argument stack is shared with subroutines
+	stems                   int
+}
+
+// parse interprets the charstring for subroutine calls and returns whether the charstring ended (endchar).
+func (c *cffCharStringCalls) parse(b []byte, depth int) (bool, error) {
+	if 10 < depth {
+		return false, fmt.Errorf("CFF subroutine nesting too deep")
+	}
+	for i := 0; i < len(b); {
+		b0 := b[i]
+		switch {
+		case b0 == 28:
+			if len(b) < i+3 {
+				return false, fmt.Errorf("invalid CFF charstring")
+			}
+			c.stack = append(c.stack, int(int16(binary.BigEndian.Uint16(b[i+1:]))))
+			i += 3
+		case 32 <= b0 && b0 <= 246:
+			c.stack = append(c.stack, int(b0)-139)
+			i++
+		case 247 <= b0 && b0 <= 254:
+			if len(b) < i+2 {
+				return false, fmt.Errorf("invalid CFF charstring")
+			}
+			if b0 <= 250 {
+				c.stack = append(c.stack, (int(b0)-247)*256+int(b[i+1])+108)
+			} else {
+				c.stack = append(c.stack, -(int(b0)-251)*256-int(b[i+1])-108)
+			}
+			i += 2
+		case b0 == 255:
+			c.stack = append(c.stack, 0) // fixed point, never a subroutine number
+			i += 5
+		default: // operator
+			i++
+			switch b0 {
+			case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+				c.stems += len(c.stack) / 2
+			case 19, 20: // hintmask, cntrmask
+				c.stems += len(c.stack) / 2 // implicit vstem
+				i += (c.stems + 7) / 8
+			case 10, 29: // callsubr, callgsubr
+				if len(c.stack) == 0 {
+					return false, fmt.Errorf("invalid CFF charstring")
+				}
+				subrs, used := c.localSubrs, c.usedLocal
+				if b0 == 29 {
+					subrs, used = c.globalSubrs, c.usedGlobal
+				}
+				n := c.stack[len(c.stack)-1] + cffSubrsBias(len(subrs))
+				c.stack = c.stack[:len(c.stack)-1]
+				if n < 0 || len(subrs) <= n {
+					return false, fmt.Errorf("invalid CFF subroutine %d", n)
+				}
+				used[n] = true
+				if ended, err := c.parse(subrs[n], depth+1); err != nil || ended {
+					return ended, err
+				}
+				continue // the operands remain for the operator following the call
+			case 11: // return
+				return false, nil
+			case 14: // endchar
+				return true, nil
+			case 12:
+				i++
+			}
+			c.stack = c.stack[:0]
+		}
+	}
+	return false, nil
+}
+
+func cffSubrsBias(n int) int {
+	if n < 1240 {
+		return 107
+	} else if n < 33900 {
+		return 1131
+	}
+	return 32768
+}
+
+// cffPruneSubrs returns the subroutines where unused ones are replaced by a subroutine that only returns.
+func cffPruneSubrs(subrs [][]byte, used map[int]bool) [][]byte {
+	pruned := make([][]byte, len(subrs))
+	for i, subr := range subrs {
+		if used[i] {
+			pruned[i] = subr
+		} else {
+			pruned[i] = []byte{11} // return
+		}
+	}
+	return pruned
+}
+
+// cffReadIndex reads a CFF INDEX at pos and returns its items and the position after the INDEX.
+func cffReadIndex(b []byte, pos int) ([][]byte, int, error) {
+	if pos < 0 || len(b) < pos+2 {
+		return nil, 0, fmt.Errorf("invalid CFF INDEX")
+	}
+	count := int(binary.BigEndian.Uint16(b[pos:]))
+	if count == 0 {
+		return [][]byte{}, pos + 2, nil
+	}
+	if len(b) < pos+3 {
+		return nil, 0, fmt.Errorf("invalid CFF INDEX")
+	}
+	offSize := int(b[pos+2])
+	if offSize < 1 || 4 < offSize || len(b) < pos+3+(count+1)*offSize {
+		return nil, 0, fmt.Errorf("invalid CFF INDEX")
+	}
+	offset := func(i int) int {
+		v := 0
+		for _, c := range b[pos+3+i*offSize : pos+3+(i+1)*offSize] {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+	data := pos + 3 + (count+1)*offSize - 1 // offsets are one-based
+	items := make([][]byte, count)
+	for i := range items {
+		start, end := offset(i), offset(i+1)
+		if start < 1 || end < start || len(b) < data+end {
+			return nil, 0, fmt.Errorf("invalid CFF INDEX")
+		}
+		items[i] = b[data+start : data+end]
+	}
+	return items, data + offset(count), nil
+}
+
+// cffWriteIndex returns the CFF INDEX of the given items.
+func cffWriteIndex(items [][]byte) []byte {
+	if len(items) == 0 {
+		return []byte{0, 0}
+	}
+	size := 1
+	for _, item := range items {
+		size += len(item)
+	}
+	offSize := 1
+	for 1<<(8*uint(offSize)) <= size {
+		offSize++
+	}
+
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, uint16(len(items)))
+	b.WriteByte(byte(offSize))
+	offset := 1
+	writeOffset := func(offset int) {
+		for i := offSize - 1; 0 <= i; i-- {
+			b.WriteByte(byte(offset >> (8 * uint(i))))
+		}
+	}
+	writeOffset(offset)
+	for _, item := range items {
+		offset += len(item)
+		writeOffset(offset)
+	}
+	for _, item := range items {
+		b.Write(item)
+	}
+	return b.Bytes()
+}
+
+// cffReadDict parses a CFF DICT into its entries.
+func cffReadDict(b []byte) ([]cffDictEntry, error) {
+	dict := []cffDictEntry{}
+	operands := [][]byte{}
+	for i := 0; i < len(b); {
+		b0 := b[i]
+		n := 0
+		switch {
+		case b0 <= 21:
+			op := int(b0)
+			i++
+			if b0 == 12 {
+				if len(b) <= i {
+					return nil, fmt.Errorf("invalid CFF DICT")
+				}
+				op = 1200 + int(b[i])
+				i++
+			}
+			dict = append(dict, cffDictEntry{op, operands})
+			operands = [][]byte{}
+			continue
+		case b0 == 28:
+			n = 3
+		case b0 == 29:
+			n = 5
+		case b0 == 30:
+			n = 1
+			for i+n < len(b) && b[i+n]&0x0F != 0x0F && b[i+n]&0xF0 != 0xF0 {
+				n++
+			}
+			n++
+		case 32 <= b0 && b0 <= 246:
+			n = 1
+		case 247 <= b0 && b0 <= 254:
+			n = 2
+		default:
+			return nil, fmt.Errorf("invalid CFF DICT")
+		}
+		if len(b) < i+n {
+			return nil, fmt.Errorf("invalid CFF DICT")
+		}
+		operands = append(operands, b[i:i+n])
+		i += n
+	}
+	return dict, nil
+}
+
+// cffWriteDict returns the encoding of the CFF DICT entries.
+func cffWriteDict(dict []cffDictEntry) []byte {
+	b := &bytes.Buffer{}
+	for _, entry := range dict {
+		for _, operand := range entry.operands {
+			b.Write(operand)
+		}
+		if 1200 <= entry.op {
+			b.Write([]byte{12, byte(entry.op - 1200)})
+		} else {
+			b.WriteByte(byte(entry.op))
+		}
+	}
+	return b.Bytes()
+}
+
+// cffReplaceDict returns the DICT without the given operators and with the entries appended.
+func cffReplaceDict(dict []cffDictEntry, remove []int, entries ...cffDictEntry) []cffDictEntry {
+	replaced := []cffDictEntry{}
+REMOVE:
+	for _, entry := range dict {
+		for _, op := range remove {
+			if entry.op == op {
+				continue REMOVE
+			}
+		}
+		replaced = append(replaced, entry)
+	}
+	return append(replaced, entries...)
+}
+
+// cffDictInt returns the i-th integer operand of the operator.
+func cffDictInt(dict []cffDictEntry, op, i int) (int, bool) {
+	for _, entry := range dict {
+		if entry.op == op && i < len(entry.operands) {
+			return cffInt(entry.operands[i]), true
+		}
+	}
+	return 0, false
+}
+
+// cffInt decodes an integer operand, real operands return zero.
+func cffInt(b []byte) int {
+	switch b0 := int(b[0]); {
+	case b0 == 28:
+		return int(int16(binary.BigEndian.Uint16(b[1:])))
+	case b0 == 29:
+		return int(int32(binary.BigEndian.Uint32(b[1:])))
+	case 32 <= b0 && b0 <= 246:
+		return b0 - 139
+	case 247 <= b0 && b0 <= 250:
+		return (b0-247)*256 + int(b[1]) + 108
+	case 251 <= b0 && b0 <= 254:
+		return -(b0-251)*256 - int(b[1]) - 108
+	}
+	return 0
+}
+
+// cffInt5 encodes an integer operand using five bytes.
+func cffInt5(v int) []byte {
+	b := []byte{29, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(int32(v)))
+	return b
+}
+
+// cffReadCharset returns the string identifier (or CID) for each glyph.
+func cffReadCharset(b []byte, pos, numGlyphs int) ([]uint16, error) {
+	sids := make([]uint16, numGlyphs)
+	if pos <= 2 {
+		// predefined charsets, of which only ISOAdobe maps glyph indices one-to-one
+		for i := range sids {
+			sids[i] = uint16(i)
+		}
+		return sids, nil
+	}
+	if len(b) <= pos {
+		return nil, fmt.Errorf("invalid CFF charset")
+	}
+	format := b[pos]
+	pos++
+	for i := 1; i < numGlyphs; {
+		if format == 0 {
+			if len(b) < pos+2 {
+				return nil, fmt.Errorf("invalid CFF charset")
+			}
+			sids[i] = binary.BigEndian.Uint16(b[pos:])
+			pos += 2
+			i++
+		} else if format == 1 || format == 2 {
+			n := 3 + int(format) - 1
+			if len(b) < pos+n {
+				return nil, fmt.Errorf("invalid CFF charset")
+			}
+			first := binary.BigEndian.Uint16(b[pos:])
+			left := int(b[pos+2])
+			if format == 2 {
+				left = int(binary.BigEndian.Uint16(b[pos+2:]))
+			}
+			pos += n
+			for j := 0; j <= left && i < numGlyphs; j++ {
+				sids[i] = first + uint16(j)
+				i++
+			}
+		} else {
+			return nil, fmt.Errorf("invalid CFF charset format %d", format)
+		}
+	}
+	return sids, nil
+}
+
+// cffReadFDSelect returns the font DICT index for each glyph.
+func cffReadFDSelect(b []byte, pos, numGlyphs int) ([]byte, error) {
+	if len(b) <= pos {
+		return nil, fmt.Errorf("invalid CFF FDSelect")
+	}
+	fds := make([]byte, numGlyphs)
+	switch b[pos] {
+	case 0:
+		if len(b) < pos+1+numGlyphs {
+			return nil, fmt.Errorf("invalid CFF FDSelect")
+		}
+		copy(fds, b[pos+1:])
+	case 3:
+		if len(b) < pos+3 {
+			return nil, fmt.Errorf("invalid CFF FDSelect")
+		}
+		nRanges := int(binary.BigEndian.Uint16(b[pos+1:]))
+		if len(b) < pos+3+3*nRanges+2 {
+			return nil, fmt.Errorf("invalid CFF FDSelect")
+		}
+		for i := 0; i < nRanges; i++ {
+			r := b[pos+3+3*i:]
+			first, fd := int(binary.BigEndian.Uint16(r)), r[2]
+			last := int(binary.BigEndian.Uint16(r[3:])) // first of next range or sentinel
+			for j := first; j < last && j < numGlyphs; j++ {
+				fds[j] = fd
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid CFF FDSelect format %d", b[pos])
+	}
+	return fds, nil
+}
@@ -0,0 +1,65 @@
+package canvas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/test"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestFontSubset(t *testing.T) {
+	s := newFontSubset()
+	test.T(t, s.Get(42, "a"), uint16(1))
+	test.T(t, s.Get(7, "fi"), uint16(2))
+	test.T(t, s.Get(42, "b"), uint16(1))
+	test.T(t, s.Get(0, "c"), uint16(0))
+	test.String(t, fmt.Sprint(s.glyphs), "[0 42 7]")
+	test.T(t, len(s.Tag()), 6)
+	test.That(t, s.Tag() != newFontSubset().Tag(), "tag depends on glyphs")
+	test.String(t, string(s.ToUnicode()), "/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n2 beginbfchar\n<0001> <0061>\n<0002> <00660069>\nendbfchar\nendcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+}
+
+func testFontSubset(t *testing.T, filename, text string) {
+	b, err := ioutil.ReadFile(filename)
+	test.Error(t, err)
+	f, err := parseFont("font", b)
+	test.Error(t, err)
+
+	s := newFontSubset()
+	for _, index := range f.toIndices(text) {
+		s.Get(index, "")
+	}
+	raw, glyphs, err := f.subset(s.glyphs)
+	test.Error(t, err)
+	test.That(t, len(raw) < len(b)/10, "subset is much smaller")
+
+	sub, err := sfnt.Parse(raw)
+	test.Error(t, err)
+	test.T(t, sub.NumGlyphs(), len(glyphs))
+
+	ppem := fixed.I(int(f.sfnt.UnitsPerEm()))
+	for i, glyph := range glyphs {
+		segs0, err := f.sfnt.LoadGlyph(&sfntBuffer, sfnt.GlyphIndex(glyph), ppem, nil)
+		test.Error(t, err)
+		segs0 = append([]sfnt.Segment{}, segs0...)
+		segs1, err := sub.LoadGlyph(&sfntBuffer, sfnt.GlyphIndex(i), ppem, nil)
+		test.Error(t, err)
+		test.String(t, fmt.Sprint(segs1), fmt.Sprint(segs0))
+
+		adv0, _ := f.sfnt.GlyphAdvance(&sfntBuffer, sfnt.GlyphIndex(glyph), ppem, font.HintingNone)
+		adv1, _ := sub.GlyphAdvance(&sfntBuffer, sfnt.GlyphIndex(i), ppem, font.HintingNone)
+		test.T(t, adv1, adv0)
+	}
+}
+
+func TestFontSubsetTTF(t *testing.T) {
+	testFontSubset(t, "test/DejaVuSerif.ttf", "Subset fonts Äé")
+}
+
+func TestFontSubsetOTF(t *testing.T) {
+	testFontSubset(t, "test/EBGaramond12-Regular.otf", "Subset fonts Äé")
+}
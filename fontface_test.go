@@ -74,8 +74,8 @@ func TestFontDecoration(t *testing.T) {
 
 	Tolerance = 1e-1
 	face = family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontSineUnderline)
-	test.T(t, face.Decorate(4.0), MustParseSVG("M0.9 -2.25L0.71841 -2.2046L1.3867 -3.987L1.6333 -4.05L1.88 -3.987L2.5483 -2.2046L2.3667 -2.25L2.1851 -2.2046L2.8534 -3.987L3.1 -4.05A0.45 0.45 0 0 1 3.1 -3.15L3.2816 -3.1954L2.6133 -1.413L2.3667 -1.35L2.12 -1.413L1.4517 -3.1954L1.6333 -3.15L1.8149 -3.1954L1.1466 -1.413L0.9 -1.35A0.45 0.45 0 0 1 0.9 -2.25z"))
+	test.T(t, face.Decorate(4.0), MustParseSVG("M0.72084 -2.2111L1.3867 -3.987L1.6333 -4.05L1.88 -3.987L2.3667 -2.6889L2.8534 -3.987L3.1 -4.05A0.45 0.45 0 0 1 3.2792 -3.1889L2.6133 -1.413L2.3667 -1.35L2.12 -1.413L1.6333 -2.7111L1.1466 -1.413L0.9 -1.35A0.45 0.45 0 0 1 0.72084 -2.2111z"))
 
 	face = family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontSawtoothUnderline)
-	test.T(t, face.Decorate(4.0), MustParseSVG("M0.20564 -1.9305L0.75112 -3.7305L1.1818 -5.1516L1.6124 -3.7305L2.1579 -1.9305L1.2966 -1.9305L1.8421 -3.7305L2.2727 -5.1516L2.7034 -3.7305L3.2489 -1.9305L2.3876 -1.9305L2.933 -3.7305L3.7944 -3.4695L3.2489 -1.6695L2.8182 -0.24838L2.3876 -1.6695L1.8421 -3.4695L2.7034 -3.4695L2.1579 -1.6695L1.7273 -0.24838L1.2966 -1.6695L0.75112 -3.4695L1.6124 -3.4695L1.067 -1.6695L0.20564 -1.9305z"))
+	test.T(t, face.Decorate(4.0), MustParseSVG("M0.20564 -1.9305L1.1818 -5.1516L1.7273 -3.3516L2.2727 -5.1516L2.8182 -3.3516L2.933 -3.7305L3.7944 -3.4695L2.8182 -0.24838L2.2727 -2.0484L1.7273 -0.24838L1.1818 -2.0484L1.067 -1.6695z"))
 }
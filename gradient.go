@@ -0,0 +1,296 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// GradientSpread specifies how a gradient is extended beyond its start and end, ie. outside the [0,1] range of its color stops.
+type GradientSpread int
+
+// see GradientSpread
+const (
+	PadSpread GradientSpread = iota
+	ReflectSpread
+	RepeatSpread
+)
+
+func (spread GradientSpread) String() string {
+	switch spread {
+	case ReflectSpread:
+		return "Reflect"
+	case RepeatSpread:
+		return "Repeat"
+	}
+	return "Pad"
+}
+
+// Stop is a color stop of a gradient, with the offset in the range [0,1].
+type Stop struct {
+	Offset float64
+	Color  color.RGBA
+}
+
+// Stops is a list of color stops sorted by their offset.
+type Stops []Stop
+
+// Add adds a color stop at the given offset in the range [0,1]. Stops at the same offset will be kept in the order they were added, which allows for sharp color transitions.
+func (stops *Stops) Add(offset float64, col color.Color) {
+	offset = math.Max(0.0, math.Min(1.0, offset))
+	r, g, b, a := col.RGBA()
+	stop := Stop{offset, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}}
+	i := sort.Search(len(*stops), func(i int) bool {
+		return offset < (*stops)[i].Offset
+	})
+	*stops = append(*stops, Stop{})
+	copy((*stops)[i+1:], (*stops)[i:])
+	(*stops)[i] = stop
+}
+
+// At returns the interpolated color at t in the range [0,1]. Colors are interpolated in non-premultiplied space, as is done by SVG and PDF viewers.
+func (stops Stops) At(t float64) color.RGBA {
+	if len(stops) == 0 {
+		return Transparent
+	} else if t <= stops[0].Offset {
+		return stops[0].Color
+	} else if stops[len(stops)-1].Offset <= t {
+		return stops[len(stops)-1].Color
+	}
+	i := sort.Search(len(stops), func(i int) bool {
+		return t < stops[i].Offset
+	})
+	t = (t - stops[i-1].Offset) / (stops[i].Offset - stops[i-1].Offset)
+	return interpolateColor(stops[i-1].Color, stops[i].Color, t)
+}
+
+// alpha returns the alpha value that is shared by all stops, or NaN if they differ.
+func (stops Stops) alpha() float64 {
+	if len(stops) == 0 {
+		return 0.0
+	}
+	for _, stop := range stops[1:] {
+		if stop.Color.A != stops[0].Color.A {
+			return math.NaN()
+		}
+	}
+	return float64(stops[0].Color.A) / 255.0
+}
+
+func interpolateColor(c0, c1 color.RGBA, t float64) color.RGBA {
+	r0, g0, b0, a0 := unpremultiply(c0)
+	r1, g1, b1, a1 := unpremultiply(c1)
+	a := (1.0-t)*a0 + t*a1
+	return color.RGBA{
+		uint8(((1.0-t)*r0+t*r1)*a*255.0 + 0.5),
+		uint8(((1.0-t)*g0+t*g1)*a*255.0 + 0.5),
+		uint8(((1.0-t)*b0+t*b1)*a*255.0 + 0.5),
+		uint8(a*255.0 + 0.5),
+	}
+}
+
+// unpremultiply returns the red, green, blue and alpha components in the range [0,1], with the color components not premultiplied by alpha.
+func unpremultiply(col color.RGBA) (float64, float64, float64, float64) {
+	if col.A == 0 {
+		return 0.0, 0.0, 0.0, 0.0
+	}
+	a := float64(col.A) / 255.0
+	return float64(col.R) / 255.0 / a, float64(col.G) / 255.0 / a, float64(col.B) / 255.0 / a, a
+}
+
+////////////////////////////////////////////////////////////////
+
+// Gradient is a paint that varies its color over space, it can be set as the fill or stroke of a Canvas. It is implemented only by LinearGradient and RadialGradient, as each output format writes gradients by their geometry.
+type Gradient interface {
+	// ColorStops returns the color stops of the gradient.
+	ColorStops() Stops
+	// SpreadMethod returns how the gradient is extended beyond its color stops.
+	SpreadMethod() GradientSpread
+	// T returns the gradient parameter at the given position, where zero and one correspond to the first and last offset of the color stops. It returns NaN when the color is undefined at that position.
+	T(Point) float64
+
+	isGradient()
+}
+
+// LinearGradient is a gradient along the line from Start to End. The colors are constant along lines perpendicular to that line.
+type LinearGradient struct {
+	Start, End Point
+	Stops
+	Spread GradientSpread
+}
+
+// NewLinearGradient returns a new linear gradient from (x0,y0) to (x1,y1). Add color stops using Add.
+func NewLinearGradient(x0, y0, x1, y1 float64) *LinearGradient {
+	return &LinearGradient{
+		Start: Point{x0, y0},
+		End:   Point{x1, y1},
+	}
+}
+
+// ColorStops returns the color stops of the gradient.
+func (g *LinearGradient) ColorStops() Stops {
+	return g.Stops
+}
+
+// SpreadMethod returns how the gradient is extended beyond its color stops.
+func (g *LinearGradient) SpreadMethod() GradientSpread {
+	return g.Spread
+}
+
+func (g *LinearGradient) isGradient() {}
+
+// T returns the gradient parameter at point p, see Gradient.
+func (g *LinearGradient) T(p Point) float64 {
+	d := g.End.Sub(g.Start)
+	if equal(d.X, 0.0) && equal(d.Y, 0.0) {
+		return math.NaN()
+	}
+	return p.Sub(g.Start).Dot(d) / d.Dot(d)
+}
+
+// RadialGradient is a gradient that interpolates between the circle at C0 with radius R0 and the circle at C1 with radius R1. This follows the definition of radial gradients in PDF, SVG (with C0 the focal point) and the HTML canvas.
+type RadialGradient struct {
+	C0, C1 Point
+	R0, R1 float64
+	Stops
+	Spread GradientSpread
+}
+
+// NewRadialGradient returns a new radial gradient between the circle at (x0,y0) with radius r0 and the circle at (x1,y1) with radius r1. Add color stops using Add.
+func NewRadialGradient(x0, y0, r0, x1, y1, r1 float64) *RadialGradient {
+	return &RadialGradient{
+		C0: Point{x0, y0},
+		R0: r0,
+		C1: Point{x1, y1},
+		R1: r1,
+	}
+}
+
+// ColorStops returns the color stops of the gradient.
+func (g *RadialGradient) ColorStops() Stops {
+	return g.Stops
+}
+
+// SpreadMethod returns how the gradient is extended beyond its color stops.
+func (g *RadialGradient) SpreadMethod() GradientSpread {
+	return g.Spread
+}
+
+func (g *RadialGradient) isGradient() {}
+
+// T returns the gradient parameter at point p, see Gradient.
+func (g *RadialGradient) T(p Point) float64 {
+	// solve |p - c(t)| = r(t) with c(t) = C0 + t*(C1-C0) and r(t) = R0 + t*(R1-R0) for the largest t with r(t) >= 0
+	cd := g.C1.Sub(g.C0)
+	pd := p.Sub(g.C0)
+	dr := g.R1 - g.R0
+	a := cd.Dot(cd) - dr*dr
+	b := pd.Dot(cd) + g.R0*dr
+	c := pd.Dot(pd) - g.R0*g.R0
+	if equal(a, 0.0) {
+		if equal(b, 0.0) {
+			return math.NaN()
+		}
+		t := c / (2.0 * b)
+		if g.R0+t*dr < 0.0 {
+			return math.NaN()
+		}
+		return t
+	}
+
+	discriminant := b*b - a*c
+	if discriminant < 0.0 {
+		return math.NaN()
+	}
+	discriminant = math.Sqrt(discriminant)
+	t1, t0 := (b+discriminant)/a, (b-discriminant)/a
+	if t1 < t0 {
+		t0, t1 = t1, t0
+	}
+	if 0.0 <= g.R0+t1*dr {
+		return t1
+	} else if 0.0 <= g.R0+t0*dr {
+		return t0
+	}
+	return math.NaN()
+}
+
+// gradientColor returns the color of the gradient at point p in the gradient's coordinate system.
+func gradientColor(g Gradient, p Point) color.RGBA {
+	t := g.T(p)
+	if math.IsNaN(t) {
+		return Transparent
+	}
+	return g.ColorStops().At(spreadT(g.SpreadMethod(), t))
+}
+
+// gradientImage is an image of a gradient that is used as a source for rasterization. Matrix m maps pixel coordinates to gradient coordinates.
+type gradientImage struct {
+	Gradient
+	m Matrix
+}
+
+func (img gradientImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img gradientImage) Bounds() image.Rectangle {
+	return image.Rectangle{image.Point{-1e9, -1e9}, image.Point{1e9, 1e9}}
+}
+
+func (img gradientImage) At(x, y int) color.Color {
+	return gradientColor(img.Gradient, img.m.Dot(Point{float64(x) + 0.5, float64(y) + 0.5}))
+}
+
+// spreadT maps the gradient parameter t into the [0,1] range following the spread method.
+func spreadT(spread GradientSpread, t float64) float64 {
+	switch spread {
+	case ReflectSpread:
+		t = math.Mod(math.Abs(t), 2.0)
+		if 1.0 < t {
+			t = 2.0 - t
+		}
+	case RepeatSpread:
+		t -= math.Floor(t)
+	default:
+		t = math.Max(0.0, math.Min(1.0, t))
+	}
+	return t
+}
+
+// gradientRange returns the range of the gradient parameter t that covers the given rectangle in the gradient's coordinate system. It is used by output formats that do not support the reflect and repeat spread methods natively, so that the color stops can be repeated explicitly.
+func gradientRange(g Gradient, r Rect) (float64, float64) {
+	// linear gradients have their extremes at the corners, for radial gradients we sample along the edges
+	n := 1
+	if _, ok := g.(*RadialGradient); ok {
+		n = 32
+	}
+
+	tmin, tmax := 0.0, 1.0
+	for i := 0; i < n; i++ {
+		f := float64(i) / float64(n)
+		for _, p := range []Point{
+			{r.X + f*r.W, r.Y},
+			{r.X + r.W, r.Y + f*r.H},
+			{r.X + r.W - f*r.W, r.Y + r.H},
+			{r.X, r.Y + r.H - f*r.H},
+		} {
+			if t := g.T(p); !math.IsNaN(t) {
+				tmin = math.Min(tmin, t)
+				tmax = math.Max(tmax, t)
+			}
+		}
+	}
+
+	if g, ok := g.(*RadialGradient); ok && g.R0 != g.R1 {
+		// radii must stay non-negative
+		dr := g.R1 - g.R0
+		if tr := -g.R0 / dr; 0.0 < dr {
+			tmin = math.Max(tmin, tr)
+		} else {
+			tmax = math.Min(tmax, tr)
+		}
+	}
+	return tmin, tmax
+}
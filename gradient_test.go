@@ -0,0 +1,136 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestStops(t *testing.T) {
+	stops := Stops{}
+	stops.Add(1.0, Blue)
+	stops.Add(0.0, Red)
+	stops.Add(0.5, Green)
+	stops.Add(0.5, White)
+	test.T(t, len(stops), 4)
+	test.T(t, stops[0].Color, Red)
+	test.T(t, stops[1].Color, Green)
+	test.T(t, stops[2].Color, White)
+	test.T(t, stops[3].Color, Blue)
+
+	test.T(t, stops.At(-1.0), Red)
+	test.T(t, stops.At(0.25), color.RGBA{128, 64, 0, 255})
+	test.T(t, stops.At(0.75), color.RGBA{128, 128, 255, 255})
+	test.T(t, stops.At(2.0), Blue)
+
+	stops = Stops{}
+	stops.Add(0.0, Transparent)
+	stops.Add(1.0, Red)
+	test.T(t, stops.At(0.5), color.RGBA{64, 0, 0, 128}) // interpolated without premultiplication
+	test.T(t, math.IsNaN(stops.alpha()), true)
+}
+
+func TestLinearGradient(t *testing.T) {
+	g := NewLinearGradient(0.0, 0.0, 10.0, 0.0)
+	test.Float(t, g.T(Point{5.0, 5.0}), 0.5)
+	test.Float(t, g.T(Point{-5.0, 0.0}), -0.5)
+	test.Float(t, g.T(Point{20.0, 0.0}), 2.0)
+
+	test.Float(t, spreadT(PadSpread, -0.5), 0.0)
+	test.Float(t, spreadT(ReflectSpread, -0.25), 0.25)
+	test.Float(t, spreadT(ReflectSpread, 1.25), 0.75)
+	test.Float(t, spreadT(RepeatSpread, 1.25), 0.25)
+	test.Float(t, spreadT(RepeatSpread, -0.25), 0.75)
+
+	tmin, tmax := gradientRange(g, Rect{-5.0, 0.0, 30.0, 10.0})
+	test.Float(t, tmin, -0.5)
+	test.Float(t, tmax, 2.5)
+}
+
+func TestRadialGradient(t *testing.T) {
+	g := NewRadialGradient(0.0, 0.0, 0.0, 0.0, 0.0, 10.0)
+	test.Float(t, g.T(Point{5.0, 0.0}), 0.5)
+	test.Float(t, g.T(Point{0.0, 20.0}), 2.0)
+
+	g = NewRadialGradient(0.0, 0.0, 5.0, 0.0, 0.0, 10.0)
+	test.Float(t, g.T(Point{0.0, 7.5}), 0.5)
+	test.Float(t, g.T(Point{0.0, 2.5}), -0.5)
+	test.Float(t, g.T(Point{0.0, 0.0}), -1.0)
+
+	g = NewRadialGradient(5.0, 0.0, 0.0, 0.0, 0.0, 10.0) // focal point
+	test.Float(t, g.T(Point{5.0, 0.0}), 0.0)
+	test.Float(t, g.T(Point{10.0, 0.0}), 1.0)
+	test.Float(t, g.T(Point{-10.0, 0.0}), 1.0)
+
+	g = NewRadialGradient(0.0, 0.0, 1.0, 10.0, 0.0, 1.0) // cone
+	test.T(t, math.IsNaN(g.T(Point{5.0, 5.0})), true)
+}
+
+func TestGradientCanvas(t *testing.T) {
+	g := NewLinearGradient(0.0, 0.0, 10.0, 0.0)
+	g.Add(0.0, Red)
+	g.Add(1.0, Blue)
+
+	c := New(10, 10)
+	c.SetFillGradient(g)
+	c.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><linearGradient id="g0" gradientUnits="userSpaceOnUse" gradientTransform="matrix(1,0,0,-1,0,10)" x1="0" y1="0" x2="10" y2="0"><stop offset="0" stop-color="#f00"/><stop offset="1" stop-color="#00f"/></linearGradient></defs><path d="M0 10H10V0H0z" fill="url(#g0)"/></svg>`)
+
+	pdfCompress = false
+	buf.Reset()
+	c.WritePDF(buf)
+	test.That(t, strings.Contains(buf.String(), "/Pattern cs /P0 scn 0 0 m 10 0 l 10 10 l 0 10 l f"), "pattern fill in content stream")
+	test.That(t, strings.Contains(buf.String(), "/Shading << /ColorSpace /DeviceRGB /Coords [0 0 10 0] /Domain [0 1] /Extend [true true] /Function << /C0 [1 0 0] /C1 [0 0 1] /Domain [0 1] /FunctionType 2 /N 1 >> /ShadingType 2 >>"), "axial shading")
+
+	img := c.WriteImage(1.0)
+	test.T(t, img.At(0, 5), color.RGBA{242, 0, 13, 255})
+	test.T(t, img.At(9, 5), color.RGBA{13, 0, 242, 255})
+}
+
+func TestGradientPDFPattern(t *testing.T) {
+	g := NewLinearGradient(0.0, 0.0, 10.0, 0.0)
+	g.Add(0.0, Red)
+	g.Add(1.0, Blue)
+
+	// the pattern is written once and shared by the paths
+	c := New(10, 10)
+	c.SetFillGradient(g)
+	c.DrawPath(0.0, 0.0, Rectangle(5.0, 10.0))
+	c.DrawPath(0.0, 0.0, Rectangle(5.0, 10.0).Translate(5.0, 0.0))
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WritePDF(buf))
+	test.T(t, strings.Count(buf.String(), "/PatternType 2"), 1)
+	test.T(t, strings.Count(buf.String(), "/Pattern cs /P0 scn"), 1)
+
+	// repeating gradients cover the painted area and are written for each
+	g.Spread = RepeatSpread
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	test.T(t, strings.Count(buf.String(), "/PatternType 2"), 2)
+	pdfCompress = true
+}
+
+func TestGradientPDFSpread(t *testing.T) {
+	g := NewLinearGradient(0.0, 0.0, 10.0, 0.0)
+	g.Add(0.0, color.RGBA{255, 0, 0, 255})
+	g.Add(1.0, color.RGBA{0, 0, 0, 0})
+	g.Spread = ReflectSpread
+
+	shading := pdfGradientShading(g, Rect{0.0, 0.0, 20.0, 10.0}, true)
+	test.T(t, shading["ColorSpace"], pdfName("DeviceGray"))
+	test.String(t, fmt.Sprint(shading["Domain"]), "[0 2]")
+	test.String(t, fmt.Sprint(shading["Coords"]), "[0 0 20 0]")
+	function := shading["Function"].(pdfDict)
+	test.String(t, fmt.Sprint(function["Bounds"]), "[1]")
+	test.String(t, fmt.Sprint(function["Encode"]), "[0 1 1 0]")
+}
@@ -0,0 +1,300 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// BlendMode is the function that mixes the colors of a group with the colors of its backdrop. They correspond to the blend modes of PDF and of CSS compositing.
+type BlendMode int
+
+// see BlendMode
+const (
+	NormalBlend BlendMode = iota
+	MultiplyBlend
+	ScreenBlend
+	OverlayBlend
+	DarkenBlend
+	LightenBlend
+	ColorDodgeBlend
+	ColorBurnBlend
+	HardLightBlend
+	SoftLightBlend
+	DifferenceBlend
+	ExclusionBlend
+	HueBlend
+	SaturationBlend
+	ColorBlend
+	LuminosityBlend
+)
+
+var blendModeNames = []struct {
+	pdf, css string
+}{
+	{"Normal", "normal"},
+	{"Multiply", "multiply"},
+	{"Screen", "screen"},
+	{"Overlay", "overlay"},
+	{"Darken", "darken"},
+	{"Lighten", "lighten"},
+	{"ColorDodge", "color-dodge"},
+	{"ColorBurn", "color-burn"},
+	{"HardLight", "hard-light"},
+	{"SoftLight", "soft-light"},
+	{"Difference", "difference"},
+	{"Exclusion", "exclusion"},
+	{"Hue", "hue"},
+	{"Saturation", "saturation"},
+	{"Color", "color"},
+	{"Luminosity", "luminosity"},
+}
+
+// String returns the PDF name of the blend mode.
+func (mode BlendMode) String() string {
+	if mode < 0 || int(mode) >= len(blendModeNames) {
+		return "Normal"
+	}
+	return blendModeNames[mode].pdf
+}
+
+func (mode BlendMode) css() string {
+	if mode < 0 || int(mode) >= len(blendModeNames) {
+		return "normal"
+	}
+	return blendModeNames[mode].css
+}
+
+type groupStart struct {
+	index     int
+	opacity   float64
+	blendMode BlendMode
+}
+
+// BeginGroup starts a group of drawing operations that is composited as a whole onto the canvas with the given opacity and blend mode, so that overlapping elements within a semi-transparent group do not show through each other. The group ends with EndGroup, and groups can be nested. Groups that are not ended are drawn as separate elements.
+func (c *Canvas) BeginGroup(opacity float64, blendMode BlendMode) {
+	c.groups = append(c.groups, groupStart{len(c.layers), math.Max(0.0, math.Min(1.0, opacity)), blendMode})
+}
+
+// EndGroup ends the last group started by BeginGroup. If there is no group, this will do nothing.
+func (c *Canvas) EndGroup() {
+	if len(c.groups) == 0 {
+		return
+	}
+	group := c.groups[len(c.groups)-1]
+	c.groups = c.groups[:len(c.groups)-1]
+	if group.index == len(c.layers) {
+		return
+	}
+
+	layers := make([]layer, len(c.layers)-group.index)
+	copy(layers, c.layers[group.index:])
+	c.layers = append(c.layers[:group.index], groupLayer{layers, group.opacity, group.blendMode})
+}
+
+////////////////////////////////////////////////////////////////
+
+// groupLayer is a transparency group. Its layers carry their own clipping paths, so that the group itself is never clipped.
+type groupLayer struct {
+	layers    []layer
+	opacity   float64
+	blendMode BlendMode
+}
+
+func (l groupLayer) Bounds() Rect {
+	return layersBounds(l.layers)
+}
+
+func (l groupLayer) clipPaths() []clipPath {
+	return nil
+}
+
+func (l groupLayer) WriteSVG(w *svgWriter) {
+	fmt.Fprintf(w, `<g`)
+	if l.opacity != 1.0 {
+		fmt.Fprintf(w, ` opacity="%v"`, dec(l.opacity))
+	}
+	if l.blendMode != NormalBlend {
+		fmt.Fprintf(w, ` style="mix-blend-mode:%s"`, l.blendMode.css())
+	}
+	fmt.Fprintf(w, `>`)
+	writeSVGLayers(w, l.layers)
+	fmt.Fprintf(w, `</g>`)
+}
+
+func (l groupLayer) WritePDF(w *pdfPageWriter) {
+	w.DrawGroup(l.layers, l.Bounds(), l.opacity, l.blendMode)
+}
+
+// WriteEPS writes the layers of the group, opacity and blend modes are not supported in EPS.
+func (l groupLayer) WriteEPS(w *epsWriter) {
+	writeEPSLayers(w, l.layers)
+}
+
+func (l groupLayer) WriteImage(img draw.Image, m Matrix) {
+	bounds := img.Bounds()
+	group := image.NewRGBA(bounds)
+	writeImageLayers(group, l.layers, m)
+	if l.blendMode == NormalBlend {
+		mask := image.NewUniform(color.Alpha{uint8(l.opacity*255.0 + 0.5)})
+		draw.DrawMask(img, bounds, group, bounds.Min, mask, image.Point{}, draw.Over)
+		return
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := group.PixOffset(x, y)
+			if group.Pix[i+3] == 0 {
+				continue
+			}
+			// colors are premultiplied by alpha, the blend function uses colors that are not
+			ag := float64(group.Pix[i+3]) / 255.0
+			cs := [3]float64{float64(group.Pix[i]) / 255.0, float64(group.Pix[i+1]) / 255.0, float64(group.Pix[i+2]) / 255.0}
+			as := ag * l.opacity
+
+			r, g, b, a := img.At(x, y).RGBA()
+			cb := [3]float64{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff}
+			ab := float64(a) / 0xffff
+
+			var blended [3]float64
+			if ab != 0.0 {
+				var src, dst [3]float64
+				for k := range cs {
+					src[k] = math.Min(1.0, cs[k]/ag)
+					dst[k] = math.Min(1.0, cb[k]/ab)
+				}
+				blended = blend(l.blendMode, dst, src)
+			}
+
+			col := color.RGBA64{}
+			channels := []*uint16{&col.R, &col.G, &col.B}
+			for k := range cs {
+				c := (1.0-ab)*cs[k]*l.opacity + (1.0-as)*cb[k] + as*ab*blended[k]
+				*channels[k] = uint16(math.Max(0.0, math.Min(1.0, c))*0xffff + 0.5)
+			}
+			col.A = uint16((as+ab-as*ab)*0xffff + 0.5)
+			img.Set(x, y, col)
+		}
+	}
+}
+
+// blend returns the blended color of the backdrop cb and the source cs, see https://www.w3.org/TR/compositing-1/#blending
+func blend(mode BlendMode, cb, cs [3]float64) [3]float64 {
+	switch mode {
+	case HueBlend:
+		return setLum(setSat(cs, sat(cb)), lum(cb))
+	case SaturationBlend:
+		return setLum(setSat(cb, sat(cs)), lum(cb))
+	case ColorBlend:
+		return setLum(cs, lum(cb))
+	case LuminosityBlend:
+		return setLum(cb, lum(cs))
+	}
+
+	var c [3]float64
+	for k := range c {
+		c[k] = blendChannel(mode, cb[k], cs[k])
+	}
+	return c
+}
+
+// blendChannel returns the result of a separable blend mode for one color channel.
+func blendChannel(mode BlendMode, cb, cs float64) float64 {
+	switch mode {
+	case MultiplyBlend:
+		return cb * cs
+	case ScreenBlend:
+		return cb + cs - cb*cs
+	case OverlayBlend:
+		return blendChannel(HardLightBlend, cs, cb)
+	case DarkenBlend:
+		return math.Min(cb, cs)
+	case LightenBlend:
+		return math.Max(cb, cs)
+	case ColorDodgeBlend:
+		if cb == 0.0 {
+			return 0.0
+		} else if cs == 1.0 {
+			return 1.0
+		}
+		return math.Min(1.0, cb/(1.0-cs))
+	case ColorBurnBlend:
+		if cb == 1.0 {
+			return 1.0
+		} else if cs == 0.0 {
+			return 0.0
+		}
+		return 1.0 - math.Min(1.0, (1.0-cb)/cs)
+	case HardLightBlend:
+		if cs <= 0.5 {
+			return cb * 2.0 * cs
+		}
+		return blendChannel(ScreenBlend, cb, 2.0*cs-1.0)
+	case SoftLightBlend:
+		if cs <= 0.5 {
+			return cb - (1.0-2.0*cs)*cb*(1.0-cb)
+		}
+		d := math.Sqrt(cb)
+		if cb <= 0.25 {
+			d = ((16.0*cb-12.0)*cb + 4.0) * cb
+		}
+		return cb + (2.0*cs-1.0)*(d-cb)
+	case DifferenceBlend:
+		return math.Abs(cb - cs)
+	case ExclusionBlend:
+		return cb + cs - 2.0*cb*cs
+	}
+	return cs
+}
+
+func lum(c [3]float64) float64 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func setLum(c [3]float64, l float64) [3]float64 {
+	d := l - lum(c)
+	for k := range c {
+		c[k] += d
+	}
+
+	// clip color
+	l = lum(c)
+	n := math.Min(c[0], math.Min(c[1], c[2]))
+	x := math.Max(c[0], math.Max(c[1], c[2]))
+	for k := range c {
+		if n < 0.0 {
+			c[k] = l + (c[k]-l)*l/(l-n)
+		}
+		if 1.0 < x {
+			c[k] = l + (c[k]-l)*(1.0-l)/(x-l)
+		}
+	}
+	return c
+}
+
+func sat(c [3]float64) float64 {
+	return math.Max(c[0], math.Max(c[1], c[2])) - math.Min(c[0], math.Min(c[1], c[2]))
+}
+
+func setSat(c [3]float64, s float64) [3]float64 {
+	// order the channels from minimum to maximum
+	min, mid, max := 0, 1, 2
+	if c[mid] < c[min] {
+		min, mid = mid, min
+	}
+	if c[max] < c[mid] {
+		mid, max = max, mid
+		if c[mid] < c[min] {
+			min, mid = mid, min
+		}
+	}
+
+	var r [3]float64
+	if c[min] < c[max] {
+		r[mid] = (c[mid] - c[min]) * s / (c[max] - c[min])
+		r[max] = s
+	}
+	return r
+}
@@ -0,0 +1,111 @@
+package canvas
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestGroup(t *testing.T) {
+	c := New(10, 10)
+	c.EndGroup() // no group
+	c.BeginGroup(0.5, MultiplyBlend)
+	c.SetFillColor(Red)
+	c.DrawPath(0, 0, Rectangle(6, 6))
+	c.BeginGroup(1.0, NormalBlend)
+	c.EndGroup() // empty group
+	c.DrawPath(4, 4, Rectangle(6, 6))
+	c.EndGroup()
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].Bounds(), Rect{0.0, 0.0, 10.0, 10.0})
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><g opacity=".5" style="mix-blend-mode:multiply"><path d="M0 10H6V4H0z" fill="#f00"/><path d="M4 6H10V0H4z" fill="#f00"/></g></svg>`)
+
+	pdfCompress = false
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "/Subtype /Form /BBox [0 0 10 10] /Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >>"), "transparency group XObject")
+	test.That(t, strings.Contains(s, "/B0 << /BM /Multiply /CA .5 /ca .5 >>"), "group graphics state")
+	test.That(t, strings.Contains(s, "q /B0 gs /Gr0 Do Q"), "group drawn")
+	pdfCompress = true
+
+	buf.Reset()
+	test.Error(t, c.writeEPS(buf))
+	test.T(t, strings.Count(buf.String(), "fill"), 2)
+
+	c.Fit(1.0)
+	test.T(t, c.layers[0].Bounds(), Rect{1.0, 1.0, 10.0, 10.0})
+}
+
+func TestGroupLink(t *testing.T) {
+	c := New(10, 10)
+	c.BeginGroup(0.5, NormalBlend)
+	c.SetFillColor(Red)
+	c.DrawPath(0, 0, Rectangle(6, 6))
+	c.DrawLinkRect(Rect{1, 2, 3, 4}, Link{URI: "https://example.com"})
+	c.EndGroup()
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WritePDF(buf))
+	test.That(t, strings.Contains(buf.String(), "/Annots [<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com) >> /Border [0 0 0] /F 4 /Rect [1 2 4 6] >>]"), "link in group")
+	pdfCompress = true
+}
+
+func TestGroupWriteImage(t *testing.T) {
+	// overlapping shapes in a group do not show seams
+	c := New(10, 10)
+	c.BeginGroup(0.5, NormalBlend)
+	c.SetFillColor(Black)
+	c.DrawPath(0, 0, Rectangle(6, 10))
+	c.DrawPath(4, 0, Rectangle(6, 10))
+	c.EndGroup()
+	img := c.WriteImage(1.0)
+	test.T(t, img.RGBAAt(2, 5), img.RGBAAt(5, 5))
+	test.T(t, img.RGBAAt(8, 5), color.RGBA{127, 127, 127, 255})
+
+	c = New(10, 10)
+	c.SetFillColor(Blue)
+	c.DrawPath(0, 0, Rectangle(5, 10))
+	c.BeginGroup(1.0, MultiplyBlend)
+	c.SetFillColor(Red)
+	c.DrawPath(0, 0, Rectangle(10, 10))
+	c.EndGroup()
+	img = c.WriteImage(1.0)
+	test.T(t, img.RGBAAt(2, 5), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(8, 5), color.RGBA{255, 0, 0, 255})
+}
+
+func TestBlend(t *testing.T) {
+	var tts = []struct {
+		mode   BlendMode
+		cb, cs [3]float64
+		c      [3]float64
+	}{
+		{NormalBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.5, 0.5, 0.5}},
+		{MultiplyBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.1, 0.2, 0.3}},
+		{ScreenBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.6, 0.7, 0.8}},
+		{OverlayBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.2, 0.4, 0.6}},
+		{DarkenBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.2, 0.4, 0.5}},
+		{LightenBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.5, 0.5, 0.6}},
+		{DifferenceBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.3, 0.1, 0.1}},
+		{ExclusionBlend, [3]float64{0.2, 0.4, 0.6}, [3]float64{0.5, 0.5, 0.5}, [3]float64{0.5, 0.5, 0.5}},
+		{LuminosityBlend, [3]float64{1.0, 0.0, 0.0}, [3]float64{0.5, 0.5, 0.5}, [3]float64{1.0, 2.0 / 7.0, 2.0 / 7.0}},
+		{ColorBlend, [3]float64{0.5, 0.5, 0.5}, [3]float64{1.0, 0.0, 0.0}, [3]float64{1.0, 2.0 / 7.0, 2.0 / 7.0}},
+		{SaturationBlend, [3]float64{0.5, 0.5, 0.5}, [3]float64{1.0, 0.0, 0.0}, [3]float64{0.5, 0.5, 0.5}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			c := blend(tt.mode, tt.cb, tt.cs)
+			for k := range c {
+				test.Float(t, c[k], tt.c[k])
+			}
+		})
+	}
+}
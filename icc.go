@@ -0,0 +1,100 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// sRGBProfile returns an ICC version 2 display profile of the sRGB IEC61966-2.1 color space, with the primaries adapted to the D50 illuminant of the profile connection space.
+func sRGBProfile() []byte {
+	s15Fixed16 := func(v float64) uint32 {
+		return uint32(int32(math.Round(v * 65536.0)))
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		binary.BigEndian.PutUint32(b[8:], s15Fixed16(x))
+		binary.BigEndian.PutUint32(b[12:], s15Fixed16(y))
+		binary.BigEndian.PutUint32(b[16:], s15Fixed16(z))
+		return b
+	}
+
+	desc := []byte("sRGB IEC61966-2.1\x00")
+	descTag := make([]byte, 12, 12+len(desc)+4+4+2+1+67)
+	copy(descTag, "desc")
+	binary.BigEndian.PutUint32(descTag[8:], uint32(len(desc)))
+	descTag = append(descTag, desc...)
+	descTag = append(descTag, make([]byte, 4+4+2+1+67)...) // no Unicode and ScriptCode descriptions
+
+	cprtTag := append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)
+
+	// tone reproduction curve of sRGB, sampled at 1024 points
+	trcTag := make([]byte, 12+2*1024)
+	copy(trcTag, "curv")
+	binary.BigEndian.PutUint32(trcTag[8:], 1024)
+	for i := 0; i < 1024; i++ {
+		v := float64(i) / 1023.0
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(trcTag[12+2*i:], uint16(math.Round(v*65535.0)))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", descTag},
+		{"cprt", cprtTag},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trcTag},
+		{"gTRC", trcTag},
+		{"bTRC", trcTag},
+	}
+
+	// tag data is aligned at four bytes, the tone reproduction curves share their data
+	table := &bytes.Buffer{}
+	data := &bytes.Buffer{}
+	offset := 128 + 4 + 12*len(tags)
+	binary.Write(table, binary.BigEndian, uint32(len(tags)))
+	trcOffset := 0
+	for _, tag := range tags {
+		tagOffset := offset + data.Len()
+		if tag.sig[1:] == "TRC" {
+			if trcOffset == 0 {
+				trcOffset = tagOffset
+				data.Write(tag.data)
+			}
+			tagOffset = trcOffset
+		} else {
+			data.Write(tag.data)
+		}
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+		table.WriteString(tag.sig)
+		binary.Write(table, binary.BigEndian, uint32(tagOffset))
+		binary.Write(table, binary.BigEndian, uint32(len(tag.data)))
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+table.Len()+data.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntrRGB XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2000) // creation date of 2000-01-01
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	binary.BigEndian.PutUint32(header[68:], s15Fixed16(0.9642)) // D50 illuminant
+	binary.BigEndian.PutUint32(header[72:], s15Fixed16(1.0))
+	binary.BigEndian.PutUint32(header[76:], s15Fixed16(0.8249))
+
+	b := append(header, table.Bytes()...)
+	return append(b, data.Bytes()...)
+}
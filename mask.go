@@ -0,0 +1,68 @@
+package canvas
+
+import (
+	"image"
+)
+
+// MaskType is the channel of a mask that determines the visibility of what is drawn under it.
+type MaskType int
+
+// see MaskType
+const (
+	AlphaMask MaskType = iota
+	LuminosityMask
+)
+
+// Mask is a group of drawing operations that is used as a soft mask by Canvas.SetMask. For an AlphaMask the opacity of the contents determines the visibility of the masked drawing operations, for a LuminosityMask the luminance of the contents over a black backdrop is used. Anything outside of the contents is invisible. Draw onto the mask using the methods of its Canvas, with the origin of the mask at (0,0). The size of the canvas is not used.
+type Mask struct {
+	*Canvas
+	Type MaskType
+}
+
+// NewMask returns a new empty mask of the given type.
+func NewMask(typ MaskType) *Mask {
+	return &Mask{New(0.0, 0.0), typ}
+}
+
+// Bounds returns the bounding box of the contents of the mask in mask coordinates.
+func (mask *Mask) Bounds() Rect {
+	return layersBounds(mask.layers)
+}
+
+// alpha returns the mask values over an image of the given size, with m mapping mask coordinates to pixel coordinates.
+func (mask *Mask) alpha(w, h int, m Matrix) *image.Alpha {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	writeImageLayers(img, mask.layers, m)
+
+	alpha := image.NewAlpha(img.Bounds())
+	for k := range alpha.Pix {
+		if mask.Type == LuminosityMask {
+			// colors are premultiplied, which equals compositing over a black backdrop
+			lum := lum([3]float64{float64(img.Pix[4*k]), float64(img.Pix[4*k+1]), float64(img.Pix[4*k+2])})
+			alpha.Pix[k] = uint8(lum + 0.5)
+		} else {
+			alpha.Pix[k] = img.Pix[4*k+3]
+		}
+	}
+	return alpha
+}
+
+// SetMask sets the soft mask in the current view, the visibility of subsequent drawing operations is modulated by the mask. The mask is intersected with any clipping path or mask that was set before, use PushState and PopState to restore a previous mask. The contents of the mask must be drawn before writing the canvas. Masks are not supported by EPS.
+func (c *Canvas) SetMask(mask *Mask) {
+	for font := range mask.fonts {
+		c.fonts[font] = true
+	}
+	clip := clipPath{mask: mask, m: c.m}
+	c.clips = append(c.clips[:len(c.clips):len(c.clips)], clip)
+}
+
+// ResetMask removes the masks so that drawing operations are no longer masked. Clipping paths set by SetClipPath remain.
+func (c *Canvas) ResetMask() {
+	var clips []clipPath
+	for _, clip := range c.clips {
+		if clip.mask == nil {
+			clips = append(clips, clip)
+		}
+	}
+	c.clips = clips
+}
@@ -0,0 +1,78 @@
+package canvas
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestMask(t *testing.T) {
+	mask := NewMask(AlphaMask)
+	mask.SetFillColor(Black)
+	mask.DrawPath(0, 0, Rectangle(5, 10))
+	test.T(t, mask.Bounds(), Rect{0.0, 0.0, 5.0, 10.0})
+
+	c := New(10, 10)
+	c.SetView(Identity.Translate(1, 0))
+	c.SetMask(mask)
+	c.SetFillColor(Red)
+	c.DrawPath(-1, 0, Rectangle(10, 10))
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><mask id="m0" maskUnits="userSpaceOnUse" x="1" y="0" width="5" height="10" style="mask-type:alpha"><g transform="matrix(1,0,0,1,1,10)"><path d="M0 0H5V-10H0z"/></g></mask></defs><g mask="url(#m0)"><path d="M0 10H10V0H0z" fill="#f00"/></g></svg>`)
+
+	// the mask definition is reused
+	c2 := New(10, 10)
+	c2.SetMask(mask)
+	c2.DrawPath(0, 0, Rectangle(10, 10))
+	c2.ResetMask()
+	c2.DrawPath(0, 0, Rectangle(5, 5))
+	c2.SetMask(mask)
+	c2.DrawPath(0, 0, Rectangle(5, 5))
+	buf.Reset()
+	c2.WriteSVG(buf)
+	test.T(t, strings.Count(buf.String(), "<mask "), 1)
+	test.T(t, strings.Count(buf.String(), `mask="url(#m0)"`), 2)
+
+	pdfCompress = false
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "/Subtype /Form /BBox [0 0 5 10] /Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >> /Length 38 /Matrix [1 0 0 1 1 0]"), "mask form XObject")
+	test.That(t, strings.Contains(s, "/M0 << /SMask << /Type /Mask /G 1 0 R /S /Alpha >> >>"), "soft mask graphics state")
+	test.That(t, strings.Contains(s, "q /M0 gs 1 0 0 rg"), "soft mask set")
+	pdfCompress = true
+
+	c.ResetClipPath()
+	test.T(t, len(c.clips), 1)
+	c.ResetMask()
+	test.T(t, len(c.clips), 0)
+}
+
+func TestMaskWriteImage(t *testing.T) {
+	mask := NewMask(LuminosityMask)
+	mask.SetFillColor(White)
+	mask.DrawPath(0, 0, Rectangle(5, 10))
+	mask.SetFillColor(color.RGBA{128, 128, 128, 255})
+	mask.DrawPath(5, 0, Rectangle(3, 10))
+
+	c := New(10, 10)
+	c.SetMask(mask)
+	c.SetFillColor(Black)
+	c.DrawPath(0, 0, Rectangle(10, 10))
+	img := c.WriteImage(1.0)
+	test.T(t, img.RGBAAt(2, 5), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(6, 5), color.RGBA{127, 127, 127, 255})
+	test.T(t, img.RGBAAt(9, 5), color.RGBA{255, 255, 255, 255})
+
+	// empty masks hide everything
+	c = New(10, 10)
+	c.SetMask(NewMask(AlphaMask))
+	c.SetFillColor(Black)
+	c.DrawPath(0, 0, Rectangle(10, 10))
+	test.T(t, c.WriteImage(1.0).RGBAAt(5, 5), color.RGBA{255, 255, 255, 255})
+}
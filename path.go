@@ -562,6 +562,66 @@ func (p *Path) Length() float64 {
 	return d
 }
 
+// PointAt returns the position at distance d along the path. The distance is clamped between zero and the length of the path, and MoveTo commands do not add to the distance. It returns the origin for a path without length.
+func (p *Path) PointAt(d float64) Point {
+	segs := p.arcSegments()
+	if len(segs) == 0 {
+		return Point{}
+	}
+	seg, t := arcSegmentAt(segs, d)
+	return seg.pos(t)
+}
+
+// TangentAt returns the unit tangent in the direction of the path at distance d along the path, see PointAt. At the joint between two segments it returns the tangent of the latter.
+func (p *Path) TangentAt(d float64) Point {
+	segs := p.arcSegments()
+	if len(segs) == 0 {
+		return Point{}
+	}
+	seg, t := arcSegmentAt(segs, d)
+	return seg.direction(t)
+}
+
+// NormalAt returns the unit normal at distance d along the path, see PointAt. It points to the right-hand side of the path, ie. it is the tangent rotated by 90 degrees clockwise.
+func (p *Path) NormalAt(d float64) Point {
+	return p.TangentAt(d).Rot90CW()
+}
+
+// CurvatureAt returns the curvature at distance d along the path, see PointAt, which is the reciprocal of the radius of curvature. It is positive when the path bends counter clockwise and negative when it bends clockwise.
+func (p *Path) CurvatureAt(d float64) float64 {
+	segs := p.arcSegments()
+	if len(segs) == 0 {
+		return 0.0
+	}
+	seg, t := arcSegmentAt(segs, d)
+	dp := seg.deriv(t)
+	if dp.IsZero() {
+		return 0.0
+	}
+	return dp.PerpDot(seg.deriv2(t)) / math.Pow(dp.Dot(dp), 1.5)
+}
+
+// Sample returns n points that are evenly spaced by their distance along the path, the first and last points are the start and end of the path. It returns no points for a path without length.
+func (p *Path) Sample(n int) []Point {
+	segs := p.arcSegments()
+	if n <= 0 || len(segs) == 0 {
+		return []Point{}
+	} else if n == 1 {
+		return []Point{segs[0].start}
+	}
+
+	length := 0.0
+	for _, seg := range segs {
+		length += seg.length
+	}
+	points := make([]Point, n)
+	for i := range points {
+		seg, t := arcSegmentAt(segs, length*float64(i)/float64(n-1))
+		points[i] = seg.pos(t)
+	}
+	return points
+}
+
 // Transform transform the path by the given transformation matrix and returns a new path.
 func (p *Path) Transform(m Matrix) *Path {
 	p = p.Copy()
@@ -649,6 +709,61 @@ func (p *Path) Flatten() *Path {
 	return p.Copy().Replace(nil, flattenCubicBezier, flattenEllipse)
 }
 
+// Simplify removes vertices from runs of consecutive line segments using the Ramer-Douglas-Peucker algorithm, such that the simplified lines deviate at most tolerance from the original lines. Other segments and the start and end points of each run are kept.
+func (p *Path) Simplify(tolerance float64) *Path {
+	q := &Path{}
+	var run []Point
+	flush := func() {
+		if 1 < len(run) {
+			for _, pos := range ramerDouglasPeucker(run, tolerance)[1:] {
+				q.LineTo(pos.X, pos.Y)
+			}
+		}
+	}
+
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		end := Point{p.d[i-2], p.d[i-1]}
+		switch cmd {
+		case lineToCmd:
+			if len(run) == 0 {
+				run = append(run, start)
+			}
+			run = append(run, end)
+		case closeCmd:
+			if len(run) == 0 {
+				run = append(run, start)
+			}
+			run = append(run, end)
+			points := ramerDouglasPeucker(run, tolerance)
+			for _, pos := range points[1 : len(points)-1] {
+				q.LineTo(pos.X, pos.Y)
+			}
+			q.Close()
+			run = run[:0]
+		default:
+			flush()
+			run = run[:0]
+			switch cmd {
+			case moveToCmd:
+				q.MoveTo(end.X, end.Y)
+			case quadToCmd:
+				q.QuadTo(p.d[i-4], p.d[i-3], end.X, end.Y)
+			case cubeToCmd:
+				q.CubeTo(p.d[i-6], p.d[i-5], p.d[i-4], p.d[i-3], end.X, end.Y)
+			case arcToCmd:
+				largeArc, sweep := fromArcFlags(p.d[i-3])
+				q.ArcTo(p.d[i-6], p.d[i-5], p.d[i-4]*180.0/math.Pi, largeArc, sweep, end.X, end.Y)
+			}
+		}
+		start = end
+	}
+	flush()
+	return q
+}
+
 // Replace replaces path segments by their respective functions, each returning the path that will replace the segment or nil if no replacement is to be performed.
 // The line function will take the start and end points. The bezier function will take the start point, control point 1 and 2, and the end point (ie. a cubic Bézier, quadratic Béziers will be implicitly converted to cubic ones). The arc function will take a start point, the major and minor radii, the radial rotaton counter clockwise, the largeArc and sweep booleans, and the end point.
 // Be aware this will change the path inplace. Changing the end point of one path will subsequently change the start point of the next segment. Returning nil has no effect on the path.
@@ -1066,6 +1181,78 @@ func (p *Path) Dash(offset float64, d ...float64) *Path {
 	return q
 }
 
+// Hatch returns the hatch lines that fill the path, such as for plotter output. The lines are placed at the given angle in degrees counter-clockwise from the x-axis, spaced by distance and aligned with the origin so that hatches of adjacent paths line up. The lines are clipped to the interior of the path, which depends on the FillRule. All subpaths are considered to be closed.
+func (p *Path) Hatch(angle, distance float64) *Path {
+	q := &Path{}
+	if p.Empty() || distance <= 0.0 {
+		return q
+	}
+
+	// hatch horizontally in a rotated coordinate system
+	type edge struct {
+		a, b Point
+	}
+	edges := []edge{}
+	var start, pos Point
+	flat := p.Transform(Identity.Rotate(-angle)).Flatten()
+	for i := 0; i < len(flat.d); {
+		cmd := flat.d[i]
+		i += cmdLen(cmd)
+		end := Point{flat.d[i-2], flat.d[i-1]}
+		if cmd == moveToCmd {
+			if pos != start {
+				edges = append(edges, edge{pos, start})
+			}
+			start = end
+		} else {
+			edges = append(edges, edge{pos, end})
+		}
+		pos = end
+	}
+	if pos != start {
+		edges = append(edges, edge{pos, start})
+	}
+
+	type crossing struct {
+		x       float64
+		winding int
+	}
+	bounds := flat.Bounds()
+	m := Identity.Rotate(angle)
+	for y := math.Ceil(bounds.Y/distance) * distance; y <= bounds.Y+bounds.H; y += distance {
+		crossings := []crossing{}
+		for _, e := range edges {
+			if e.a.Y <= y && y < e.b.Y || e.b.Y <= y && y < e.a.Y {
+				x := e.a.X + (y-e.a.Y)/(e.b.Y-e.a.Y)*(e.b.X-e.a.X)
+				winding := 1
+				if e.b.Y < e.a.Y {
+					winding = -1
+				}
+				crossings = append(crossings, crossing{x, winding})
+			}
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+		count := 0
+		for i := 0; i+1 < len(crossings); i++ {
+			count += crossings[i].winding
+			if FillRule == NonZero && count != 0 || FillRule == EvenOdd && count%2 != 0 {
+				x0, x1 := crossings[i].x, crossings[i+1].x
+				if equal(x0, x1) {
+					continue
+				}
+				p0, p1 := m.Dot(Point{x0, y}), m.Dot(Point{x1, y})
+				if 0 < len(q.d) && q.Pos().Equals(p0) {
+					q.LineTo(p1.X, p1.Y)
+				} else {
+					q.MoveTo(p0.X, p0.Y).LineTo(p1.X, p1.Y)
+				}
+			}
+		}
+	}
+	return q
+}
+
 // Reverse returns a new path that is the same path as p but in the reverse direction.
 func (p *Path) Reverse() *Path {
 	ip := &Path{}
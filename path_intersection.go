@@ -0,0 +1,916 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// And returns the boolean path operation of path p and q, resulting in the intersection of both paths, ie. the area that is filled by both p and q. Curves are flattened using Tolerance and the interior of each path is determined by FillRule, open subpaths are closed implicitly. The result consists of closed subpaths that do not overlap, with filled areas winding counter clockwise and holes winding clockwise.
+func (p *Path) And(q *Path) *Path {
+	return boolean(p, q, booleanAnd, FillRule)
+}
+
+// Or returns the boolean path operation of path p and q, resulting in the union of both paths, ie. the area that is filled by either p or q. See And.
+func (p *Path) Or(q *Path) *Path {
+	return boolean(p, q, booleanOr, FillRule)
+}
+
+// Xor returns the boolean path operation of path p and q, resulting in the exclusive union of both paths, ie. the area that is filled by either p or q but not by both. See And.
+func (p *Path) Xor(q *Path) *Path {
+	return boolean(p, q, booleanXor, FillRule)
+}
+
+// Not returns the boolean path operation of path p and q, resulting in the difference of both paths, ie. the area that is filled by p but not by q. See And.
+func (p *Path) Not(q *Path) *Path {
+	return boolean(p, q, booleanNot, FillRule)
+}
+
+// Settle returns a path without self-intersections or overlapping subpaths that fills the same area as p, where the interior of p is determined by FillRule. Curves that are cut are split at the intersections. The result consists of closed subpaths with filled areas winding counter clockwise and holes winding clockwise.
+func (p *Path) Settle() *Path {
+	return boolean(p, &Path{}, booleanOr, FillRule)
+}
+
+// selfIntersects returns true if any of the (flattened) segments of p cross or overlap, end points touching excluded.
+func (p *Path) selfIntersects() bool {
+	segs := booleanSegments(make([]booleanSegment, 0, len(p.d)/cmdLen(lineToCmd)), &[]booleanCurve{}, p, 1, 0)
+	return booleanSplitSegments(segs)
+}
+
+// settle returns p if it does not intersect itself, and otherwise the path without self-intersections and overlaps as for Settle, where the interior of p is determined by fillRule.
+func settle(p *Path, fillRule FillRuleType) *Path {
+	curves := []booleanCurve{}
+	segs := booleanSegments(make([]booleanSegment, 0, len(p.d)/cmdLen(lineToCmd)), &curves, p, 1, 0)
+	if !booleanSplitSegments(segs) {
+		return p
+	}
+	return booleanResult(segs, curves, booleanOr, fillRule)
+}
+
+type booleanOp int
+
+const (
+	booleanAnd booleanOp = iota
+	booleanOr
+	booleanXor
+	booleanNot
+)
+
+// booleanSnap is the grid size to which vertices are snapped in order to merge (nearly) coinciding vertices.
+const booleanSnap = 1e-7
+
+// booleanPositive is an internal fill rule that fills areas with a positive winding number only, it is used to remove the inverted loops of offset paths.
+const booleanPositive FillRuleType = -1
+
+// booleanCurve is a curved path segment that has been flattened into line segments. Successive edges of the same curve in the result are restored to the part of the original curve between their curve parameters.
+type booleanCurve struct {
+	seg pathSegment
+	d   []float64 // path command
+}
+
+// booleanSegment is a line segment of either path, the weights are the winding contributions to path p and q respectively. Line segments originating from a curve refer to the curve and the curve parameters at their end points.
+type booleanSegment struct {
+	a, b   Point
+	wp, wq int
+	curve  int
+	ta, tb float64
+	splits []Point
+}
+
+// booleanEdge is a unique edge between two vertices after all segments have been split at their intersections. Segments that coincide are merged and their weights added.
+type booleanEdge struct {
+	u, v   int     // vertex indices with u < v
+	wp, wq int     // weights in the direction from u to v
+	curve  int     // originating curve, or -1
+	tu, tv float64 // curve parameters at u and v
+	used   bool
+}
+
+func boolean(p, q *Path, op booleanOp, fillRule FillRuleType) *Path {
+	curves := []booleanCurve{}
+	segs := make([]booleanSegment, 0, (len(p.d)+len(q.d))/cmdLen(lineToCmd))
+	segs = booleanSegments(segs, &curves, p, 1, 0)
+	segs = booleanSegments(segs, &curves, q, 0, 1)
+	booleanSplitSegments(segs)
+	return booleanResult(segs, curves, op, fillRule)
+}
+
+// booleanResult returns the boundary of the area for which the boolean operation holds, given the segments that have been split at their intersections.
+func booleanResult(segs []booleanSegment, curves []booleanCurve, op booleanOp, fillRule FillRuleType) *Path {
+	// create vertices and unique edges
+	vertices := make([]Point, 0, len(segs))
+	vertexIDs := make(map[[2]int64]int, len(segs))
+	vertex := func(p Point) int {
+		key := [2]int64{int64(math.Round(p.X / booleanSnap)), int64(math.Round(p.Y / booleanSnap))}
+		if id, ok := vertexIDs[key]; ok {
+			return id
+		}
+		vertexIDs[key] = len(vertices)
+		vertices = append(vertices, p)
+		return len(vertices) - 1
+	}
+
+	n := len(segs)
+	for _, seg := range segs {
+		n += len(seg.splits)
+	}
+	edges := make([]booleanEdge, 0, n)
+	edgeIDs := make(map[[2]int]int, n)
+	last, lastID := Point{}, -1
+	for _, seg := range segs {
+		d := seg.b.Sub(seg.a)
+		prev, tprev := lastID, seg.ta
+		if prev == -1 || seg.a != last {
+			prev = vertex(seg.a) // successive segments mostly share their end points
+		}
+		for j := 0; j <= len(seg.splits); j++ {
+			var cur int
+			var tcur float64
+			if j < len(seg.splits) {
+				// curve parameters of the split points are interpolated along the line segment
+				cur = vertex(seg.splits[j])
+				tcur = seg.ta + (seg.tb-seg.ta)*seg.splits[j].Sub(seg.a).Dot(d)/d.Dot(d)
+			} else {
+				cur, tcur = vertex(seg.b), seg.tb
+				last, lastID = seg.b, cur
+			}
+			if cur == prev {
+				continue
+			}
+			u, v, tu, tv, sign := prev, cur, tprev, tcur, 1
+			if v < u {
+				u, v, tu, tv, sign = v, u, tv, tu, -1
+			}
+			id, ok := edgeIDs[[2]int{u, v}]
+			if !ok {
+				id = len(edges)
+				edgeIDs[[2]int{u, v}] = id
+				edges = append(edges, booleanEdge{u: u, v: v, curve: seg.curve, tu: tu, tv: tv})
+			} else {
+				edges[id].curve = -1 // coinciding segments
+			}
+			edges[id].wp += sign * seg.wp
+			edges[id].wq += sign * seg.wq
+			prev, tprev = cur, tcur
+		}
+	}
+
+	// remove edges that cancel out
+	k := 0
+	for _, edge := range edges {
+		if edge.wp != 0 || edge.wq != 0 {
+			edges[k] = edge
+			k++
+		}
+	}
+	edges = edges[:k]
+
+	inside := func(w int) bool {
+		if fillRule == NonZero {
+			return w != 0
+		} else if fillRule == booleanPositive {
+			return 0 < w
+		}
+		return w%2 != 0
+	}
+	result := func(wp, wq int) bool {
+		switch op {
+		case booleanAnd:
+			return inside(wp) && inside(wq)
+		case booleanOr:
+			return inside(wp) || inside(wq)
+		case booleanXor:
+			return inside(wp) != inside(wq)
+		}
+		return inside(wp) && !inside(wq)
+	}
+
+	// select the edges at the boundary of the result, oriented so that the interior is to the left
+	boundary := make([]booleanEdge, 0, len(edges))
+	windings := booleanWindings(vertices, edges)
+	for i, edge := range edges {
+		w := windings[i]
+		left, right := result(w[0], w[1]), result(w[2], w[3])
+		if left == right {
+			continue
+		} else if !left {
+			edge.u, edge.v = edge.v, edge.u
+			edge.tu, edge.tv = edge.tv, edge.tu
+		}
+		boundary = append(boundary, edge)
+	}
+	return booleanContours(vertices, boundary, curves)
+}
+
+// booleanSegments appends the line segments of the path to segs, flattening curves and implicitly closing each subpath. Curves are appended to curves.
+func booleanSegments(segs []booleanSegment, curves *[]booleanCurve, p *Path, wp, wq int) []booleanSegment {
+	points, ts := []Point{}, []float64{}
+	for _, ps := range p.Split() {
+		if ps.Empty() {
+			continue
+		}
+
+		var first Point
+		i := 0
+		if ps.d[0] == moveToCmd {
+			first = Point{ps.d[1], ps.d[2]}
+			i += cmdLen(moveToCmd)
+		}
+		start := first
+		for _, pathSeg := range ps.segments() {
+			cmd := ps.d[i]
+			i += cmdLen(cmd)
+			end := pathSeg.end
+			if pathSeg.isLine() {
+				if !start.Equals(end) {
+					segs = append(segs, booleanSegment{a: start, b: end, wp: wp, wq: wq, curve: -1})
+				}
+			} else {
+				points, ts = append(points[:0], start), append(ts[:0], 0.0)
+				for _, t := range pathSeg.flatten()[1:] {
+					if point := pathSeg.pos(t); !point.Equals(points[len(points)-1]) {
+						points = append(points, point)
+						ts = append(ts, t)
+					}
+				}
+				points[len(points)-1], ts[len(ts)-1] = end, 1.0
+
+				curve := len(*curves)
+				*curves = append(*curves, booleanCurve{pathSeg, ps.d[i-cmdLen(cmd) : i]})
+				for j := 1; j < len(points); j++ {
+					segs = append(segs, booleanSegment{a: points[j-1], b: points[j], wp: wp, wq: wq, curve: curve, ta: ts[j-1], tb: ts[j]})
+				}
+			}
+			start = end
+		}
+		if !start.Equals(first) {
+			segs = append(segs, booleanSegment{a: start, b: first, wp: wp, wq: wq, curve: -1})
+		}
+	}
+	return segs
+}
+
+// booleanSplitSegments finds all intersections between the segments and adds them as split points to the segments, sorted along each segment. It returns true if any segment has been split.
+func booleanSplitSegments(segs []booleanSegment) bool {
+	if len(segs) == 0 {
+		return false
+	}
+
+	// order the segments by their left-most point, sorting indices is much cheaper than sorting the segments themselves
+	order := make([]int, len(segs))
+	left := make([]float64, len(segs))
+	for i, seg := range segs {
+		order[i] = i
+		left[i] = math.Min(seg.a.X, seg.b.X)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return left[order[i]] < left[order[j]]
+	})
+	sorted := make([]booleanSegment, len(segs))
+	for k, i := range order {
+		sorted[k] = segs[i]
+		order[k] = k
+	}
+	copy(segs, sorted)
+
+	// sweep along the axis in which the segments are spread out the most, so that few segments overlap in their sweep range
+	xmin, xmax, ymin, ymax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, seg := range segs {
+		xmin, xmax = math.Min(xmin, math.Min(seg.a.X, seg.b.X)), math.Max(xmax, math.Max(seg.a.X, seg.b.X))
+		ymin, ymax = math.Min(ymin, math.Min(seg.a.Y, seg.b.Y)), math.Max(ymax, math.Max(seg.a.Y, seg.b.Y))
+	}
+	vertical := xmax-xmin < ymax-ymin
+
+	// ranges holds the range of each segment along the sweep axis followed by the range along the other axis
+	ranges := make([][4]float64, len(segs))
+	for i, seg := range segs {
+		a, b := seg.a, seg.b
+		if vertical {
+			a, b = Point{a.Y, a.X}, Point{b.Y, b.X}
+		}
+		ranges[i] = [4]float64{math.Min(a.X, b.X), math.Max(a.X, b.X), math.Min(a.Y, b.Y), math.Max(a.Y, b.Y)}
+	}
+	if vertical {
+		sort.Slice(order, func(i, j int) bool {
+			return ranges[order[i]][0] < ranges[order[j]][0]
+		})
+	}
+
+	for k, i := range order {
+		a := ranges[i]
+		for _, j := range order[k+1:] {
+			b := ranges[j]
+			if a[1]+booleanSnap < b[0] {
+				break
+			} else if a[3]+booleanSnap < b[2] || b[3]+booleanSnap < a[2] {
+				continue
+			}
+			intersectSegments(&segs[i], &segs[j])
+		}
+	}
+
+	split := false
+	for i := range segs {
+		seg := &segs[i]
+		if len(seg.splits) == 0 {
+			continue
+		}
+		split = true
+		// insertion sort along the segment, segments are split only a few times
+		d := seg.b.Sub(seg.a)
+		for j := 1; j < len(seg.splits); j++ {
+			for k := j; 0 < k && seg.splits[k].Sub(seg.a).Dot(d) < seg.splits[k-1].Sub(seg.a).Dot(d); k-- {
+				seg.splits[k], seg.splits[k-1] = seg.splits[k-1], seg.splits[k]
+			}
+		}
+	}
+	return split
+}
+
+// intersectSegments adds the intersection points of both segments as split points, excluding their end points. Collinear segments are split at the end points of the other segment.
+func intersectSegments(a, b *booleanSegment) {
+	const eps = 1e-9
+	r := a.b.Sub(a.a)
+	s := b.b.Sub(b.a)
+	qp := b.a.Sub(a.a)
+	denom := r.PerpDot(s)
+	if math.Abs(denom) <= eps*r.Length()*s.Length() {
+		if booleanSnap < math.Abs(qp.PerpDot(r))/r.Length() {
+			return // parallel
+		}
+
+		// collinear
+		onSegment := func(seg *booleanSegment, p Point) {
+			d := seg.b.Sub(seg.a)
+			t := p.Sub(seg.a).Dot(d) / d.Dot(d)
+			if eps < t && t < 1.0-eps && !p.Equals(seg.a) && !p.Equals(seg.b) {
+				seg.splits = append(seg.splits, p)
+			}
+		}
+		onSegment(a, b.a)
+		onSegment(a, b.b)
+		onSegment(b, a.a)
+		onSegment(b, a.b)
+		return
+	}
+
+	t := qp.PerpDot(s) / denom
+	u := qp.PerpDot(r) / denom
+	if t < -eps || 1.0+eps < t || u < -eps || 1.0+eps < u {
+		return
+	}
+
+	// prefer existing end points to avoid numerical errors
+	var p Point
+	tEnd := t < eps || 1.0-eps < t
+	uEnd := u < eps || 1.0-eps < u
+	if t < eps {
+		p = a.a
+	} else if 1.0-eps < t {
+		p = a.b
+	} else if u < eps {
+		p = b.a
+	} else if 1.0-eps < u {
+		p = b.b
+	} else {
+		p = a.a.Add(r.Mul(t))
+	}
+	if !tEnd {
+		a.splits = append(a.splits, p)
+	}
+	if !uEnd {
+		b.splits = append(b.splits, p)
+	}
+}
+
+// booleanWindings returns the winding numbers for path p and q to the left and right of each edge. The faces of the arrangement of edges are traced, and since the windings at either side of an edge differ by its weights, the windings are propagated from face to face. Only for the outer face of each connected set of edges a ray is cast to find its windings.
+func booleanWindings(vertices []Point, edges []booleanEdge) [][4]int {
+	// half-edge 2*i runs from u to v of edge i and half-edge 2*i+1 runs back from v to u
+	start := func(h int) int {
+		if h%2 == 0 {
+			return edges[h/2].u
+		}
+		return edges[h/2].v
+	}
+
+	// sort the outgoing half-edges of each vertex counter clockwise, the order of two half-edges is always counter clockwise
+	angles := make([]float64, 2*len(edges))
+	outgoing := booleanAdjacency(len(vertices), len(angles), start)
+	index := make([]int, 2*len(edges)) // index of the half-edge in the outgoing half-edges of its start vertex
+	for _, hs := range outgoing {
+		if 2 < len(hs) {
+			for _, h := range hs {
+				d := vertices[start(h^1)].Sub(vertices[start(h)])
+				angles[h] = math.Atan2(d.Y, d.X)
+			}
+			// insertion sort, vertices have few half-edges
+			for i := 1; i < len(hs); i++ {
+				for j := i; 0 < j && angles[hs[j]] < angles[hs[j-1]]; j-- {
+					hs[j], hs[j-1] = hs[j-1], hs[j]
+				}
+			}
+		}
+		for k, h := range hs {
+			index[h] = k
+		}
+	}
+
+	// trace the faces, each face lies to the left of its half-edges
+	faces := make([]int, 2*len(edges))
+	for h := range faces {
+		faces[h] = -1
+	}
+	nfaces := 0
+	for h := range faces {
+		if faces[h] != -1 {
+			continue
+		}
+		face := nfaces
+		nfaces++
+		for g := h; faces[g] == -1; {
+			faces[g] = face
+
+			// continue with the half-edge that turns the most to the right
+			hs := outgoing[start(g^1)]
+			g = hs[(index[g^1]+len(hs)-1)%len(hs)]
+		}
+	}
+
+	// visit the vertices from left to right, the first vertex of a connected set of edges is to the right of its outer face
+	order := make([]int, len(vertices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := vertices[order[i]], vertices[order[j]]
+		return a.X < b.X || a.X == b.X && a.Y < b.Y
+	})
+
+	halfEdges := booleanAdjacency(nfaces, len(faces), func(h int) int {
+		return faces[h]
+	})
+
+	// divide the vertical extent into bands that hold the edges passing through them, so that a ray only needs to check the edges of its band
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	for _, vertex := range vertices {
+		ymin, ymax = math.Min(ymin, vertex.Y), math.Max(ymax, vertex.Y)
+	}
+	nbands := int(math.Sqrt(float64(len(edges)))) + 1
+	band := func(y float64) int {
+		if ymax <= ymin {
+			return 0
+		}
+		return int(math.Min(float64(nbands-1), float64(nbands)*(y-ymin)/(ymax-ymin)))
+	}
+	offsets := make([]int, nbands+1)
+	for _, edge := range edges {
+		a, b := vertices[edge.u].Y, vertices[edge.v].Y
+		for k, kmax := band(math.Min(a, b)), band(math.Max(a, b)); k <= kmax; k++ {
+			offsets[k+1]++
+		}
+	}
+	for k := 0; k < nbands; k++ {
+		offsets[k+1] += offsets[k]
+	}
+	bands := make([]int, offsets[nbands])
+	fill := append([]int{}, offsets[:nbands]...)
+	for i, edge := range edges {
+		a, b := vertices[edge.u].Y, vertices[edge.v].Y
+		for k, kmax := band(math.Min(a, b)), band(math.Max(a, b)); k <= kmax; k++ {
+			bands[fill[k]] = i
+			fill[k]++
+		}
+	}
+
+	known := make([]bool, nfaces)
+	windings := make([][2]int, nfaces)
+	for _, i := range order {
+		if len(outgoing[i]) == 0 {
+			continue
+		}
+
+		// the outer face is left of the half-edge with the largest angle
+		hs := outgoing[i]
+		outer := faces[hs[len(hs)-1]]
+		if len(hs) == 2 {
+			a, b := vertices[start(hs[0]^1)].Sub(vertices[i]), vertices[start(hs[1]^1)].Sub(vertices[i])
+			if math.Atan2(b.Y, b.X) < math.Atan2(a.Y, a.X) {
+				outer = faces[hs[0]]
+			}
+		}
+		if known[outer] {
+			continue
+		}
+
+		// cast a ray to the left and count the windings of the edges crossed
+		p := vertices[i]
+		k := band(p.Y)
+		for _, j := range bands[offsets[k]:offsets[k+1]] {
+			edge := edges[j]
+			a, b := vertices[edge.u], vertices[edge.v]
+			if edge.u == i || edge.v == i || (a.Y <= p.Y) == (b.Y <= p.Y) || p.X <= a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+				continue
+			} else if b.Y < a.Y {
+				windings[outer][0] += edge.wp
+				windings[outer][1] += edge.wq
+			} else {
+				windings[outer][0] -= edge.wp
+				windings[outer][1] -= edge.wq
+			}
+		}
+
+		// propagate the windings to the adjacent faces
+		known[outer] = true
+		queue := []int{outer}
+		for 0 < len(queue) {
+			face := queue[0]
+			queue = queue[1:]
+			for _, h := range halfEdges[face] {
+				if other := faces[h^1]; !known[other] {
+					edge := edges[h/2]
+					sign := 1
+					if h%2 == 1 {
+						sign = -1
+					}
+					windings[other][0] = windings[face][0] - sign*edge.wp
+					windings[other][1] = windings[face][1] - sign*edge.wq
+					known[other] = true
+					queue = append(queue, other)
+				}
+			}
+		}
+	}
+
+	ws := make([][4]int, len(edges))
+	for i := range edges {
+		left, right := windings[faces[2*i]], windings[faces[2*i+1]]
+		ws[i] = [4]int{left[0], left[1], right[0], right[1]}
+	}
+	return ws
+}
+
+// booleanAdjacency returns the items that belong to each of the n nodes, where node returns the node of each of the m items.
+func booleanAdjacency(n, m int, node func(int) int) [][]int {
+	offsets := make([]int, n+1)
+	for i := 0; i < m; i++ {
+		offsets[node(i)+1]++
+	}
+	for i := 0; i < n; i++ {
+		offsets[i+1] += offsets[i]
+	}
+	items := make([]int, m)
+	adjacency := make([][]int, n)
+	for i := range adjacency {
+		adjacency[i] = items[offsets[i]:offsets[i]:offsets[i+1]]
+	}
+	for i := 0; i < m; i++ {
+		adjacency[node(i)] = append(adjacency[node(i)], i)
+	}
+	return adjacency
+}
+
+// booleanContours links the directed edges into closed contours. At vertices with multiple outgoing edges the one making the sharpest left turn is taken, so that contours touching at a vertex are kept separate. Flattened curves that are still complete are restored and collinear line segments are merged.
+func booleanContours(vertices []Point, edges []booleanEdge, curves []booleanCurve) *Path {
+	outgoing := booleanAdjacency(len(vertices), len(edges), func(i int) int {
+		return edges[i].u
+	})
+
+	p := &Path{}
+	contour := []booleanEdge{}
+	for i := range edges {
+		if edges[i].used {
+			continue
+		}
+
+		contour = contour[:0]
+		edge := &edges[i]
+		for !edge.used {
+			edge.used = true
+			contour = append(contour, *edge)
+
+			din := vertices[edge.v].Sub(vertices[edge.u])
+			next := -1
+			nextAngle := math.Inf(-1)
+			for _, j := range outgoing[edge.v] {
+				if edges[j].used && j != i {
+					continue
+				}
+				dout := vertices[edges[j].v].Sub(vertices[edges[j].u])
+				angle := math.Atan2(din.PerpDot(dout), din.Dot(dout))
+				if nextAngle < angle {
+					next = j
+					nextAngle = angle
+				}
+			}
+			if next == -1 {
+				break // should not happen for a consistent set of edges
+			}
+			edge = &edges[next]
+		}
+		booleanContour(p, vertices, contour, curves)
+	}
+	return p
+}
+
+// booleanContour appends the closed contour to p.
+func booleanContour(p *Path, vertices []Point, contour []booleanEdge, curves []booleanCurve) {
+	// continues returns whether edge b follows edge a within the same flattened curve
+	continues := func(a, b booleanEdge) bool {
+		return a.curve != -1 && a.curve == b.curve && a.tv == b.tu && (a.tu < a.tv) == (b.tu < b.tv)
+	}
+
+	// rotate the contour so that it does not start halfway a curve
+	for k := range contour {
+		if !continues(contour[(k+len(contour)-1)%len(contour)], contour[k]) {
+			contour = append(contour[k:], contour[:k]...)
+			break
+		}
+	}
+
+	// group the edges into lines and (parts of) restored curves
+	type element struct {
+		a, b   Point
+		curve  int
+		t0, t1 float64
+	}
+	elements := make([]element, 0, len(contour))
+	for k := 0; k < len(contour); {
+		edge := contour[k]
+		n := 1
+		for k+n < len(contour) && continues(contour[k+n-1], contour[k+n]) {
+			n++
+		}
+		last := contour[k+n-1]
+		elements = append(elements, element{vertices[edge.u], vertices[last.v], edge.curve, edge.tu, last.tv})
+		k += n
+	}
+
+	// merge collinear lines
+	collinear := func(a, b element) bool {
+		da, db := a.b.Sub(a.a), b.b.Sub(b.a)
+		return a.curve == -1 && b.curve == -1 && math.Abs(da.PerpDot(db)) <= booleanSnap*da.Length()*db.Length() && 0.0 < da.Dot(db)
+	}
+	for k := range elements {
+		if !collinear(elements[(k+len(elements)-1)%len(elements)], elements[k]) {
+			elements = append(elements[k:], elements[:k]...)
+			break
+		}
+	}
+	k := 0
+	for _, elem := range elements {
+		if 0 < k && collinear(elements[k-1], elem) {
+			elements[k-1].b = elem.b
+			continue
+		}
+		elements[k] = elem
+		k++
+	}
+	elements = elements[:k]
+	if len(elements) < 2 || len(elements) == 2 && elements[0].curve == -1 && elements[1].curve == -1 {
+		return
+	}
+
+	p.MoveTo(elements[0].a.X, elements[0].a.Y)
+	for _, elem := range elements[:len(elements)-1] {
+		if elem.curve == -1 {
+			p.LineTo(elem.b.X, elem.b.Y)
+		} else {
+			booleanCurveTo(p, elem.b, curves[elem.curve], elem.t0, elem.t1)
+		}
+	}
+	if last := elements[len(elements)-1]; last.curve != -1 {
+		booleanCurveTo(p, last.b, curves[last.curve], last.t0, last.t1)
+	}
+	p.Close()
+}
+
+// booleanCurveTo appends the part of the curve between the curve parameters t0 and t1 to p, which is reversed when t1 < t0. The end point b is used instead of the end point of the curve, since end points have been snapped.
+func booleanCurveTo(p *Path, b Point, curve booleanCurve, t0, t1 float64) {
+	reverse := t1 < t0
+	if reverse {
+		t0, t1 = t1, t0
+	}
+
+	s := curve.seg
+	if t0 == 0.0 && t1 == 1.0 {
+		d := append([]float64{}, curve.d...)
+		if reverse {
+			switch d[0] {
+			case cubeToCmd:
+				d[1], d[2], d[3], d[4] = d[3], d[4], d[1], d[2]
+			case arcToCmd:
+				largeArc, sweep := fromArcFlags(d[4])
+				d[4] = toArcFlags(largeArc, !sweep)
+			}
+		}
+		d[len(d)-2], d[len(d)-1] = b.X, b.Y
+		p.d = append(p.d, d...)
+		return
+	}
+
+	switch s.cmd {
+	case quadToCmd:
+		p0, p1, p2 := s.start, s.cp1, s.end
+		if t1 < 1.0 {
+			p0, p1, p2, _, _, _ = splitQuadraticBezier(p0, p1, p2, t1)
+		}
+		if 0.0 < t0 {
+			_, _, _, p0, p1, p2 = splitQuadraticBezier(p0, p1, p2, t0/t1)
+		}
+		p.QuadTo(p1.X, p1.Y, b.X, b.Y)
+	case cubeToCmd:
+		p0, p1, p2, p3 := s.start, s.cp1, s.cp2, s.end
+		if t1 < 1.0 {
+			p0, p1, p2, p3, _, _, _, _ = splitCubicBezier(p0, p1, p2, p3, t1)
+		}
+		if 0.0 < t0 {
+			_, _, _, _, p0, p1, p2, p3 = splitCubicBezier(p0, p1, p2, p3, t0/t1)
+		}
+		if reverse {
+			p1, p2 = p2, p1
+		}
+		p.CubeTo(p1.X, p1.Y, p2.X, p2.Y, b.X, b.Y)
+	case arcToCmd:
+		dtheta := (t1 - t0) * (s.theta1 - s.theta0)
+		sweep := s.theta0 < s.theta1
+		if reverse {
+			sweep = !sweep
+		}
+		p.ArcTo(s.rx, s.ry, s.phi*180.0/math.Pi, math.Pi < math.Abs(dtheta), sweep, b.X, b.Y)
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// Intersection is an intersection point of two paths. SegA and SegB are the indices of the segments in either path that intersect, where MoveTo commands are not counted, and TA and TB are the curve parameters in [0,1] along those segments. For arcs the curve parameter is proportional to the angle.
+type Intersection struct {
+	Point
+	SegA, SegB int
+	TA, TB     float64
+}
+
+// Intersections returns the intersection points of path p and q, ordered along p. Curves are approximated within Tolerance to find the intersections, which are then refined on the original curves. Segments whose bounding boxes do not overlap are skipped. An intersection found on adjacent pieces of the approximation, or at a vertex shared by consecutive segments, is reported once, but distinct segments meeting at the same position are each reported. Segments that overlap are not reported.
+func (p *Path) Intersections(q *Path) []Intersection {
+	segsA, segsB := p.segments(), q.segments()
+	flatA, tsA, minA, maxA := flattenSegments(segsA)
+	flatB, tsB, minB, maxB := flattenSegments(segsB)
+
+	// sweep over the segments of q ordered by their left-most coordinate
+	orderB := make([]int, len(segsB))
+	for j := range orderB {
+		orderB[j] = j
+	}
+	sort.Slice(orderB, func(i, j int) bool {
+		return minB[orderB[i]].X < minB[orderB[j]].X
+	})
+
+	zs := []Intersection{}
+	for i, a := range segsA {
+		for _, j := range orderB {
+			if maxA[i].X+Epsilon < minB[j].X {
+				break
+			} else if maxB[j].X+Epsilon < minA[i].X || maxA[i].Y+Epsilon < minB[j].Y || maxB[j].Y+Epsilon < minA[i].Y {
+				continue
+			}
+			b := segsB[j]
+			for ka := 1; ka < len(flatA[i]); ka++ {
+				a0, a1 := flatA[i][ka-1], flatA[i][ka]
+				for kb := 1; kb < len(flatB[j]); kb++ {
+					b0, b1 := flatB[j][kb-1], flatB[j][kb]
+					sa, sb, ok := intersectionLineSegments(a0, a1, b0, b1)
+					if !ok {
+						continue
+					}
+					ta := tsA[i][ka-1] + sa*(tsA[i][ka]-tsA[i][ka-1])
+					tb := tsB[j][kb-1] + sb*(tsB[j][kb]-tsB[j][kb-1])
+					ta, tb = refineIntersection(a, b, ta, tb)
+					z := Intersection{a.pos(ta), i, j, ta, tb}
+
+					duplicate := false
+					for _, z2 := range zs {
+						if z2.Point.Equals(z.Point) && sameSegmentPosition(segsA, z2.SegA, z.SegA, z.Point) && sameSegmentPosition(segsB, z2.SegB, z.SegB, z.Point) {
+							duplicate = true
+							break
+						}
+					}
+					if !duplicate {
+						zs = append(zs, z)
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(zs, func(i, j int) bool {
+		return zs[i].SegA < zs[j].SegA || zs[i].SegA == zs[j].SegA && zs[i].TA < zs[j].TA
+	})
+	return zs
+}
+
+// flattenSegments returns the flattened points and their curve parameters for each segment, together with the bounding box of each flattened segment.
+func flattenSegments(segs []pathSegment) ([][]Point, [][]float64, []Point, []Point) {
+	flat := make([][]Point, len(segs))
+	ts := make([][]float64, len(segs))
+	min := make([]Point, len(segs))
+	max := make([]Point, len(segs))
+	for i, seg := range segs {
+		ts[i] = seg.flatten()
+		flat[i] = make([]Point, len(ts[i]))
+		min[i] = Point{math.Inf(1), math.Inf(1)}
+		max[i] = Point{math.Inf(-1), math.Inf(-1)}
+		for k, t := range ts[i] {
+			pos := seg.pos(t)
+			flat[i][k] = pos
+			min[i] = Point{math.Min(min[i].X, pos.X), math.Min(min[i].Y, pos.Y)}
+			max[i] = Point{math.Max(max[i].X, pos.X), math.Max(max[i].Y, pos.Y)}
+		}
+	}
+	return flat, ts, min, max
+}
+
+// sameSegmentPosition returns true if position p on segments i and j is the same position along the path, that is if i and j are the same segment or p is the vertex where one ends and the other starts.
+func sameSegmentPosition(segs []pathSegment, i, j int, p Point) bool {
+	return i == j || segs[i].end.Equals(p) && segs[j].start.Equals(p) || segs[j].end.Equals(p) && segs[i].start.Equals(p)
+}
+
+// intersectionLineSegments returns the parameters along both line segments of their intersection point, end points included. Parallel line segments do not intersect.
+func intersectionLineSegments(a0, a1, b0, b1 Point) (float64, float64, bool) {
+	da := a1.Sub(a0)
+	db := b1.Sub(b0)
+	div := da.PerpDot(db)
+	if equal(div, 0.0) {
+		return 0.0, 0.0, false
+	}
+
+	ta := db.PerpDot(a0.Sub(b0)) / div
+	tb := da.PerpDot(a0.Sub(b0)) / div
+	if -Epsilon <= ta && ta <= 1.0+Epsilon && -Epsilon <= tb && tb <= 1.0+Epsilon {
+		return math.Max(0.0, math.Min(1.0, ta)), math.Max(0.0, math.Min(1.0, tb)), true
+	}
+	return 0.0, 0.0, false
+}
+
+// refineIntersection refines the curve parameters of an approximate intersection of two segments using Newton's method.
+func refineIntersection(a, b pathSegment, ta, tb float64) (float64, float64) {
+	if a.isLine() && b.isLine() {
+		return ta, tb
+	}
+	for i := 0; i < 10; i++ {
+		f := a.pos(ta).Sub(b.pos(tb))
+		if f.Length() < 1e-12 {
+			break
+		}
+		da, db := a.deriv(ta), b.deriv(tb).Neg()
+		div := da.PerpDot(db)
+		if div == 0.0 {
+			break
+		}
+		// solve da*dta + db*dtb = -f
+		ta = math.Max(0.0, math.Min(1.0, ta-f.PerpDot(db)/div))
+		tb = math.Max(0.0, math.Min(1.0, tb-da.PerpDot(f)/div))
+	}
+	return ta, tb
+}
+
+// Distance returns the shortest distance from point pt to the path, or +Inf if the path has no segments. Open subpaths are not closed.
+func (p *Path) Distance(pt Point) float64 {
+	dist := math.Inf(1)
+	for _, seg := range p.segments() {
+		ts := seg.flatten()
+		points := make([]Point, len(ts))
+		for k, t := range ts {
+			points[k] = seg.pos(t)
+		}
+
+		kmin, dmin := 0, math.Inf(1)
+		for k := 1; k < len(points); k++ {
+			if d := distanceToLineSegment(pt, points[k-1], points[k]); d < dmin {
+				kmin, dmin = k, d
+			}
+		}
+		if !seg.isLine() {
+			// the closest point on the curve is near the closest line segment of the approximation
+			lo, hi := ts[kmin-1], ts[kmin]
+			if 1 < kmin {
+				lo = ts[kmin-2]
+			}
+			if kmin+1 < len(ts) {
+				hi = ts[kmin+1]
+			}
+			for i := 0; i < 50; i++ {
+				t1, t2 := lo+(hi-lo)/3.0, hi-(hi-lo)/3.0
+				if seg.pos(t1).Sub(pt).Length() < seg.pos(t2).Sub(pt).Length() {
+					hi = t2
+				} else {
+					lo = t1
+				}
+			}
+			dmin = seg.pos((lo + hi) / 2.0).Sub(pt).Length()
+		}
+		dist = math.Min(dist, dmin)
+	}
+	return dist
+}
+
+// StrokeContains returns true if point pt lies on the stroke of the path with the given stroke width, as if stroked with round caps and joins. Dashes are not taken into account. This can be used for hit-testing.
+func (p *Path) StrokeContains(pt Point, width float64) bool {
+	return p.Distance(pt) <= width/2.0
+}
@@ -0,0 +1,155 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathBoolean(t *testing.T) {
+	var tts = []struct {
+		op     string
+		p, q   string
+		result string
+	}{
+		{"and", "M0 0H10V10H0z", "M5 5H15V15H5z", "M10 10L5 10L5 5L10 5z"},
+		{"or", "M0 0H10V10H0z", "M5 5H15V15H5z", "M0 0L10 0L10 5L15 5L15 15L5 15L5 10L0 10z"},
+		{"xor", "M0 0H10V10H0z", "M5 5H15V15H5z", "M0 0L10 0L10 5L5 5L5 10L0 10zM5 10L10 10L10 5L15 5L15 15L5 15z"},
+		{"not", "M0 0H10V10H0z", "M5 5H15V15H5z", "M0 0L10 0L10 5L5 5L5 10L0 10z"},
+		{"not", "M5 5H15V15H5z", "M0 0H10V10H0z", "M5 10L10 10L10 5L15 5L15 15L5 15z"},
+
+		// coinciding edges and vertices
+		{"or", "M0 0H10V10H0z", "M0 0H10V10H0z", "M0 0L10 0L10 10L0 10z"},
+		{"and", "M0 0H10V10H0z", "M0 0H10V10H0z", "M0 0L10 0L10 10L0 10z"},
+		{"not", "M0 0H10V10H0z", "M0 0H10V10H0z", ""},
+		{"or", "M0 0H10V10H0z", "M10 0H20V10H10z", "M0 0L20 0L20 10L0 10z"},
+		{"or", "M0 0H10V10H0z", "M10 10H20V20H10z", "M0 0L10 0L10 10L0 10zM10 10L20 10L20 20L10 20z"},
+		{"and", "M0 0H10V10H0z", "M10 0H20V10H10z", ""},
+
+		// self-intersections, holes and orientation
+		{"or", "M0 0L10 10L10 0L0 10z", "", "M0 0L5 5L0 10zM10 10L5 5L10 0z"},
+		{"or", "M0 0V10H10V0z", "", "M0 10L0 0L10 0L10 10z"},
+		{"or", "M0 0H10V10H0zM3 3V7H7V3z", "", "M0 0L10 0L10 10L0 10zM3 3L3 7L7 7L7 3z"},
+		{"or", "M0 0H10V10H0zM3 3H7V7H3z", "", "M0 0L10 0L10 10L0 10z"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.op+" "+tt.p+" "+tt.q, func(t *testing.T) {
+			p, q := MustParseSVG(tt.p), MustParseSVG(tt.q)
+			var r *Path
+			switch tt.op {
+			case "and":
+				r = p.And(q)
+			case "or":
+				r = p.Or(q)
+			case "xor":
+				r = p.Xor(q)
+			case "not":
+				r = p.Not(q)
+			}
+			test.T(t, r, MustParseSVG(tt.result))
+		})
+	}
+
+	FillRule = EvenOdd
+	test.T(t, MustParseSVG("M0 0H10V10H0zM3 3H7V7H3z").Or(&Path{}), MustParseSVG("M0 0L10 0L10 10L0 10zM7 3L3 3L3 7L7 7z"))
+	FillRule = NonZero
+}
+
+func TestPathBooleanCurves(t *testing.T) {
+	p := Circle(5.0).Translate(5.0, 5.0)
+	q := Rectangle(10.0, 5.0)
+	r := p.And(q)
+	test.That(t, r.Closed())
+	test.T(t, len(r.Split()), 1)
+	bounds := r.Bounds()
+	test.Float(t, bounds.X, 0.0)
+	test.Float(t, bounds.Y, 0.0)
+	test.Float(t, bounds.W, 10.0)
+	test.Float(t, bounds.H, 5.0)
+
+	r = Circle(5.0).Or(Circle(5.0).Translate(8.0, 0.0))
+	test.T(t, len(r.Split()), 1)
+	test.That(t, r.Interior(4.0, 0.0))
+	test.That(t, !r.Interior(4.0, 4.5))
+
+	// curves that are cut are split instead of flattened
+	Tolerance = 0.01
+	Epsilon = 1e-3
+	test.T(t, p.And(q), MustParseSVG("M10 5L0 5A5 5 0 0 1 10 5z"))
+	test.T(t, MustParseSVG("M0 0C0 10 10 10 10 0z").And(q), MustParseSVG("M0 0L10 0C10 2.1162 9.5522 3.7846 8.843 5L1.157 5C0.44784 3.7846 0 2.1162 0 0z"))
+	test.T(t, MustParseSVG("M0 0Q5 10 10 0z").And(Rectangle(10.0, 2.5)), MustParseSVG("M0 0L10 0Q9.2663 1.4674 8.5326 2.5L1.4674 2.5Q0.7337 1.4674 0 0z"))
+}
+
+func TestPathSettle(t *testing.T) {
+	test.T(t, MustParseSVG("M0 0H10V10H0zM5 5H15V15H5z").Settle(), MustParseSVG("M0 0L10 0L10 5L15 5L15 15L5 15L5 10L0 10z"))
+	test.T(t, MustParseSVG("M0 0L10 10V0L0 10z").Settle(), MustParseSVG("M0 0L5 5L0 10zM10 10L5 5L10 0z"))
+
+	// curves that are not cut are kept
+	p := Circle(5.0).Settle()
+	test.T(t, p, MustParseSVG("M-5 0A5 5 0 0 1 5 0A5 5 0 0 1 -5 0z"))
+	test.T(t, p.selfIntersects(), false)
+	test.T(t, MustParseSVG("M0 0L10 0L10 10L5 -5").selfIntersects(), true)
+}
+
+func TestPathIntersections(t *testing.T) {
+	var tts = []struct {
+		p, q string
+		zs   []Intersection
+	}{
+		{"M0 0L10 10", "M0 10L10 0", []Intersection{{Point{5.0, 5.0}, 0, 0, 0.5, 0.5}}},
+		{"M0 0L10 0", "M0 5L10 5", []Intersection{}},
+		{"M0 0H10V10H0z", "M5 -5V15", []Intersection{{Point{5.0, 0.0}, 0, 0, 0.5, 0.25}, {Point{5.0, 10.0}, 2, 0, 0.5, 0.75}}},
+		{"M0 0H10V10H0z", "M0 0L10 10", []Intersection{{Point{0.0, 0.0}, 0, 0, 0.0, 0.0}, {Point{10.0, 10.0}, 1, 0, 1.0, 1.0}}},
+		{"M-5 0A5 5 0 0 1 5 0", "M0 -10V10", []Intersection{{Point{0.0, -5.0}, 0, 0, 0.5, 0.25}}},
+		{"M0 0Q5 10 10 0", "M0 2.5H10", []Intersection{{Point{1.4644660940672625, 2.5}, 0, 0, 0.14644660940672624, 0.14644660940672624}, {Point{8.535533905932738, 2.5}, 0, 0, 0.8535533905932737, 0.8535533905932737}}},
+		{"M0 0C0 10 10 10 10 0", "M5 0V10", []Intersection{{Point{5.0, 7.5}, 0, 0, 0.5, 0.75}}},
+		{"M0 0L10 10M0 10L10 0", "M5 0V10", []Intersection{{Point{5.0, 5.0}, 0, 0, 0.5, 0.5}, {Point{5.0, 5.0}, 1, 0, 0.5, 0.5}}},
+		{"M0 0L10 10", "M0 10L10 0M5 0V10", []Intersection{{Point{5.0, 5.0}, 0, 0, 0.5, 0.5}, {Point{5.0, 5.0}, 0, 1, 0.5, 0.5}}},
+		{"M0 0L5 5L10 0", "M0 5H10", []Intersection{{Point{5.0, 5.0}, 0, 0, 1.0, 0.5}}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p+" "+tt.q, func(t *testing.T) {
+			zs := MustParseSVG(tt.p).Intersections(MustParseSVG(tt.q))
+			test.T(t, len(zs), len(tt.zs))
+			for i := range zs {
+				if i < len(tt.zs) {
+					test.T(t, zs[i].Point, tt.zs[i].Point)
+					test.T(t, zs[i].SegA, tt.zs[i].SegA)
+					test.T(t, zs[i].SegB, tt.zs[i].SegB)
+					test.Float(t, zs[i].TA, tt.zs[i].TA)
+					test.Float(t, zs[i].TB, tt.zs[i].TB)
+				}
+			}
+		})
+	}
+}
+
+func TestPathDistance(t *testing.T) {
+	var tts = []struct {
+		p    string
+		pt   Point
+		dist float64
+	}{
+		{"", Point{0.0, 0.0}, math.Inf(1)},
+		{"M0 0L10 0", Point{5.0, 5.0}, 5.0},
+		{"M0 0L10 0", Point{13.0, 4.0}, 5.0},
+		{"M0 0H10V10H0z", Point{-3.0, 5.0}, 3.0},
+		{"M0 0H10V10H0", Point{-3.0, 5.0}, math.Sqrt(34.0)},
+		{"M-5 0A5 5 0 0 1 5 0", Point{0.0, 0.0}, 5.0},
+		{"M-5 0A5 5 0 0 1 5 0", Point{3.0, -4.0}, 0.0},
+		{"M-5 0A5 5 0 0 1 5 0", Point{0.0, -2.0}, 3.0},
+		{"M-5 0A5 5 0 0 1 5 0", Point{0.0, 2.0}, math.Sqrt(29.0)},
+		{"M0 0C0 10 10 10 10 0", Point{5.0, 10.0}, 2.5},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.Float(t, MustParseSVG(tt.p).Distance(tt.pt), tt.dist)
+		})
+	}
+
+	p := MustParseSVG("M0 0L10 0")
+	test.That(t, p.StrokeContains(Point{5.0, 0.9}, 2.0), "point on stroke")
+	test.That(t, !p.StrokeContains(Point{5.0, 1.1}, 2.0), "point outside stroke")
+	test.That(t, p.StrokeContains(Point{-0.5, 0.5}, 2.0), "point on round cap")
+}
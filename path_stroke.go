@@ -443,8 +443,6 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 		}
 	}
 
-	// TODO: (stroke) split at intersections and filter out overlapped parts
-
 	if closed {
 		rhs.Close()
 		lhs.Close()
@@ -460,7 +458,7 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 	return rhs, nil
 }
 
-// Offset offsets the path to expand by w and returns a new path. If w is negative it will contract. Path must be closed.
+// Offset offsets the path to expand by w and returns a new path. If w is negative it will contract. Path must be closed. When the offset path intersects itself, eg. at inner bends, its inverted loops are removed. Curves that are cut are split at the intersections.
 func (p *Path) Offset(w float64) *Path {
 	if equal(w, 0.0) {
 		return p
@@ -485,14 +483,21 @@ func (p *Path) Offset(w float64) *Path {
 		}
 
 		rhs, lhs := offsetSegment(ps, math.Abs(w), ButtCapper, RoundJoiner)
+		r := lhs
 		if useRHS {
-			if rhs != nil {
-				q = q.Append(rhs)
+			r = rhs
+		}
+		if r != nil {
+			// remove the inverted loops at inner bends, which wind opposite to the offset path
+			ccw := r.CCW()
+			if !ccw {
+				r = r.Reverse()
 			}
-		} else {
-			if lhs != nil {
-				q = q.Append(lhs)
+			r = settle(r, booleanPositive)
+			if !ccw {
+				r = r.Reverse()
 			}
+			q = q.Append(r)
 		}
 	}
 	return q
@@ -500,28 +505,177 @@ func (p *Path) Offset(w float64) *Path {
 
 // Stroke converts a path into a stroke of width w and returns a new path. It uses cr to cap the start and end of the path, and
 // jr to join all path elemtents. If the path closes itself, it will use a join between the start and end instead of capping them.
-// The tolerance is the maximum deviation from the original path when flattening Béziers and optimizing the stroke.
+// The tolerance is the maximum deviation from the original path when flattening Béziers and optimizing the stroke. When the stroke outline intersects itself, eg. at inner bends or where the path crosses itself, the overlapping parts are removed (see Settle) so that it can be filled with either fill rule or with a translucent color. Curves that are cut are split at the intersections.
 func (p *Path) Stroke(w float64, cr Capper, jr Joiner) *Path {
 	q := &Path{}
 	halfWidth := w / 2.0
 	for _, ps := range p.Split() {
 		rhs, lhs := offsetSegment(ps, halfWidth, cr, jr)
-		if rhs != nil && lhs != nil { // closed path
-			// inner path should go opposite direction to cancel the outer path
-			if ps.CCW() {
-				lhs = lhs.Reverse()
-				q = q.Append(rhs)
-				q = q.Append(lhs)
-			} else {
-				rhs = rhs.Reverse()
-				q = q.Append(lhs)
-				q = q.Append(rhs)
-			}
-		} else if rhs != nil {
+		q = appendStroke(q, ps, rhs, lhs)
+	}
+	return settle(q, NonZero)
+}
+
+// appendStroke appends the stroke outline of subpath ps to q, given by the rhs and lhs paths from offsetting ps.
+func appendStroke(q, ps, rhs, lhs *Path) *Path {
+	if rhs != nil && lhs != nil { // closed path
+		// inner path should go opposite direction to cancel the outer path
+		if ps.CCW() {
+			lhs = lhs.Reverse()
 			q = q.Append(rhs)
+			q = q.Append(lhs)
 		} else {
+			rhs = rhs.Reverse()
 			q = q.Append(lhs)
+			q = q.Append(rhs)
 		}
+	} else if rhs != nil {
+		q = q.Append(rhs)
+	} else if lhs != nil {
+		q = q.Append(lhs)
 	}
 	return q
 }
+
+////////////////
+
+// WidthProfile returns the stroke width at t in the range [0,1], which is the distance along a subpath relative to its length.
+type WidthProfile func(float64) float64
+
+// WidthStop is a control point of a width profile, with the offset in the range [0,1].
+type WidthStop struct {
+	Offset float64
+	Width  float64
+}
+
+// LinearWidthProfile returns a width profile that interpolates linearly between the widths of the stops, which must be sorted by their offset. The width is constant before the first and after the last stop.
+func LinearWidthProfile(stops ...WidthStop) WidthProfile {
+	return func(t float64) float64 {
+		if len(stops) == 0 {
+			return 0.0
+		} else if t <= stops[0].Offset {
+			return stops[0].Width
+		}
+		for i := 1; i < len(stops); i++ {
+			if t < stops[i].Offset {
+				s0, s1 := stops[i-1], stops[i]
+				return s0.Width + (s1.Width-s0.Width)*(t-s0.Offset)/(s1.Offset-s0.Offset)
+			}
+		}
+		return stops[len(stops)-1].Width
+	}
+}
+
+// variableStrokeVertex is a vertex of a flattened subpath, with d the distance along the subpath and joint true at the start and end points of its segments.
+type variableStrokeVertex struct {
+	pos   Point
+	d     float64
+	joint bool
+}
+
+// offsetVariableSegment returns the rhs and lhs paths from offsetting a path segment by the half widths of the width profile. Béziers and arcs are flattened and lines are subdivided so that the outline follows the profile within Tolerance.
+// It closes rhs and lhs when p is closed as well.
+func offsetVariableSegment(p *Path, profile WidthProfile, cr Capper, jr Joiner) (*Path, *Path) {
+	vs := []variableStrokeVertex{}
+	for _, seg := range p.segments() {
+		for _, t := range seg.flatten() {
+			pos := seg.pos(t)
+			joint := t == 0.0 || t == 1.0
+			if len(vs) == 0 {
+				vs = append(vs, variableStrokeVertex{pos, 0.0, joint})
+			} else if last := &vs[len(vs)-1]; last.pos.Equals(pos) {
+				last.joint = last.joint || joint
+			} else {
+				vs = append(vs, variableStrokeVertex{pos, last.d + pos.Sub(last.pos).Length(), joint})
+			}
+		}
+	}
+	if len(vs) < 2 {
+		return nil, nil
+	}
+	closed := p.Closed()
+	length := vs[len(vs)-1].d
+	halfWidth := func(d float64) float64 {
+		return math.Max(0.0, profile(d/length)) / 2.0
+	}
+
+	// subdivide lines where the profile deviates from linear interpolation
+	var subdivide func(Point, Point, float64, float64, int)
+	subdivided := []variableStrokeVertex{vs[0]}
+	subdivide = func(p0, p1 Point, d0, d1 float64, depth int) {
+		d := (d0 + d1) / 2.0
+		if depth < 16 && Tolerance < math.Abs(halfWidth(d)-(halfWidth(d0)+halfWidth(d1))/2.0) {
+			mid := p0.Interpolate(p1, 0.5)
+			subdivide(p0, mid, d0, d, depth+1)
+			subdivided = append(subdivided, variableStrokeVertex{mid, d, false})
+			subdivide(mid, p1, d, d1, depth+1)
+		}
+	}
+	for i := 1; i < len(vs); i++ {
+		subdivide(vs[i-1].pos, vs[i].pos, vs[i-1].d, vs[i].d, 0)
+		subdivided = append(subdivided, vs[i])
+	}
+	vs = subdivided
+
+	normals := make([]Point, len(vs)-1)
+	for i := range normals {
+		normals[i] = vs[i+1].pos.Sub(vs[i].pos).Rot90CW().Norm(1.0)
+	}
+
+	rhs, lhs := &Path{}, &Path{}
+	hw0 := halfWidth(0.0)
+	rStart := vs[0].pos.Add(normals[0].Mul(hw0))
+	lStart := vs[0].pos.Sub(normals[0].Mul(hw0))
+	rhs.MoveTo(rStart.X, rStart.Y)
+	lhs.MoveTo(lStart.X, lStart.Y)
+	for i, n := range normals {
+		v := vs[i+1]
+		hw := halfWidth(v.d)
+		rEnd := v.pos.Add(n.Mul(hw))
+		lEnd := v.pos.Sub(n.Mul(hw))
+		rhs.LineTo(rEnd.X, rEnd.Y)
+		lhs.LineTo(lEnd.X, lEnd.Y)
+
+		if i+1 < len(normals) {
+			if v.joint {
+				jr.Join(rhs, lhs, hw, v.pos, n.Mul(hw), normals[i+1].Mul(hw), math.NaN(), math.NaN())
+			} else {
+				// flattened curves are joined directly
+				BevelJoiner.Join(rhs, lhs, hw, v.pos, n.Mul(hw), normals[i+1].Mul(hw), math.NaN(), math.NaN())
+			}
+		}
+	}
+
+	n1 := normals[len(normals)-1]
+	if closed {
+		// the width at the end may differ from the start
+		rEnd := vs[0].pos.Add(n1.Mul(hw0))
+		lEnd := vs[0].pos.Sub(n1.Mul(hw0))
+		rhs.LineTo(rEnd.X, rEnd.Y)
+		lhs.LineTo(lEnd.X, lEnd.Y)
+		jr.Join(rhs, lhs, hw0, vs[0].pos, n1.Mul(hw0), normals[0].Mul(hw0), math.NaN(), math.NaN())
+		rhs.Close()
+		lhs.Close()
+		return rhs, lhs
+	}
+
+	// default to CCW direction
+	hw1 := halfWidth(length)
+	lhs = lhs.Reverse()
+	cr.Cap(rhs, hw1, vs[len(vs)-1].pos, n1.Mul(hw1))
+	rhs = rhs.Join(lhs)
+	cr.Cap(rhs, hw0, vs[0].pos, normals[0].Mul(-hw0))
+	rhs.Close()
+	return rhs, nil
+}
+
+// VariableStroke converts a path into a stroke whose width varies along each subpath by the width profile and returns a new path. It uses cr to cap the start and end of the path and jr to join the path elements, with the size of the caps and joins following the width at their position. If the path closes itself, it will use a join between the start and end instead of capping them.
+// Béziers and arcs are flattened, and the outline deviates at most Tolerance from the profile. Overlapping parts of the outline are removed as for Stroke.
+func (p *Path) VariableStroke(profile WidthProfile, cr Capper, jr Joiner) *Path {
+	q := &Path{}
+	for _, ps := range p.Split() {
+		rhs, lhs := offsetVariableSegment(ps, profile, cr, jr)
+		q = appendStroke(q, ps, rhs, lhs)
+	}
+	return settle(q, NonZero)
+}
@@ -26,48 +26,48 @@ func TestPathStroke(t *testing.T) {
 		{"M10 10L10 5", 2.0, SquareCapper, RoundJoiner, "M9 10L9 5L9 4L11 4L11 5L11 10L11 11L9 11L9 10z"},
 
 		{"M0 0L10 0L20 0", 2.0, ButtCapper, RoundJoiner, "M0 -1L10 -1L20 -1L20 1L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 10", 2.0, ButtCapper, RoundJoiner, "M0 -1L10 -1A1 1 0 0 1 11 0L11 10L9 10L9 0L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 -10", 2.0, ButtCapper, RoundJoiner, "M0 -1L10 -1L9 0L9 -10L11 -10L11 0A1 1 0 0 1 10 1L0 1L0 -1z"},
+		{"M0 0L10 0L10 10", 2.0, ButtCapper, RoundJoiner, "M0 -1L10 -1A1 1 0 0 1 11 0L11 10L9 10L9 1L0 1z"},
+		{"M0 0L10 0L10 -10", 2.0, ButtCapper, RoundJoiner, "M0 -1L9 -1L9 -10L11 -10L11 0A1 1 0 0 1 10 1L0 1z"},
 
 		{"M0 0L10 0L20 0", 2.0, ButtCapper, BevelJoiner, "M0 -1L10 -1L20 -1L20 1L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 10", 2.0, ButtCapper, BevelJoiner, "M0 -1L10 -1L11 0L11 10L9 10L9 0L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 -10", 2.0, ButtCapper, BevelJoiner, "M0 -1L10 -1L9 0L9 -10L11 -10L11 0L10 1L0 1L0 -1z"},
+		{"M0 0L10 0L10 10", 2.0, ButtCapper, BevelJoiner, "M0 -1L10 -1L11 0L11 10L9 10L9 1L0 1z"},
+		{"M0 0L10 0L10 -10", 2.0, ButtCapper, BevelJoiner, "M0 -1L9 -1L9 -10L11 -10L11 0L10 1L0 1z"},
 
 		{"M0 0L10 0L20 0", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L20 -1L20 1L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L5 0", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L10 1L5 1L5 -1L10 -1L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 10", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 2.0), "M0 -1L10 -1L11 0L11 10L9 10L9 0L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 10", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L11 -1L11 0L11 10L9 10L9 0L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 -10", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L9 0L9 -10L11 -10L11 0L11 1L10 1L0 1L0 -1z"},
+		{"M0 0L10 0L5 0", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L10 1L0 1z"},
+		{"M0 0L10 0L10 10", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 2.0), "M0 -1L10 -1L11 0L11 10L9 10L9 1L0 1z"},
+		{"M0 0L10 0L10 10", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L11 -1L11 10L9 10L9 1L0 1z"},
+		{"M0 0L10 0L10 -10", 2.0, ButtCapper, MiterClipJoiner(BevelJoiner, 4.0), "M0 -1L9 -1L9 -10L11 -10L11 1L0 1z"},
 
 		{"M0 0L10 0L20 0", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L20 -1L20 1L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L5 0", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L10 1L5 1L5 -1L10 -1L10 1L0 1L0 -1z"},
-		{"M0 0L10 0L10 10", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 2.0), "M0 -1L10 -1L11 0L11 10L9 10L9 0L10 1L0 1L0 -1z"},
+		{"M0 0L10 0L5 0", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 4.0), "M0 -1L10 -1L10 1L0 1z"},
+		{"M0 0L10 0L10 10", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 2.0), "M0 -1L10 -1L11 0L11 10L9 10L9 1L0 1z"},
 
-		{"M0 0L10 0L10 10L0 10z", 2.0, ButtCapper, BevelJoiner, "M0 -1L10 -1L11 0L11 10L10 11L0 11L-1 10L-1 0L0 -1zM0 1L1 0L1 10L0 9L10 9L9 10L9 0L10 1z"},
-		{"M0 0L0 10L10 10L10 0z", 2.0, ButtCapper, BevelJoiner, "M-1 0L-1 10L0 11L10 11L11 10L11 0L10 -1L0 -1L-1 0zM1 0L0 1L10 1L9 0L9 10L10 9L0 9L1 10z"},
+		{"M0 0L10 0L10 10L0 10z", 2.0, ButtCapper, BevelJoiner, "M0 11L-1 10L-1 0L0 -1L10 -1L11 0L11 10L10 11zM1 9L9 9L9 1L1 1z"},
+		{"M0 0L0 10L10 10L10 0z", 2.0, ButtCapper, BevelJoiner, "M-1 10L-1 0L0 -1L10 -1L11 0L11 10L10 11L0 11zM1 9L9 9L9 1L1 1z"},
 		{"M0 0Q10 0 10 10", 2.0, ButtCapper, BevelJoiner, "M0 -1L9.6467 3.7346L11 10L9 10L7.6849 4.3366L0 1L0 -1z"},
 		{"M0 0C0 10 10 10 10 0", 2.0, ButtCapper, BevelJoiner, "M1 0L3.4056 6.0146L6.9801 5.7289L9 0L11 0L9.7996 5.3497L2.7451 7.9408L-1 0L1 0z"},
 		{"M0 0A10 5 0 0 0 20 0", 2.0, ButtCapper, BevelJoiner, "M1 0A9 4 0 0 0 19 0L21 0A11 6 0 0 1 -1 0L1 0z"},
 		{"M0 0A10 5 0 0 1 20 0", 2.0, ButtCapper, BevelJoiner, "M-1 0A11 6 0 0 1 21 0L19 0A9 4 0 0 0 1 0L-1 0z"},
-		{"M5 2L2 2A2 2 0 0 0 0 0", 2.0, ButtCapper, BevelJoiner, "M5 3L2 3L1 2A1 1 0 0 0 0 1L0 -1A3 3 0 0 1 3 2L2 1L5 1L5 3z"},
+		{"M5 2L2 2A2 2 0 0 0 0 0", 2.0, ButtCapper, BevelJoiner, "M0 1L0 -1A3 3 0 0 1 2.8165 1L5 1L5 3L2 3L1 2A1 1 0 0 0 0 1z"},
 
 		// two circle quadrants joining at 90 degrees
-		{"M0 0A10 10 0 0 1 10 10A10 10 0 0 1 0 0z", 2.0, ButtCapper, ArcsJoiner, "M0 -1A11 11 0 0 1 11 10A11 11 0 0 1 10.958 10.958A11 11 0 0 1 10 11A11 11 0 0 1 -1 0A11 11 0 0 1 -0.958 -0.958A11 11 0 0 1 0 -1zM0 1L1 0A9 9 0 0 0 10 9L9 10A9 9 0 0 0 0 1z"},
+		{"M0 0A10 10 0 0 1 10 10A10 10 0 0 1 0 0z", 2.0, ButtCapper, ArcsJoiner, "M-1 0A11 11 0 0 1 -0.9582 -0.9582A11 11 0 0 1 0 -1A11 11 0 0 1 11 10A11 11 0 0 1 10.9582 10.9582A11 11 0 0 1 10 11A11 11 0 0 1 -1 0zM1.1093 1.1093A9 9 0 0 0 8.8907 8.8907A9 9 0 0 0 1.1093 1.1093z"},
 
 		// circles joining at one point (10,0), stroke will never join
-		{"M0 0A5 5 0 0 0 10 0A10 10 0 0 1 0 10", 2.0, ButtCapper, ArcsJoiner, "M1 0A4 4 0 0 0 9 0L11 0A11 11 0 0 1 0 11L0 9A9 9 0 0 0 9 0L11 0A6 6 0 0 1 -1 0L1 0z"},
+		{"M0 0A5 5 0 0 0 10 0A10 10 0 0 1 0 10", 2.0, ButtCapper, ArcsJoiner, "M-1 0L1 0A4 4 0 0 0 9 0L11 0A11 11 0 0 1 0 11L0 9A9 9 0 0 0 6.987 5.6048A6 6 0 0 1 -1 0z"},
 
 		// circle and line intersecting in one point
-		{"M0 0A2 2 0 0 1 2 2L5 2", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 -1A3 3 0 0 1 3 2L2 1L5 1L5 3L2 3L0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1L0-1z"},
-		{"M0 4A2 2 0 0 0 2 2L5 2", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1L2 1L5 1L5 3L2 3L3 2A3 3 0 0 1 0 5L0 3z"},
-		{"M5 2L2 2A2 2 0 0 0 0 0", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M5 3L2 3L0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1L0-1A3 3 0 0 1 3 2L2 1L5 1L5 3z"},
-		{"M5 2L2 2A2 2 0 0 1 0 4", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M5 3L2 3L3 2A3 3 0 0 1 0 5L0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1L2 1L5 1L5 3z"},
+		{"M0 0A2 2 0 0 1 2 2L5 2", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 -1A3 3 0 0 1 2.8165 1L5 1L5 3L0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1z"},
+		{"M0 4A2 2 0 0 0 2 2L5 2", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1L5 1L5 3L2.8165 3A3 3 0 0 1 0 5z"},
+		{"M5 2L2 2A2 2 0 0 0 0 0", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 1L0 -1A3 3 0 0 1 2.8165 1L5 1L5 3L0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1z"},
+		{"M5 2L2 2A2 2 0 0 1 0 4", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 3A1 1 0 0 0 1 2A1 1 0 0 0 0 1L5 1L5 3L2.8165 3A3 3 0 0 1 0 5z"},
 
 		// cut by limit
-		{"M0 0A2 2 0 0 1 2 2L5 2", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 1.0), "M0 -1A3 3 0 0 1 3 2L2 1L5 1L5 3L2 3L1 2A1 1 0 0 0 0 1L0-1z"},
+		{"M0 0A2 2 0 0 1 2 2L5 2", 2.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 1.0), "M0 -1A3 3 0 0 1 2.8165 1L5 1L5 3L2 3L1 2A1 1 0 0 0 0 1z"},
 
 		// no intersection
-		{"M0 0A2 2 0 0 1 2 2L5 2", 3.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 -1.5A3.5 3.5 0 0 1 3.5 2L2 .5L5 .5L5 3.5L2 3.5L.5 2A.5 .5 0 0 0 0 1.5L0 -1.5z"},
+		{"M0 0A2 2 0 0 1 2 2L5 2", 3.0, ButtCapper, ArcsClipJoiner(BevelJoiner, 10.0), "M0 1.5L0 -1.5A3.5 3.5 0 0 1 3.1477 0.5L5 0.5L5 3.5L2 3.5L0.5 2A0.5 0.5 0 0 0 0 1.5z"},
 	}
 	for j, tt := range tts {
 		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
@@ -93,7 +93,6 @@ func TestPathStrokeEllipse(t *testing.T) {
 }
 
 func TestPathOffset(t *testing.T) {
-	// TODO: cut at intersection on inner bends
 	var tts = []struct {
 		orig   string
 		w      float64
@@ -102,7 +101,7 @@ func TestPathOffset(t *testing.T) {
 		{"M0 0L10 0L10 10L0 10z", 0.0, "M0 0L10 0L10 10L0 10z"},
 		{"M0 0L10 0L10 10L0 10", 1.0, ""},
 		{"M0 0L10 0L10 10L0 10z", 1.0, "M0 -1L10 -1A1 1 0 0 1 11 0L11 10A1 1 0 0 1 10 11L0 11A1 1 0 0 1 -1 10L-1 0A1 1 0 0 1 0 -1z"},
-		{"M0 0L10 0L10 10L0 10z", -1.0, "M0 1L10 1L9 0L9 10L10 9L0 9L1 10L1 0L0 1z"},
+		{"M0 0L10 0L10 10L0 10z", -1.0, "M1 1L9 1L9 9L1 9z"},
 	}
 	for j, tt := range tts {
 		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
@@ -111,3 +110,97 @@ func TestPathOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestPathVariableStroke(t *testing.T) {
+	constant := func(float64) float64 { return 2.0 }
+	var tts = []struct {
+		orig string
+		cp   Capper
+		jr   Joiner
+	}{
+		{"M10 10L10 5", ButtCapper, RoundJoiner},
+		{"M10 10L10 5", SquareCapper, RoundJoiner},
+		{"M0 0L10 0L20 0", ButtCapper, RoundJoiner},
+		{"M0 0L10 0L10 10", ButtCapper, MiterJoiner},
+		{"M0 0L10 0L10 -10", RoundCapper, BevelJoiner},
+		{"M0 0H10V10H0z", ButtCapper, MiterJoiner},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			p := MustParseSVG(tt.orig)
+			test.T(t, p.VariableStroke(constant, tt.cp, tt.jr), p.Stroke(2.0, tt.cp, tt.jr))
+		})
+	}
+
+	taper := LinearWidthProfile(WidthStop{0.0, 0.0}, WidthStop{1.0, 2.0})
+	test.T(t, MustParseSVG("M0 0L10 0").VariableStroke(taper, ButtCapper, MiterJoiner), MustParseSVG("M0 0L10 -1L10 1L0 0z"))
+	test.T(t, MustParseSVG("M0 0L10 0").VariableStroke(taper, RoundCapper, MiterJoiner), MustParseSVG("M0 0L10 -1A1 1 0 0 1 10 1L0 0z"))
+
+	// lines are subdivided to follow the profile
+	bulge := func(t float64) float64 { return 2.0 + 20.0*math.Sin(t*math.Pi) }
+	q := MustParseSVG("M0 0L100 0").VariableStroke(bulge, ButtCapper, MiterJoiner)
+	test.T(t, q.Bounds(), Rect{0.0, -11.0, 100.0, 22.0})
+	test.That(t, 10 < len(q.Coords()), "subdivided lines")
+}
+
+func TestPathVariableStrokeLong(t *testing.T) {
+	Tolerance = 0.01
+	Epsilon = 1e-10
+
+	// a long wave of half circles whose width oscillates many times along its length
+	p := &Path{}
+	p.MoveTo(0.0, 0.0)
+	for i := 0; i < 200; i++ {
+		p.ArcTo(5.0, 5.0, 0.0, false, i%2 == 0, float64(10*i+10), 0.0)
+	}
+	length := 200.0 * 5.0 * math.Pi
+	profile := func(t float64) float64 { return 1.5 + math.Sin(40.0*math.Pi*t) }
+	q := p.VariableStroke(profile, ButtCapper, RoundJoiner)
+
+	// the overlaps at the inner side of the flattened arcs are removed
+	test.T(t, len(q.Split()), 1)
+	test.That(t, q.CCW(), "outline is counter clockwise")
+	test.That(t, !q.selfIntersects(), "outline intersects itself")
+
+	// points just within half the width from the path are inside, points just beyond it are outside
+	polyline := PolylineFromPath(q)
+	for i, seg := range p.segments() {
+		for _, s := range []float64{0.1, 0.3, 0.5, 0.7, 0.9} {
+			pos := seg.pos(s)
+			n := seg.direction(s).Rot90CW()
+			hw := profile(5.0*math.Pi*(float64(i)+s)/length) / 2.0
+			for _, side := range []float64{-1.0, 1.0} {
+				in := pos.Add(n.Mul(side * (hw - 0.05)))
+				out := pos.Add(n.Mul(side * (hw + 0.05)))
+				test.That(t, polyline.Interior(in.X, in.Y), fmt.Sprintf("%v must be inside", in))
+				test.That(t, !polyline.Interior(out.X, out.Y), fmt.Sprintf("%v must be outside", out))
+			}
+		}
+	}
+}
+
+func TestLinearWidthProfile(t *testing.T) {
+	profile := LinearWidthProfile(WidthStop{0.5, 2.0}, WidthStop{1.0, 4.0})
+	test.Float(t, profile(-1.0), 2.0)
+	test.Float(t, profile(0.5), 2.0)
+	test.Float(t, profile(0.75), 3.0)
+	test.Float(t, profile(1.0), 4.0)
+	test.Float(t, profile(2.0), 4.0)
+	test.Float(t, LinearWidthProfile()(0.5), 0.0)
+}
+
+func BenchmarkPathStroke(b *testing.B) {
+	// a long polyline whose inner joins all overlap
+	p := &Path{}
+	p.MoveTo(0.0, 0.0)
+	for i := 1; i < 4000; i++ {
+		p.LineTo(float64(i), 5.0*math.Sin(0.7*float64(i)))
+	}
+
+	Tolerance = 0.01
+	Epsilon = 1e-10
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Stroke(0.5, RoundCapper, RoundJoiner)
+	}
+}
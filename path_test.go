@@ -1,6 +1,7 @@
 package canvas
 
 import (
+	"fmt"
 	"math"
 	"os"
 	"strings"
@@ -210,6 +211,80 @@ func TestPathLength(t *testing.T) {
 	}
 }
 
+func TestPathPointAt(t *testing.T) {
+	var tts = []struct {
+		p       string
+		d       float64
+		pos     Point
+		tangent Point
+		k       float64
+	}{
+		{"", 5.0, Point{0.0, 0.0}, Point{0.0, 0.0}, 0.0},
+		{"M0 0L10 0L10 10", -1.0, Point{0.0, 0.0}, Point{1.0, 0.0}, 0.0},
+		{"M0 0L10 0L10 10", 5.0, Point{5.0, 0.0}, Point{1.0, 0.0}, 0.0},
+		{"M0 0L10 0L10 10", 10.0, Point{10.0, 0.0}, Point{0.0, 1.0}, 0.0},
+		{"M0 0L10 0L10 10", 15.0, Point{10.0, 5.0}, Point{0.0, 1.0}, 0.0},
+		{"M0 0L10 0L10 10", 25.0, Point{10.0, 10.0}, Point{0.0, 1.0}, 0.0},
+		{"M0 0L10 0M20 0L30 0", 15.0, Point{25.0, 0.0}, Point{1.0, 0.0}, 0.0},
+		{"M10 0A10 10 0 0 1 -10 0", 5.0 * math.Pi, Point{0.0, 10.0}, Point{-1.0, 0.0}, 0.1},
+		{"M10 0A10 10 0 0 0 -10 0", 5.0 * math.Pi, Point{0.0, -10.0}, Point{-1.0, 0.0}, -0.1},
+		{"M0 0C0 10 10 10 10 0", 0.0, Point{0.0, 0.0}, Point{0.0, 1.0}, -0.0666667},
+		{"M0 0C0 0 10 0 10 0", 5.0, Point{5.0, 0.0}, Point{1.0, 0.0}, 0.0},
+		{"M0 0Q5 10 10 0", 0.0, Point{0.0, 0.0}, Point{0.4472136, 0.8944272}, -0.0357771},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			p := MustParseSVG(tt.p)
+			pos, tangent, normal := p.PointAt(tt.d), p.TangentAt(tt.d), p.NormalAt(tt.d)
+			if 1e-3 < pos.Sub(tt.pos).Length() {
+				test.Fail(t, pos, "!=", tt.pos)
+			}
+			if 1e-3 < tangent.Sub(tt.tangent).Length() {
+				test.Fail(t, tangent, "!=", tt.tangent)
+			}
+			if 1e-3 < normal.Sub(tt.tangent.Rot90CW()).Length() {
+				test.Fail(t, normal, "!=", tt.tangent.Rot90CW())
+			}
+			if k := p.CurvatureAt(tt.d); 1e-3 < math.Abs(k-tt.k) {
+				test.Fail(t, k, "!=", tt.k)
+			}
+		})
+	}
+}
+
+func TestPathSample(t *testing.T) {
+	test.String(t, fmt.Sprint(MustParseSVG("M0 0L10 0L10 10").Sample(5)), "[(0,0) (5,0) (10,0) (10,5) (10,10)]")
+	test.String(t, fmt.Sprint(MustParseSVG("M0 0L10 0").Sample(1)), "[(0,0)]")
+	test.T(t, len(MustParseSVG("M0 0L10 0").Sample(0)), 0)
+	test.T(t, len(MustParseSVG("M5 5").Sample(3)), 0)
+
+	// points on a circle are evenly spaced
+	ps := Circle(10.0).Sample(9)
+	for i := 1; i < len(ps); i++ {
+		test.Float(t, math.Round(ps[i].Sub(ps[i-1]).Length()*1e3)/1e3, 7.654)
+	}
+}
+
+func TestPathSimplify(t *testing.T) {
+	var tts = []struct {
+		orig       string
+		simplified string
+	}{
+		{"", ""},
+		{"M0 0L1 0.01L2 0L3 0L4 5", "M0 0L3 0L4 5"},
+		{"M0 0L5 0.01L10 0L10 10L0 10z", "M0 0L10 0L10 10L0 10z"},
+		{"M0 0L10 0.01L20 0L20 10L10 10.01L0 10z", "M0 0L20 0L20 10L0 10z"},
+		{"M0 0L1 0L2 0Q5 5 10 0L11 0.01L12 0", "M0 0L2 0Q5 5 10 0L12 0"},
+		{"L1 0.01L2 0A5 5 0 0 1 12 0L13 0z", "L2 0A5 5 0 0 1 12 0L13 0z"},
+		{"M0 0L1 0.5L2 0", "M0 0L1 0.5L2 0"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).Simplify(0.1), MustParseSVG(tt.simplified))
+		})
+	}
+}
+
 func TestPathTransform(t *testing.T) {
 	Epsilon = 1e-3
 	var tts = []struct {
@@ -393,6 +468,27 @@ func TestPathDash(t *testing.T) {
 	}
 }
 
+func TestPathHatch(t *testing.T) {
+	var tts = []struct {
+		orig     string
+		angle    float64
+		distance float64
+		hatch    string
+	}{
+		{"", 0.0, 2.0, ""},
+		{"L10 0L10 10L0 10z", 0.0, 0.0, ""},
+		{"L10 0L10 10L0 10z", 0.0, 4.0, "M0 0L10 0M0 4L10 4M0 8L10 8"},
+		{"M1 1L10 1L10 10L1 10", 0.0, 4.0, "M1 4L10 4M1 8L10 8"},
+		{"L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z", 0.0, 4.0, "M0 0L10 0M0 4L2 4M8 4L10 4M0 8L10 8"},
+		{"L10 0L10 10L0 10z", 90.0, 4.0, "M8 0L8 10M4 0L4 10M0 0L0 10"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).Hatch(tt.angle, tt.distance), MustParseSVG(tt.hatch))
+		})
+	}
+}
+
 func TestPathReverse(t *testing.T) {
 	var tts = []struct {
 		orig string
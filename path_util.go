@@ -602,3 +602,217 @@ func strokeCubicBezier(p0, p1, p2, p3 Point, d, flatness float64) *Path {
 	}
 	return p
 }
+
+////////////////////////////////////////////////////////////////
+
+// pathSegment is a segment of a path that is evaluated by its curve parameter t in [0,1]. For arcs the parameter is proportional to the angle.
+type pathSegment struct {
+	cmd                  float64
+	start, cp1, cp2, end Point
+	rx, ry, phi          float64
+	cx, cy               float64
+	theta0, theta1       float64
+}
+
+// segments returns the segments of the path, excluding MoveTo commands.
+func (p *Path) segments() []pathSegment {
+	segs := make([]pathSegment, 0, len(p.d)/cmdLen(lineToCmd))
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		seg := pathSegment{cmd: cmd, start: start, end: Point{p.d[i-2], p.d[i-1]}}
+		switch cmd {
+		case quadToCmd:
+			seg.cp1 = Point{p.d[i-4], p.d[i-3]}
+		case cubeToCmd:
+			seg.cp1 = Point{p.d[i-6], p.d[i-5]}
+			seg.cp2 = Point{p.d[i-4], p.d[i-3]}
+		case arcToCmd:
+			seg.rx, seg.ry, seg.phi = p.d[i-6], p.d[i-5], p.d[i-4]
+			largeArc, sweep := fromArcFlags(p.d[i-3])
+			seg.cx, seg.cy, seg.theta0, seg.theta1 = ellipseToCenter(start.X, start.Y, seg.rx, seg.ry, seg.phi, largeArc, sweep, seg.end.X, seg.end.Y)
+		}
+		if cmd != moveToCmd {
+			segs = append(segs, seg)
+		}
+		start = seg.end
+	}
+	return segs
+}
+
+func (s pathSegment) isLine() bool {
+	return s.cmd == lineToCmd || s.cmd == closeCmd
+}
+
+func (s pathSegment) pos(t float64) Point {
+	switch s.cmd {
+	case quadToCmd:
+		return quadraticBezierPos(s.start, s.cp1, s.end, t)
+	case cubeToCmd:
+		return cubicBezierPos(s.start, s.cp1, s.cp2, s.end, t)
+	case arcToCmd:
+		return ellipsePos(s.rx, s.ry, s.phi, s.cx, s.cy, s.theta0+t*(s.theta1-s.theta0))
+	}
+	return s.start.Interpolate(s.end, t)
+}
+
+// deriv returns the derivative with respect to t.
+func (s pathSegment) deriv(t float64) Point {
+	switch s.cmd {
+	case quadToCmd:
+		return quadraticBezierDeriv(s.start, s.cp1, s.end, t)
+	case cubeToCmd:
+		return cubicBezierDeriv(s.start, s.cp1, s.cp2, s.end, t)
+	case arcToCmd:
+		return ellipseDeriv(s.rx, s.ry, s.phi, true, s.theta0+t*(s.theta1-s.theta0)).Mul(s.theta1 - s.theta0)
+	}
+	return s.end.Sub(s.start)
+}
+
+// deriv2 returns the second derivative with respect to t.
+func (s pathSegment) deriv2(t float64) Point {
+	switch s.cmd {
+	case quadToCmd:
+		return s.start.Sub(s.cp1.Mul(2.0)).Add(s.end).Mul(2.0)
+	case cubeToCmd:
+		return cubicBezierDeriv2(s.start, s.cp1, s.cp2, s.end, t)
+	case arcToCmd:
+		dtheta := s.theta1 - s.theta0
+		return ellipseDeriv2(s.rx, s.ry, s.phi, true, s.theta0+t*dtheta).Mul(dtheta * dtheta)
+	}
+	return Point{}
+}
+
+// direction returns the unit tangent at t. Where the derivative vanishes, such as at a Bézier end point that coincides with its control point, it uses the direction between nearby points instead.
+func (s pathSegment) direction(t float64) Point {
+	if d := s.deriv(t); !d.IsZero() {
+		return d.Norm(1.0)
+	}
+	const dt = 1e-6
+	return s.pos(math.Min(1.0, t+dt)).Sub(s.pos(math.Max(0.0, t-dt))).Norm(1.0)
+}
+
+// flatten returns the curve parameters of the vertices of a polyline that approximates the segment within Tolerance.
+func (s pathSegment) flatten() []float64 {
+	ts := []float64{0.0}
+	if s.isLine() {
+		return append(ts, 1.0)
+	}
+
+	var subdivide func(float64, float64, Point, Point, int)
+	subdivide = func(t0, t1 float64, p0, p1 Point, depth int) {
+		t := (t0 + t1) / 2.0
+		p := s.pos(t)
+		if depth < 16 && (depth < 3 || Tolerance < distanceToLineSegment(p, p0, p1)) {
+			subdivide(t0, t, p0, p, depth+1)
+			subdivide(t, t1, p, p1, depth+1)
+		} else {
+			ts = append(ts, t1)
+		}
+	}
+	subdivide(0.0, 1.0, s.start, s.end, 0)
+	return ts
+}
+
+// closestLineSegment returns the parameter in [0,1] of the point on the line segment from a to b that is closest to p.
+func closestLineSegment(p, a, b Point) float64 {
+	ab := b.Sub(a)
+	if ab.IsZero() {
+		return 0.0
+	}
+	return math.Max(0.0, math.Min(1.0, p.Sub(a).Dot(ab)/ab.Dot(ab)))
+}
+
+func distanceToLineSegment(p, a, b Point) float64 {
+	return p.Sub(a.Interpolate(b, closestLineSegment(p, a, b))).Length()
+}
+
+// ramerDouglasPeucker returns the points of the polyline that are needed so that the polyline through them deviates at most tolerance from the original, the first and last points are always kept.
+func ramerDouglasPeucker(points []Point, tolerance float64) []Point {
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	var simplify func(int, int)
+	simplify = func(i, j int) {
+		dmax, kmax := 0.0, 0
+		for k := i + 1; k < j; k++ {
+			if d := distanceToLineSegment(points[k], points[i], points[j]); dmax < d {
+				dmax, kmax = d, k
+			}
+		}
+		if tolerance < dmax {
+			keep[kmax] = true
+			simplify(i, kmax)
+			simplify(kmax, j)
+		}
+	}
+	simplify(0, len(points)-1)
+
+	simplified := []Point{}
+	for i, point := range points {
+		if keep[i] {
+			simplified = append(simplified, point)
+		}
+	}
+	return simplified
+}
+
+// arcSegment is a path segment together with its length and the inverse of its arc length function, which maps the distance along the segment to its curve parameter.
+type arcSegment struct {
+	pathSegment
+	length float64
+	t      func(float64) float64
+}
+
+// arcSegments returns the segments of the path that have a non-zero length.
+func (p *Path) arcSegments() []arcSegment {
+	segs := []arcSegment{}
+	for _, seg := range p.segments() {
+		var t func(float64) float64
+		var length float64
+		if seg.isLine() {
+			length = seg.end.Sub(seg.start).Length()
+			t = func(d float64) float64 {
+				return d / length
+			}
+		} else {
+			s := seg
+			speed := func(t float64) float64 {
+				return s.deriv(t).Length()
+			}
+			approx, _ := invSpeedPolynomialChebyshevApprox(10, gaussLegendre5, speed, 0.0, 1.0)
+			length = gaussLegendre7(speed, 0.0, 1.0)
+			t = func(d float64) float64 {
+				// refine the approximation using Newton's method
+				t := math.Max(0.0, math.Min(1.0, approx(d)))
+				for i := 0; i < 4; i++ {
+					v := speed(t)
+					if equal(v, 0.0) {
+						break
+					}
+					t = math.Max(0.0, math.Min(1.0, t-(gaussLegendre7(speed, 0.0, t)-d)/v))
+				}
+				return t
+			}
+		}
+		if !equal(length, 0.0) {
+			segs = append(segs, arcSegment{seg, length, t})
+		}
+	}
+	return segs
+}
+
+// arcSegmentAt returns the segment and its curve parameter at distance d along the segments, where d is clamped to the total length. At the boundary between two segments it returns the latter.
+func arcSegmentAt(segs []arcSegment, d float64) (pathSegment, float64) {
+	if d <= 0.0 {
+		return segs[0].pathSegment, 0.0
+	}
+	for _, seg := range segs {
+		if d < seg.length {
+			return seg.pathSegment, seg.t(d)
+		}
+		d -= seg.length
+	}
+	return segs[len(segs)-1].pathSegment, 1.0
+}
@@ -0,0 +1,87 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Pattern is a paint that repeats a tile of drawing operations in a grid, it can be set as the fill or stroke of a Canvas. Draw onto the tile using the methods of its Canvas, where the tile spans from (0,0) to (W,H) and anything drawn outside of it is clipped. The pattern is specified in the coordinate system of the drawn path, ie. it is transformed by the position and view of DrawPath, and the tiles are additionally transformed by M.
+type Pattern struct {
+	*Canvas
+	M Matrix
+}
+
+// NewPattern returns a new empty pattern with tiles of the given width and height.
+func NewPattern(w, h float64) *Pattern {
+	return &Pattern{New(w, h), Identity}
+}
+
+// NewPathPattern returns a pattern with tiles of the given width and height that are filled by the path using the given color.
+func NewPathPattern(path *Path, w, h float64, col color.Color) *Pattern {
+	pattern := NewPattern(w, h)
+	pattern.SetFillColor(col)
+	pattern.DrawPath(0.0, 0.0, path)
+	return pattern
+}
+
+// NewHatchPattern returns a pattern of parallel lines at the given angle in degrees counter-clockwise from the x-axis, with the given distance between the lines and the given line width.
+func NewHatchPattern(angle, distance, lineWidth float64, col color.Color) *Pattern {
+	pattern := NewPathPattern(Rectangle(distance, lineWidth).Translate(0.0, (distance-lineWidth)/2.0), distance, distance, col)
+	pattern.M = Identity.Rotate(angle)
+	return pattern
+}
+
+// NewCrossHatchPattern returns a pattern of two sets of perpendicular parallel lines, with the first set at the given angle in degrees counter-clockwise from the x-axis, with the given distance between the lines and the given line width.
+func NewCrossHatchPattern(angle, distance, lineWidth float64, col color.Color) *Pattern {
+	pattern := NewPattern(distance, distance)
+	pattern.SetFillColor(col)
+	pattern.DrawPath(0.0, (distance-lineWidth)/2.0, Rectangle(distance, lineWidth))
+	pattern.DrawPath((distance-lineWidth)/2.0, 0.0, Rectangle(lineWidth, (distance-lineWidth)/2.0))
+	pattern.DrawPath((distance-lineWidth)/2.0, (distance+lineWidth)/2.0, Rectangle(lineWidth, (distance-lineWidth)/2.0))
+	pattern.M = Identity.Rotate(angle)
+	return pattern
+}
+
+// NewDotPattern returns a pattern of dots with the given radius, placed on a square grid with the given distance between the dots.
+func NewDotPattern(distance, radius float64, col color.Color) *Pattern {
+	return NewPathPattern(Circle(radius).Translate(distance/2.0, distance/2.0), distance, distance, col)
+}
+
+// patternImage is an image of a pattern that is used as a source for rasterization. The tile is rasterized once at a resolution that matches the transformation from pattern coordinates to pixel coordinates.
+type patternImage struct {
+	tile   *image.RGBA
+	sx, sy float64 // pixels per unit of the tile
+	w, h   float64
+	m      Matrix // maps pixel coordinates to pattern coordinates
+}
+
+// newPatternImage returns the source image of the pattern, where m maps pattern coordinates to pixel coordinates.
+func newPatternImage(pattern *Pattern, m Matrix) patternImage {
+	scale := math.Sqrt(math.Abs(m.Det()))
+	tw := math.Max(1.0, math.Ceil(pattern.W*scale))
+	th := math.Max(1.0, math.Ceil(pattern.H*scale))
+	sx, sy := tw/pattern.W, th/pattern.H
+
+	tile := image.NewRGBA(image.Rect(0, 0, int(tw), int(th)))
+	writeImageLayers(tile, pattern.layers, Identity.Scale(sx, -sy).Translate(0.0, -pattern.H))
+	return patternImage{tile, sx, sy, pattern.W, pattern.H, m.Inv()}
+}
+
+func (img patternImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img patternImage) Bounds() image.Rectangle {
+	return image.Rectangle{image.Point{-1e9, -1e9}, image.Point{1e9, 1e9}}
+}
+
+func (img patternImage) At(x, y int) color.Color {
+	p := img.m.Dot(Point{float64(x) + 0.5, float64(y) + 0.5})
+	px := p.X - img.w*math.Floor(p.X/img.w)
+	py := p.Y - img.h*math.Floor(p.Y/img.h)
+	bounds := img.tile.Bounds()
+	u := int(math.Min(px*img.sx, float64(bounds.Max.X-1)))
+	v := int(math.Min((img.h-py)*img.sy, float64(bounds.Max.Y-1)))
+	return img.tile.RGBAAt(u, v)
+}
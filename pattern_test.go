@@ -0,0 +1,67 @@
+package canvas
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPattern(t *testing.T) {
+	pattern := NewPathPattern(Rectangle(1, 1), 2, 2, Red)
+	pattern.M = Identity.Scale(2, 2)
+
+	c := New(10, 10)
+	c.SetFillPattern(pattern)
+	c.DrawPath(1, 1, Rectangle(8, 8))
+	c.DrawPath(1, 1, Rectangle(4, 4))
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><pattern id="p0" patternUnits="userSpaceOnUse" width="2" height="2" patternTransform="matrix(2,0,0,2,1,5)"><path d="M0 2H1V1H0z" fill="#f00"/></pattern></defs><path d="M1 9H9V1H1z" fill="url(#p0)"/><path d="M1 9H5V5H1z" fill="url(#p0)"/></svg>`)
+
+	// a different transformation requires a new pattern
+	c.SetView(Identity.Scale(0.5, 0.5))
+	c.DrawPath(1, 1, Rectangle(4, 4))
+	buf.Reset()
+	c.WriteSVG(buf)
+	test.T(t, strings.Count(buf.String(), "<pattern "), 2)
+
+	pdfCompress = false
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	s := buf.String()
+	test.T(t, strings.Count(s, "/Type /Pattern /BBox [0 0 2 2] /Length 41 /Matrix [2 0 0 2 1 1] /PaintType 1 /PatternType 1"), 1)
+	test.That(t, strings.Contains(s, "/XStep 2 /YStep 2"), "pattern steps")
+	test.That(t, strings.Contains(s, "/Pattern cs /P0 scn 1 1 m 9 1 l 9 9 l 1 9 l f 1 1 m 5 1 l"), "pattern fill set once")
+	pdfCompress = true
+
+	buf.Reset()
+	test.Error(t, c.writeEPS(buf))
+	test.T(t, strings.Count(buf.String(), "/PaintProc { pop\n gsave 0 0 moveto 1 0 lineto 1 1 lineto 0 1 lineto closepath 1 0 0 setrgbcolor gsave fill grestore grestore } >> [2 0 0 2 1 1] makepattern setpattern fill grestore"), 2)
+}
+
+func TestPatternWriteImage(t *testing.T) {
+	c := New(8, 8)
+	c.SetFillPattern(NewHatchPattern(0.0, 2.0, 1.0, Black))
+	c.DrawPath(0, 0, Rectangle(8, 8))
+	img := c.WriteImage(2.0)
+	for y := 0; y < 16; y++ {
+		col := color.RGBA{255, 255, 255, 255}
+		if y%4 == 1 || y%4 == 2 {
+			col = color.RGBA{0, 0, 0, 255}
+		}
+		test.T(t, img.RGBAAt(3, y), col)
+	}
+
+	c = New(8, 8)
+	c.SetStrokeColor(Transparent)
+	c.SetFillPattern(NewDotPattern(4.0, 1.0, Black))
+	c.DrawPath(0, 0, Rectangle(8, 8))
+	img = c.WriteImage(4.0)
+	test.T(t, img.RGBAAt(8, 8), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(24, 8), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(16, 16), color.RGBA{255, 255, 255, 255})
+}
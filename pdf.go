@@ -3,15 +3,21 @@ package canvas
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/md5"
+	"crypto/sha256"
 	"encoding/ascii85"
-	"encoding/binary"
 	"fmt"
+	"hash"
+	"html"
 	"image"
 	"image/color"
+	"image/jpeg"
 	"io"
 	"math"
 	"sort"
 	"strings"
+	"time"
+	"unicode/utf16"
 
 	"golang.org/x/image/font"
 )
@@ -25,20 +31,79 @@ type pdfWriter struct {
 	pos        int
 	objOffsets []int
 
-	fonts map[*Font]pdfRef
-	pages []*pdfPageWriter
+	fonts     map[*Font]*pdfFont
+	images    map[[sha256.Size]byte]pdfRef
+	symbols   map[*Symbol]pdfForm
+	masks     map[clipPath]pdfRef
+	patterns  map[pdfPattern]pdfRef
+	gradients map[pdfGradient]pdfRef
+	pages     []*pdfPageWriter
+
+	metadata   Metadata
+	outlines   []*Outline
+	pageLabels []PageLabel
+
+	pdfa bool
+	hash hash.Hash // hash of the written bytes used for the document ID
+}
+
+// pdfPattern is a tiling pattern with the matrix that maps pattern coordinates to page coordinates.
+type pdfPattern struct {
+	pattern *Pattern
+	m       Matrix
+}
+
+// pdfGradient is a shading pattern of a gradient with the matrix that maps gradient coordinates to page coordinates. The bounds of the painted area in page coordinates are only set for gradients that repeat, as their shading covers the bounds explicitly.
+type pdfGradient struct {
+	gradient Gradient
+	m        Matrix
+	bounds   Rect
+	alpha    bool
+}
+
+// pdfForm is a form XObject with the links of its contents in form coordinates, which are added to the pages where the form is drawn.
+type pdfForm struct {
+	ref   pdfRef
+	links []pdfLink
+}
+
+// pdfFont is a font used in the PDF, it is written when closing the PDF so that only the used glyphs are embedded.
+type pdfFont struct {
+	ref    pdfRef
+	subset *fontSubset
 }
 
-func newPDFWriter(writer io.Writer) *pdfWriter {
+// newPDFWriter returns a PDF writer, which writes a PDF/A-2b conforming file if pdfa is set.
+func newPDFWriter(writer io.Writer, pdfa bool) *pdfWriter {
 	w := &pdfWriter{
-		w:     writer,
-		fonts: map[*Font]pdfRef{},
+		w:         writer,
+		fonts:     map[*Font]*pdfFont{},
+		images:    map[[sha256.Size]byte]pdfRef{},
+		symbols:   map[*Symbol]pdfForm{},
+		masks:     map[clipPath]pdfRef{},
+		patterns:  map[pdfPattern]pdfRef{},
+		gradients: map[pdfGradient]pdfRef{},
+		pdfa:      pdfa,
+	}
+	if w.pdfa {
+		w.hash = md5.New()
+		w.w = io.MultiWriter(writer, w.hash)
 	}
 
 	w.write("%%PDF-1.7\n")
+	if w.pdfa {
+		w.write("%%\xE2\xE3\xCF\xD3\n") // binary comment that marks the file as binary
+	}
 	return w
 }
 
+// pdfaError sets the error of the violation of PDF/A conformance, if no error occurred before.
+func (w *pdfWriter) pdfaError(format string, a ...interface{}) {
+	if w.err == nil {
+		w.err = fmt.Errorf("PDF/A: "+format, a...)
+	}
+}
+
 func (w *pdfWriter) writeBytes(b []byte) {
 	if w.err != nil {
 		return
@@ -62,6 +127,7 @@ type pdfName string
 type pdfArray []interface{}
 type pdfDict map[pdfName]interface{}
 type pdfFilter string
+type pdfHexString []byte
 type pdfStream struct {
 	dict   pdfDict
 	stream []byte
@@ -70,6 +136,7 @@ type pdfStream struct {
 const (
 	pdfFilterASCII85 pdfFilter = "ASCII85Decode"
 	pdfFilterFlate   pdfFilter = "FlateDecode"
+	pdfFilterDCT     pdfFilter = "DCTDecode"
 )
 
 func (w *pdfWriter) writeVal(i interface{}) {
@@ -88,7 +155,10 @@ func (w *pdfWriter) writeVal(i interface{}) {
 		v = strings.Replace(v, `\`, `\\`, -1)
 		v = strings.Replace(v, `(`, `\(`, -1)
 		v = strings.Replace(v, `)`, `\)`, -1)
+		v = strings.Replace(v, "\r", `\r`, -1)
 		w.write("(%v)", v)
+	case pdfHexString:
+		w.write("<%X>", []byte(v))
 	case pdfRef:
 		w.write("%v 0 R", v)
 	case pdfName, pdfFilter:
@@ -156,6 +226,8 @@ func (w *pdfWriter) writeVal(i interface{}) {
 				w := zlib.NewWriter(&b2)
 				w.Write(b)
 				w.Close()
+			case pdfFilterDCT:
+				continue // data is already JPEG encoded
 			}
 			b = b2.Bytes()
 		}
@@ -171,34 +243,73 @@ func (w *pdfWriter) writeVal(i interface{}) {
 }
 
 func (w *pdfWriter) writeObject(val interface{}) pdfRef {
-	w.objOffsets = append(w.objOffsets, w.pos)
-	w.write("%v 0 obj\n", len(w.objOffsets))
+	ref := w.reserveObject()
+	w.writeObjectAt(ref, val)
+	return ref
+}
+
+// reserveObject returns a reference to an object that is written later on using writeObjectAt.
+func (w *pdfWriter) reserveObject() pdfRef {
+	w.objOffsets = append(w.objOffsets, 0)
+	return pdfRef(len(w.objOffsets))
+}
+
+func (w *pdfWriter) writeObjectAt(ref pdfRef, val interface{}) {
+	w.objOffsets[ref-1] = w.pos
+	w.write("%v 0 obj\n", ref)
 	w.writeVal(val)
 	w.write("\nendobj\n")
-	return pdfRef(len(w.objOffsets))
 }
 
-func (w *pdfWriter) getFont(font *Font) pdfRef {
-	if ref, ok := w.fonts[font]; ok {
-		return ref
+// getFont returns the reference to the font, which will be written when closing the PDF.
+func (w *pdfWriter) getFont(font *Font) *pdfFont {
+	if f, ok := w.fonts[font]; ok {
+		return f
 	}
 
-	mimetype, b := font.Raw()
+	mimetype, _ := font.Raw()
 	if mimetype != "font/truetype" && mimetype != "font/opentype" {
 		panic("only TTF and OTF formats supported for embedding fonts in PDFs")
 	}
+	w.fonts[font] = &pdfFont{
+		ref:    w.reserveObject(),
+		subset: newFontSubset(),
+	}
+	return w.fonts[font]
+}
 
+// writeFont writes the font with only the glyphs that have been used, renumbered in order of use.
+func (w *pdfWriter) writeFont(font *Font, f *pdfFont) {
+	mimetype, _ := font.Raw()
+	fontFile := pdfName("FontFile3")
 	ffSubtype := ""
 	cidSubtype := ""
 	if mimetype == "font/truetype" {
-		ffSubtype = "TrueType"
+		fontFile = "FontFile2"
 		cidSubtype = "CIDFontType2"
 	} else if mimetype == "font/opentype" {
 		ffSubtype = "OpenType"
 		cidSubtype = "CIDFontType0"
+	} else if w.pdfa {
+		w.pdfaError("font %s of type %s cannot be embedded", font.name, mimetype)
+		return
 	}
 
-	bounds, italicAngle, ascent, descent, capHeight, widths := font.pdfInfo()
+	b, glyphs, err := font.subset(f.subset.glyphs)
+	if err != nil {
+		if w.err == nil {
+			w.err = fmt.Errorf("subsetting font %s: %w", font.name, err)
+		}
+		return
+	}
+
+	bounds, italicAngle, ascent, descent, capHeight, fontWidths := font.pdfInfo()
+	widths := make([]int, len(glyphs))
+	for i, glyph := range glyphs {
+		if int(glyph) < len(fontWidths) {
+			widths[i] = fontWidths[glyph]
+		}
+	}
 
 	// shorten glyph widths array
 	DW := widths[0]
@@ -230,19 +341,33 @@ func (w *pdfWriter) getFont(font *Font) pdfRef {
 		W = append(W, i, arr)
 	}
 
-	baseFont := strings.ReplaceAll(font.name, " ", "_")
+	baseFont := f.subset.Tag() + "+" + strings.ReplaceAll(font.name, " ", "_")
+	fontfileDict := pdfDict{
+		"Filter": pdfFilterFlate,
+	}
+	if fontFile == "FontFile2" {
+		fontfileDict["Length1"] = len(b)
+	} else {
+		fontfileDict["Subtype"] = pdfName(ffSubtype)
+	}
 	fontfileRef := w.writeObject(pdfStream{
-		dict: pdfDict{
-			"Subtype": pdfName(ffSubtype),
-			"Filter":  pdfFilterFlate,
-		},
+		dict:   fontfileDict,
 		stream: b,
 	})
-	ref := w.writeObject(pdfDict{
-		"Type":     pdfName("Font"),
-		"Subtype":  pdfName("Type0"),
-		"BaseFont": pdfName(baseFont),
-		"Encoding": pdfName("Identity-H"),
+	toUnicode := pdfStream{
+		dict:   pdfDict{},
+		stream: f.subset.ToUnicode(),
+	}
+	if pdfCompress {
+		toUnicode.dict["Filter"] = pdfFilterFlate
+	}
+	toUnicodeRef := w.writeObject(toUnicode)
+	w.writeObjectAt(f.ref, pdfDict{
+		"Type":      pdfName("Font"),
+		"Subtype":   pdfName("Type0"),
+		"BaseFont":  pdfName(baseFont),
+		"Encoding":  pdfName("Identity-H"),
+		"ToUnicode": toUnicodeRef,
 		"DescendantFonts": pdfArray{pdfDict{
 			"Type":        pdfName("Font"),
 			"Subtype":     pdfName(cidSubtype),
@@ -266,19 +391,32 @@ func (w *pdfWriter) getFont(font *Font) pdfRef {
 				"CapHeight":   -int(capHeight),
 				"StemV":       80, // taken from Inkscape, should be calculated somehow
 				"StemH":       80,
-				"FontFile3":   fontfileRef,
+				fontFile:      fontfileRef,
 			},
 		}},
 	})
-	w.fonts[font] = ref
-	return ref
 }
 
 func (w *pdfWriter) Close() error {
-	parent := pdfRef(len(w.objOffsets) + 1 + len(w.pages))
+	fonts := []*Font{}
+	for font := range w.fonts {
+		fonts = append(fonts, font)
+	}
+	sort.Slice(fonts, func(i, j int) bool {
+		return w.fonts[fonts[i]].ref < w.fonts[fonts[j]].ref
+	})
+	for _, font := range fonts {
+		w.writeFont(font, w.fonts[font])
+	}
+
+	parent := pdfRef(len(w.objOffsets) + 1 + 2*len(w.pages)) // each page writes its contents and page object
+	pageRefs := make([]pdfRef, len(w.pages))
+	for i := range w.pages {
+		pageRefs[i] = pdfRef(len(w.objOffsets) + 2*i + 2)
+	}
 	kids := pdfArray{}
 	for _, p := range w.pages {
-		kids = append(kids, p.writePage(parent))
+		kids = append(kids, p.writePage(parent, pageRefs))
 	}
 
 	refPages := w.writeObject(pdfDict{
@@ -287,10 +425,49 @@ func (w *pdfWriter) Close() error {
 		"Count": len(kids),
 	})
 
-	refCatalog := w.writeObject(pdfDict{
+	catalog := pdfDict{
 		"Type":  pdfName("Catalog"),
 		"Pages": refPages,
-	})
+	}
+	if 0 < len(w.outlines) {
+		catalog["Outlines"] = w.writeOutlines(pageRefs)
+		catalog["PageMode"] = pdfName("UseOutlines")
+	}
+	if 0 < len(w.pageLabels) {
+		catalog["PageLabels"] = w.pageLabelsDict()
+	}
+	if w.pdfa {
+		icc := pdfStream{
+			dict: pdfDict{
+				"N": 3,
+			},
+			stream: sRGBProfile(),
+		}
+		if pdfCompress {
+			icc.dict["Filter"] = pdfFilterFlate
+		}
+		catalog["OutputIntents"] = pdfArray{pdfDict{
+			"Type":                      pdfName("OutputIntent"),
+			"S":                         pdfName("GTS_PDFA1"),
+			"OutputConditionIdentifier": "sRGB IEC61966-2.1",
+			"Info":                      "sRGB IEC61966-2.1",
+			"DestOutputProfile":         w.writeObject(icc),
+		}}
+	}
+	trailer := pdfDict{}
+	if !w.metadata.Empty() || w.pdfa {
+		catalog["Metadata"] = w.writeObject(pdfStream{
+			dict: pdfDict{
+				"Type":    pdfName("Metadata"),
+				"Subtype": pdfName("XML"),
+			},
+			stream: w.metadata.xmp(w.pdfa),
+		})
+	}
+	if !w.metadata.Empty() {
+		trailer["Info"] = w.writeObject(w.metadata.pdfInfo())
+	}
+	refCatalog := w.writeObject(catalog)
 
 	xrefOffset := w.pos
 	w.write("xref\n0 %d\n0000000000 65535 f\n", len(w.objOffsets)+1)
@@ -298,14 +475,187 @@ func (w *pdfWriter) Close() error {
 		w.write("%010d 00000 n\n", objOffset)
 	}
 	w.write("trailer\n")
-	w.writeVal(pdfDict{
-		"Root": refCatalog,
-		"Size": len(w.objOffsets),
-	})
+	trailer["Root"] = refCatalog
+	trailer["Size"] = len(w.objOffsets) + 1
+	if w.pdfa {
+		id := pdfHexString(w.hash.Sum(nil))
+		trailer["ID"] = pdfArray{id, id}
+	}
+	w.writeVal(trailer)
 	w.write("\nstarxref\n%v\n%%%%EOF", xrefOffset)
 	return w.err
 }
 
+// writeOutlines writes the outline tree and returns the reference to its root.
+func (w *pdfWriter) writeOutlines(pageRefs []pdfRef) pdfRef {
+	root := w.reserveObject()
+	first, last, count := w.writeOutlineItems(root, w.outlines, pageRefs)
+	w.writeObjectAt(root, pdfDict{
+		"Type":  pdfName("Outlines"),
+		"First": first,
+		"Last":  last,
+		"Count": count,
+	})
+	return root
+}
+
+// writeOutlineItems writes the outline items that have the same parent, and returns the references to the first and last items and the total number of items including the nested items.
+func (w *pdfWriter) writeOutlineItems(parent pdfRef, items []*Outline, pageRefs []pdfRef) (pdfRef, pdfRef, int) {
+	refs := make([]pdfRef, len(items))
+	for i := range items {
+		refs[i] = w.reserveObject()
+	}
+
+	count := len(items)
+	for i, item := range items {
+		dict := pdfDict{
+			"Title":  pdfText(item.Title),
+			"Parent": parent,
+		}
+		if 0 < i {
+			dict["Prev"] = refs[i-1]
+		}
+		if i+1 < len(items) {
+			dict["Next"] = refs[i+1]
+		}
+		if 0 <= item.Page && item.Page < len(pageRefs) {
+			dict["Dest"] = pdfArray{pageRefs[item.Page], pdfName("XYZ"), item.X, item.Y, 0}
+		} else if w.err == nil {
+			w.err = fmt.Errorf("outline to page %d, but document has %d pages", item.Page, len(pageRefs))
+		}
+		if 0 < len(item.children) {
+			first, last, n := w.writeOutlineItems(refs[i], item.children, pageRefs)
+			dict["First"] = first
+			dict["Last"] = last
+			dict["Count"] = n
+			count += n
+		}
+		w.writeObjectAt(refs[i], dict)
+	}
+	return refs[0], refs[len(refs)-1], count
+}
+
+// pageLabelsDict returns the number tree of the page labels, where the first page is always included.
+func (w *pdfWriter) pageLabelsDict() pdfDict {
+	labels := make([]PageLabel, len(w.pageLabels))
+	copy(labels, w.pageLabels)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Page < labels[j].Page })
+	if labels[0].Page != 0 {
+		labels = append([]PageLabel{{Page: 0, Style: DecimalLabels}}, labels...)
+	}
+
+	nums := pdfArray{}
+	for _, label := range labels {
+		dict := pdfDict{}
+		switch label.Style {
+		case DecimalLabels:
+			dict["S"] = pdfName("D")
+		case UpperRomanLabels:
+			dict["S"] = pdfName("R")
+		case LowerRomanLabels:
+			dict["S"] = pdfName("r")
+		case UpperLetterLabels:
+			dict["S"] = pdfName("A")
+		case LowerLetterLabels:
+			dict["S"] = pdfName("a")
+		}
+		if label.Prefix != "" {
+			dict["P"] = pdfText(label.Prefix)
+		}
+		if 1 < label.Start {
+			dict["St"] = label.Start
+		}
+		nums = append(nums, label.Page, dict)
+	}
+	return pdfDict{"Nums": nums}
+}
+
+// pdfInfo returns the document information dictionary.
+func (m Metadata) pdfInfo() pdfDict {
+	info := pdfDict{}
+	if m.Title != "" {
+		info["Title"] = pdfText(m.Title)
+	}
+	if m.Author != "" {
+		info["Author"] = pdfText(m.Author)
+	}
+	if m.Subject != "" {
+		info["Subject"] = pdfText(m.Subject)
+	}
+	if m.Keywords != "" {
+		info["Keywords"] = pdfText(m.Keywords)
+	}
+	if m.Creator != "" {
+		info["Creator"] = pdfText(m.Creator)
+	}
+	if !m.CreationDate.IsZero() {
+		info["CreationDate"] = pdfDate(m.CreationDate)
+	}
+	return info
+}
+
+// xmp returns the XMP metadata packet, which mirrors the document information dictionary and includes the PDF/A identification if pdfa is set.
+func (m Metadata) xmp(pdfa bool) []byte {
+	b := &bytes.Buffer{}
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`)
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">`)
+	b.WriteString(`<dc:format>application/pdf</dc:format>`)
+	if m.Title != "" {
+		fmt.Fprintf(b, `<dc:title><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:title>`, html.EscapeString(m.Title))
+	}
+	if m.Author != "" {
+		fmt.Fprintf(b, `<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>`, html.EscapeString(m.Author))
+	}
+	if m.Subject != "" {
+		fmt.Fprintf(b, `<dc:description><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:description>`, html.EscapeString(m.Subject))
+	}
+	if m.Keywords != "" {
+		fmt.Fprintf(b, `<pdf:Keywords>%s</pdf:Keywords>`, html.EscapeString(m.Keywords))
+	}
+	if m.Creator != "" {
+		fmt.Fprintf(b, `<xmp:CreatorTool>%s</xmp:CreatorTool>`, html.EscapeString(m.Creator))
+	}
+	if !m.CreationDate.IsZero() {
+		fmt.Fprintf(b, `<xmp:CreateDate>%s</xmp:CreateDate>`, m.CreationDate.Format(time.RFC3339))
+	}
+	b.WriteString(`</rdf:Description>`)
+	if pdfa {
+		b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/"><pdfaid:part>2</pdfaid:part><pdfaid:conformance>B</pdfaid:conformance></rdf:Description>`)
+	}
+	b.WriteString("</rdf:RDF></x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// pdfText returns a text string, which is encoded in UTF-16BE if it contains non-ASCII characters.
+func pdfText(s string) string {
+	for _, r := range s {
+		if 0x80 <= r {
+			b := []byte{0xFE, 0xFF}
+			for _, c := range utf16.Encode([]rune(s)) {
+				b = append(b, byte(c>>8), byte(c))
+			}
+			return string(b)
+		}
+	}
+	return s
+}
+
+// pdfDate returns a date string such as D:20060102150405+07'00'.
+func pdfDate(t time.Time) string {
+	s := t.Format("D:20060102150405")
+	_, offset := t.Zone()
+	if offset == 0 {
+		return s + "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return s + fmt.Sprintf("%c%02d'%02d'", sign, offset/3600, offset/60%60)
+}
+
 type pdfPageWriter struct {
 	*bytes.Buffer
 	pdf           *pdfWriter
@@ -313,9 +663,32 @@ type pdfPageWriter struct {
 	resources     pdfDict
 
 	graphicsStates map[float64]pdfName
+	groupStates    map[pdfGroupState]pdfName
+	maskStates     map[pdfRef]pdfName
+	links          []pdfLink
+	pdfState
+	stack []pdfState
+}
+
+// pdfGroupState is the opacity and blend mode with which a transparency group is drawn.
+type pdfGroupState struct {
+	opacity   float64
+	blendMode BlendMode
+}
+
+// pdfLink is a link annotation of a page, it is written when closing the PDF so that links to any page can be resolved.
+type pdfLink struct {
+	area *Path
+	link Link
+}
+
+// pdfState keeps track of the graphics state so that unchanged values are not written again.
+type pdfState struct {
 	alpha          float64
 	fillColor      color.RGBA
+	fillPattern    pdfName
 	strokeColor    color.RGBA
+	strokePattern  pdfName
 	lineWidth      float64
 	lineCap        int
 	lineJoin       int
@@ -329,32 +702,41 @@ type pdfPageWriter struct {
 }
 
 func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
+	w.pages = append(w.pages, w.newPageWriter(width, height))
+	return w.pages[len(w.pages)-1]
+}
+
+// newPageWriter returns a writer for the contents of a page or a form XObject.
+func (w *pdfWriter) newPageWriter(width, height float64) *pdfPageWriter {
 	// for defaults see https://help.adobe.com/pdfl_sdk/15/PDFL_SDK_HTMLHelp/PDFL_SDK_HTMLHelp/API_References/PDFL_API_Reference/PDFEdit_Layer/General.html#_t_PDEGraphicState
-	w.pages = append(w.pages, &pdfPageWriter{
+	return &pdfPageWriter{
 		Buffer:         &bytes.Buffer{},
 		pdf:            w,
 		width:          width,
 		height:         height,
 		resources:      pdfDict{},
 		graphicsStates: map[float64]pdfName{},
-		alpha:          1.0,
-		fillColor:      Black,
-		strokeColor:    Black,
-		lineWidth:      1.0,
-		lineCap:        0,
-		lineJoin:       0,
-		miterLimit:     10.0,
-		dashes:         []float64{0.0}, // dashArray and dashPhase
-		font:           nil,
-		fontSize:       0.0,
-		textPosition:   Identity,
-		textCharSpace:  0.0,
-		textRenderMode: 0,
-	})
-	return w.pages[len(w.pages)-1]
+		groupStates:    map[pdfGroupState]pdfName{},
+		maskStates:     map[pdfRef]pdfName{},
+		pdfState: pdfState{
+			alpha:          1.0,
+			fillColor:      Black,
+			strokeColor:    Black,
+			lineWidth:      1.0,
+			lineCap:        0,
+			lineJoin:       0,
+			miterLimit:     10.0,
+			dashes:         []float64{0.0}, // dashArray and dashPhase
+			font:           nil,
+			fontSize:       0.0,
+			textPosition:   Identity,
+			textCharSpace:  0.0,
+			textRenderMode: 0,
+		},
+	}
 }
 
-func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
+func (w *pdfPageWriter) writePage(parent pdfRef, pageRefs []pdfRef) pdfRef {
 	b := w.Bytes()
 	if 0 < len(b) && b[0] == ' ' {
 		b = b[1:]
@@ -367,7 +749,7 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 		stream.dict["Filter"] = pdfFilterFlate
 	}
 	contents := w.pdf.writeObject(stream)
-	return w.pdf.writeObject(pdfDict{
+	page := pdfDict{
 		"Type":      pdfName("Page"),
 		"Parent":    parent,
 		"MediaBox":  pdfArray{0.0, 0.0, w.width, w.height},
@@ -379,7 +761,109 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 			"CS":   pdfName("DeviceRGB"),
 		},
 		"Contents": contents,
-	})
+	}
+
+	annots := pdfArray{}
+	for _, link := range w.links {
+		bounds := link.area.Bounds()
+		annot := pdfDict{
+			"Type":    pdfName("Annot"),
+			"Subtype": pdfName("Link"),
+			"Rect":    pdfArray{bounds.X, bounds.Y, bounds.X + bounds.W, bounds.Y + bounds.H},
+			"Border":  pdfArray{0, 0, 0},
+			"F":       4, // print
+		}
+		if quadPoints := pdfQuadPoints(link.area); quadPoints != nil {
+			annot["QuadPoints"] = quadPoints
+		}
+		if link.link.URI != "" {
+			annot["A"] = pdfDict{
+				"S":   pdfName("URI"),
+				"URI": link.link.URI,
+			}
+		} else if 0 <= link.link.Page && link.link.Page < len(pageRefs) {
+			annot["Dest"] = pdfArray{pageRefs[link.link.Page], pdfName("XYZ"), link.link.X, link.link.Y, 0}
+		} else {
+			if w.pdf.err == nil {
+				w.pdf.err = fmt.Errorf("link to page %d, but document has %d pages", link.link.Page, len(pageRefs))
+			}
+			continue
+		}
+		annots = append(annots, annot)
+	}
+	if 0 < len(annots) {
+		page["Annots"] = annots
+	}
+	return w.pdf.writeObject(page)
+}
+
+// pdfQuadPoints returns the quadrilaterals that make up a link area, or nil if the area is a rectangle or cannot be expressed as quadrilaterals, in which case only its bounds are used.
+func pdfQuadPoints(area *Path) pdfArray {
+	quadPoints := pdfArray{}
+	rectangle := true
+	for _, p := range area.Split() {
+		points := []Point{}
+		for i := 0; i < len(p.d); {
+			cmd := p.d[i]
+			switch cmd {
+			case moveToCmd, lineToCmd:
+				points = append(points, Point{p.d[i+1], p.d[i+2]})
+			case closeCmd:
+			default:
+				return nil
+			}
+			i += cmdLen(cmd)
+		}
+		if 1 < len(points) && points[len(points)-1].Equals(points[0]) {
+			points = points[:len(points)-1]
+		}
+		if len(points) != 4 {
+			return nil
+		}
+
+		// corners are in counter clockwise order
+		if area := (points[1].X-points[0].X)*(points[2].Y-points[0].Y) - (points[2].X-points[0].X)*(points[1].Y-points[0].Y); area < 0.0 {
+			points[1], points[3] = points[3], points[1]
+		}
+		for i, point := range points {
+			next := points[(i+1)%4]
+			if !equal(point.X, next.X) && !equal(point.Y, next.Y) {
+				rectangle = false
+			}
+			quadPoints = append(quadPoints, point.X, point.Y)
+		}
+	}
+	if rectangle && len(quadPoints) == 8 {
+		return nil
+	}
+	return quadPoints
+}
+
+// AddLink adds a clickable area to the page that follows link.
+func (w *pdfPageWriter) AddLink(area *Path, link Link) {
+	w.links = append(w.links, pdfLink{area, link})
+}
+
+// Push saves the graphics state, which can be restored with Pop.
+func (w *pdfPageWriter) Push() {
+	fmt.Fprintf(w, " q")
+	w.stack = append(w.stack, w.pdfState)
+}
+
+// Pop restores the graphics state saved by Push.
+func (w *pdfPageWriter) Pop() {
+	fmt.Fprintf(w, " Q")
+	w.pdfState = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+// Clip intersects the clipping path with the given path data. Use Push and Pop to restore the previous clipping path.
+func (w *pdfPageWriter) Clip(data string, fillRule FillRuleType) {
+	fmt.Fprintf(w, " %v W", data)
+	if fillRule == EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	fmt.Fprintf(w, " n")
 }
 
 func (w *pdfPageWriter) SetAlpha(alpha float64) {
@@ -392,30 +876,300 @@ func (w *pdfPageWriter) SetAlpha(alpha float64) {
 
 func (w *pdfPageWriter) SetFillColor(fillColor color.RGBA) {
 	a := float64(fillColor.A) / 255.0
-	if fillColor != w.fillColor {
+	if fillColor != w.fillColor || w.fillPattern != "" {
 		if fillColor.R == fillColor.G && fillColor.R == fillColor.B {
 			fmt.Fprintf(w, " %v g", dec(float64(fillColor.R)/255.0/a))
 		} else {
 			fmt.Fprintf(w, " %v %v %v rg", dec(float64(fillColor.R)/255.0/a), dec(float64(fillColor.G)/255.0/a), dec(float64(fillColor.B)/255.0/a))
 		}
 		w.fillColor = fillColor
+		w.fillPattern = ""
 	}
 	w.SetAlpha(a)
 }
 
 func (w *pdfPageWriter) SetStrokeColor(strokeColor color.RGBA) {
 	a := float64(strokeColor.A) / 255.0
-	if strokeColor != w.strokeColor {
+	if strokeColor != w.strokeColor || w.strokePattern != "" {
 		if strokeColor.R == strokeColor.G && strokeColor.R == strokeColor.B {
 			fmt.Fprintf(w, " %v G", dec(float64(strokeColor.R)/255.0/a))
 		} else {
 			fmt.Fprintf(w, " %v %v %v RG", dec(float64(strokeColor.R)/255.0/a), dec(float64(strokeColor.G)/255.0/a), dec(float64(strokeColor.B)/255.0/a))
 		}
 		w.strokeColor = strokeColor
+		w.strokePattern = ""
 	}
 	w.SetAlpha(a)
 }
 
+// SetFillGradient sets the fill to a gradient, where m maps gradient coordinates to page coordinates and bounds is the area to be filled in page coordinates. When the alpha of the color stops varies, a soft mask is required which is set in a new graphics state. In that case it returns true and the caller must call Pop after painting.
+func (w *pdfPageWriter) SetFillGradient(g Gradient, m Matrix, bounds Rect) bool {
+	pushed := w.setGradientAlpha(g, m, bounds)
+	name := w.getGradientPattern(g, m, bounds, false)
+	if name != w.fillPattern {
+		fmt.Fprintf(w, " /Pattern cs /%v scn", name)
+		w.fillPattern = name
+	}
+	return pushed
+}
+
+// SetStrokeGradient sets the stroke to a gradient, see SetFillGradient.
+func (w *pdfPageWriter) SetStrokeGradient(g Gradient, m Matrix, bounds Rect) bool {
+	pushed := w.setGradientAlpha(g, m, bounds)
+	name := w.getGradientPattern(g, m, bounds, false)
+	if name != w.strokePattern {
+		fmt.Fprintf(w, " /Pattern CS /%v SCN", name)
+		w.strokePattern = name
+	}
+	return pushed
+}
+
+func (w *pdfPageWriter) setGradientAlpha(g Gradient, m Matrix, bounds Rect) bool {
+	if alpha := g.ColorStops().alpha(); !math.IsNaN(alpha) {
+		w.SetAlpha(alpha)
+		return false
+	}
+
+	// varying alpha, use a luminosity soft mask that paints the alpha values of the gradient
+	w.Push()
+	w.SetAlpha(1.0)
+	pattern := w.getGradientPattern(g, m, bounds, true)
+	stream := pdfStream{
+		dict: pdfDict{
+			"Type":    pdfName("XObject"),
+			"Subtype": pdfName("Form"),
+			"BBox":    pdfArray{0.0, 0.0, w.width, w.height},
+			"Group": pdfDict{
+				"Type": pdfName("Group"),
+				"S":    pdfName("Transparency"),
+				"CS":   pdfName("DeviceGray"),
+			},
+			"Resources": pdfDict{
+				"Pattern": pdfDict{
+					pattern: w.resources["Pattern"].(pdfDict)[pattern],
+				},
+			},
+		},
+		stream: []byte(fmt.Sprintf("/Pattern cs /%v scn 0 0 %v %v re f", pattern, dec(w.width), dec(w.height))),
+	}
+	if pdfCompress {
+		stream.dict["Filter"] = pdfFilterFlate
+	}
+	ref := w.pdf.writeObject(stream)
+
+	if _, ok := w.resources["ExtGState"]; !ok {
+		w.resources["ExtGState"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("S%d", len(w.resources["ExtGState"].(pdfDict))))
+	w.resources["ExtGState"].(pdfDict)[name] = pdfDict{
+		"SMask": pdfDict{
+			"Type": pdfName("Mask"),
+			"S":    pdfName("Luminosity"),
+			"G":    ref,
+		},
+	}
+	fmt.Fprintf(w, " /%v gs", name)
+	return true
+}
+
+// SetFillPattern sets the fill to a tiling pattern, where m maps pattern coordinates to page coordinates.
+func (w *pdfPageWriter) SetFillPattern(pattern *Pattern, m Matrix) {
+	w.SetAlpha(1.0)
+	name := w.getTilingPattern(pattern, m)
+	if name != w.fillPattern {
+		fmt.Fprintf(w, " /Pattern cs /%v scn", name)
+		w.fillPattern = name
+	}
+}
+
+// SetStrokePattern sets the stroke to a tiling pattern, see SetFillPattern.
+func (w *pdfPageWriter) SetStrokePattern(pattern *Pattern, m Matrix) {
+	w.SetAlpha(1.0)
+	name := w.getTilingPattern(pattern, m)
+	if name != w.strokePattern {
+		fmt.Fprintf(w, " /Pattern CS /%v SCN", name)
+		w.strokePattern = name
+	}
+}
+
+// getTilingPattern returns the resource name of the tiling pattern, writing the pattern when it is first used with the given transformation.
+func (w *pdfPageWriter) getTilingPattern(pattern *Pattern, m Matrix) pdfName {
+	key := pdfPattern{pattern, m}
+	ref, ok := w.pdf.patterns[key]
+	if !ok {
+		resources, b, _ := w.pdf.formContents(pattern.layers, pattern.W, pattern.H)
+		stream := pdfStream{
+			dict: pdfDict{
+				"Type":        pdfName("Pattern"),
+				"PatternType": 1,
+				"PaintType":   1,
+				"TilingType":  1,
+				"BBox":        pdfArray{0.0, 0.0, pattern.W, pattern.H},
+				"XStep":       pattern.W,
+				"YStep":       pattern.H,
+				"Matrix":      pdfArray{m[0][0], m[1][0], m[0][1], m[1][1], m[0][2], m[1][2]},
+				"Resources":   resources,
+			},
+			stream: b,
+		}
+		if pdfCompress {
+			stream.dict["Filter"] = pdfFilterFlate
+		}
+		ref = w.pdf.writeObject(stream)
+		w.pdf.patterns[key] = ref
+	}
+
+	if _, ok := w.resources["Pattern"]; !ok {
+		w.resources["Pattern"] = pdfDict{}
+	}
+	for name, pref := range w.resources["Pattern"].(pdfDict) {
+		if ref == pref {
+			return name
+		}
+	}
+	name := pdfName(fmt.Sprintf("P%d", len(w.resources["Pattern"].(pdfDict))))
+	w.resources["Pattern"].(pdfDict)[name] = ref
+	return name
+}
+
+// getGradientPattern returns the resource name of the shading pattern for the gradient, writing the pattern when it is first used with the given transformation. When alpha is true, the pattern paints the alpha values of the gradient in gray.
+func (w *pdfPageWriter) getGradientPattern(g Gradient, m Matrix, bounds Rect, alpha bool) pdfName {
+	key := pdfGradient{g, m, Rect{}, alpha}
+	if g.SpreadMethod() != PadSpread {
+		key.bounds = bounds
+	}
+	ref, ok := w.pdf.gradients[key]
+	if !ok {
+		ref = w.pdf.writeObject(pdfDict{
+			"Type":        pdfName("Pattern"),
+			"PatternType": 2,
+			"Shading":     pdfGradientShading(g, bounds.Transform(m.Inv()), alpha),
+			"Matrix":      pdfArray{m[0][0], m[1][0], m[0][1], m[1][1], m[0][2], m[1][2]},
+		})
+		w.pdf.gradients[key] = ref
+	}
+
+	if _, ok := w.resources["Pattern"]; !ok {
+		w.resources["Pattern"] = pdfDict{}
+	}
+	for name, pref := range w.resources["Pattern"].(pdfDict) {
+		if ref == pref {
+			return name
+		}
+	}
+	name := pdfName(fmt.Sprintf("P%d", len(w.resources["Pattern"].(pdfDict))))
+	w.resources["Pattern"].(pdfDict)[name] = ref
+	return name
+}
+
+// pdfGradientShading returns the shading dictionary of a gradient, where bounds is the painted area in gradient coordinates. When alpha is true, the shading uses the DeviceGray color space for the alpha values of the color stops. Shading dictionaries have the same syntax in PostScript.
+func pdfGradientShading(g Gradient, bounds Rect, alpha bool) pdfDict {
+	stops := g.ColorStops()
+	if len(stops) == 0 {
+		stops = Stops{{0.0, Transparent}}
+	}
+	if 0.0 < stops[0].Offset {
+		stops = append(Stops{{0.0, stops[0].Color}}, stops...)
+	}
+	if stops[len(stops)-1].Offset < 1.0 {
+		stops = append(stops, Stop{1.0, stops[len(stops)-1].Color})
+	}
+
+	colorSpace := pdfName("DeviceRGB")
+	components := func(col color.RGBA) pdfArray {
+		R, G, B, A := unpremultiply(col)
+		if alpha {
+			return pdfArray{A}
+		}
+		return pdfArray{R, G, B}
+	}
+	if alpha {
+		colorSpace = pdfName("DeviceGray")
+	}
+
+	// interpolate between the color stops, skipping zero-width intervals for sharp transitions
+	functions := pdfArray{}
+	funcBounds := pdfArray{}
+	funcEncode := pdfArray{}
+	for i := 1; i < len(stops); i++ {
+		if stops[i].Offset == stops[i-1].Offset {
+			continue
+		}
+		if len(functions) != 0 {
+			funcBounds = append(funcBounds, stops[i-1].Offset)
+		}
+		functions = append(functions, pdfDict{
+			"FunctionType": 2,
+			"Domain":       pdfArray{0.0, 1.0},
+			"C0":           components(stops[i-1].Color),
+			"C1":           components(stops[i].Color),
+			"N":            1.0,
+		})
+		funcEncode = append(funcEncode, 0.0, 1.0)
+	}
+
+	var function interface{} = functions[0]
+	if 1 < len(functions) {
+		function = pdfDict{
+			"FunctionType": 3,
+			"Domain":       pdfArray{0.0, 1.0},
+			"Functions":    functions,
+			"Bounds":       funcBounds,
+			"Encode":       funcEncode,
+		}
+	}
+
+	tmin, tmax := 0.0, 1.0
+	if spread := g.SpreadMethod(); spread != PadSpread {
+		// repeat the color stops explicitly for each period that covers the bounds
+		tmin, tmax = gradientRange(g, bounds)
+		periods := pdfArray{}
+		periodBounds := pdfArray{}
+		periodEncode := pdfArray{}
+		for k := math.Floor(tmin); k < tmax; k++ {
+			if len(periods) != 0 {
+				periodBounds = append(periodBounds, k)
+			}
+			t0, t1 := math.Max(k, tmin)-k, math.Min(k+1.0, tmax)-k
+			if spread == ReflectSpread && int(k)%2 != 0 {
+				t0, t1 = 1.0-t0, 1.0-t1
+			}
+			periods = append(periods, function)
+			periodEncode = append(periodEncode, t0, t1)
+		}
+		function = pdfDict{
+			"FunctionType": 3,
+			"Domain":       pdfArray{tmin, tmax},
+			"Functions":    periods,
+			"Bounds":       periodBounds,
+			"Encode":       periodEncode,
+		}
+	}
+
+	shading := pdfDict{
+		"ColorSpace": colorSpace,
+		"Domain":     pdfArray{tmin, tmax},
+		"Function":   function,
+		"Extend":     pdfArray{true, true},
+	}
+	switch g := g.(type) {
+	case *LinearGradient:
+		d := g.End.Sub(g.Start)
+		p0, p1 := g.Start.Add(d.Mul(tmin)), g.Start.Add(d.Mul(tmax))
+		shading["ShadingType"] = 2
+		shading["Coords"] = pdfArray{p0.X, p0.Y, p1.X, p1.Y}
+	case *RadialGradient:
+		d := g.C1.Sub(g.C0)
+		p0, p1 := g.C0.Add(d.Mul(tmin)), g.C0.Add(d.Mul(tmax))
+		r0, r1 := g.R0+tmin*(g.R1-g.R0), g.R0+tmax*(g.R1-g.R0)
+		shading["ShadingType"] = 3
+		shading["Coords"] = pdfArray{p0.X, p0.Y, math.Max(0.0, r0), p1.X, p1.Y, math.Max(0.0, r1)}
+	default:
+		panic("PDF: gradient not supported")
+	}
+	return shading
+}
+
 func (w *pdfPageWriter) SetLineWidth(lineWidth float64) {
 	if lineWidth != w.lineWidth {
 		fmt.Fprintf(w, " %v w", dec(lineWidth))
@@ -516,7 +1270,7 @@ func (w *pdfPageWriter) SetFont(font *Font, size float64) {
 		w.font = font
 		w.fontSize = size
 
-		ref := w.pdf.getFont(font)
+		ref := w.pdf.getFont(font).ref
 		if _, ok := w.resources["Font"]; !ok {
 			w.resources["Font"] = pdfDict{}
 		} else {
@@ -577,8 +1331,23 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 		} else {
 			fmt.Fprintf(w, " (")
 		}
-		indices := w.font.toIndices(s)
-		binary.Write(w, binary.BigEndian, indices)
+		subset := w.pdf.getFont(w.font).subset
+		runes := []rune(s)
+		for i, index := range w.font.toIndices(s) {
+			if index == 0 && w.pdf.pdfa {
+				w.pdf.pdfaError("font %s has no glyph for %q", w.font.name, runes[i])
+			}
+			index = subset.Get(index, originalText(runes[i]))
+			for _, c := range []byte{byte(index >> 8), byte(index)} {
+				if c == '(' || c == ')' || c == '\\' {
+					w.WriteByte('\\')
+				} else if c == '\r' {
+					w.WriteString("\\r")
+					continue
+				}
+				w.WriteByte(c)
+			}
+		}
 		fmt.Fprintf(w, ")")
 	}
 
@@ -622,36 +1391,229 @@ func (w *pdfPageWriter) DrawImage(img image.Image, enc ImageEncoding, m Matrix)
 func (w *pdfPageWriter) embedImage(img image.Image, enc ImageEncoding) pdfName {
 	size := img.Bounds().Size()
 	b := make([]byte, size.X*size.Y*3)
-	for y := 0; y < size.Y; y++ {
-		for x := 0; x < size.X; x++ {
-			i := (y*size.X + x) * 3
-			R, G, B, _ := img.At(x, y).RGBA()
-			b[i+0] = byte(R >> 8)
-			b[i+1] = byte(G >> 8)
-			b[i+2] = byte(B >> 8)
-			// TODO: (PDF) handle alpha channel for images
+	alpha := make([]byte, size.X*size.Y)
+	opaque := true
+	colorSpace := pdfName("DeviceRGB")
+	filter := pdfFilterFlate
+	if data, gray, ok := jpegData(img); ok {
+		// pass through JPEG data without re-encoding
+		b = data
+		if gray {
+			colorSpace = pdfName("DeviceGray")
+		}
+		filter = pdfFilterDCT
+	} else {
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				i := y*size.X + x
+				c := color.NRGBAModel.Convert(img.At(img.Bounds().Min.X+x, img.Bounds().Min.Y+y)).(color.NRGBA)
+				b[3*i+0] = c.R
+				b[3*i+1] = c.G
+				b[3*i+2] = c.B
+				alpha[i] = c.A
+				if c.A != 255 {
+					opaque = false
+				}
+			}
+		}
+
+		// TODO: (PDF) implement JPXFilter for lossy image compression
+		if enc == Lossy {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+				if w.pdf.err == nil {
+					w.pdf.err = fmt.Errorf("encoding image: %w", err)
+				}
+			}
+			if _, ok := img.(*image.Gray); ok {
+				colorSpace = pdfName("DeviceGray")
+			}
+			b = buf.Bytes()
+			filter = pdfFilterDCT
 		}
 	}
 
-	// TODO: (PDF) implement JPXFilter for lossy image compression
-	ref := w.pdf.writeObject(pdfStream{
-		dict: pdfDict{
+	// identical images are embedded only once
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d %d %v\n", enc, size.X, size.Y, opaque)
+	h.Write(b)
+	if !opaque {
+		h.Write(alpha)
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+
+	ref, ok := w.pdf.images[key]
+	if !ok {
+		dict := pdfDict{
 			"Type":             pdfName("XObject"),
 			"Subtype":          pdfName("Image"),
 			"Width":            size.X,
 			"Height":           size.Y,
-			"ColorSpace":       pdfName("DeviceRGB"),
+			"ColorSpace":       colorSpace,
 			"BitsPerComponent": 8,
-			"Interpolate":      true,
-			"Filter":           pdfFilterFlate,
+			"Interpolate":      !w.pdf.pdfa, // interpolation is not allowed in PDF/A
+			"Filter":           filter,
+		}
+		if !opaque {
+			dict["SMask"] = w.pdf.writeObject(pdfStream{
+				dict: pdfDict{
+					"Type":             pdfName("XObject"),
+					"Subtype":          pdfName("Image"),
+					"Width":            size.X,
+					"Height":           size.Y,
+					"ColorSpace":       pdfName("DeviceGray"),
+					"BitsPerComponent": 8,
+					"Interpolate":      !w.pdf.pdfa,
+					"Filter":           pdfFilterFlate,
+				},
+				stream: alpha,
+			})
+		}
+
+		ref = w.pdf.writeObject(pdfStream{
+			dict:   dict,
+			stream: b,
+		})
+		w.pdf.images[key] = ref
+	}
+
+	if _, ok := w.resources["XObject"]; !ok {
+		w.resources["XObject"] = pdfDict{}
+	}
+	for name, imageRef := range w.resources["XObject"].(pdfDict) {
+		if ref == imageRef {
+			return name
+		}
+	}
+	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
+	w.resources["XObject"].(pdfDict)[name] = ref
+	return name
+}
+
+// DrawSymbol draws the symbol transformed by m, writing the symbol as a form XObject when it is first used.
+func (w *pdfPageWriter) DrawSymbol(symbol *Symbol, m Matrix) {
+	form, ok := w.pdf.symbols[symbol]
+	if !ok {
+		form = w.pdf.writeForm(symbol.layers, symbol.Bounds(), Identity, false)
+		w.pdf.symbols[symbol] = form
+	}
+	w.addFormLinks(form, m)
+	name := w.getXObject(form.ref, "Sy")
+	fmt.Fprintf(w, " q %v %v %v %v %v %v cm /%v Do Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
+}
+
+// DrawGroup draws the layers as a transparency group that is composited as a whole with the given opacity and blend mode.
+func (w *pdfPageWriter) DrawGroup(layers []layer, bounds Rect, opacity float64, blendMode BlendMode) {
+	form := w.pdf.writeForm(layers, bounds, Identity, true)
+	w.addFormLinks(form, Identity)
+	name := w.getXObject(form.ref, "Gr")
+	fmt.Fprintf(w, " q /%v gs /%v Do Q", w.getGroupGS(opacity, blendMode), name)
+}
+
+// addFormLinks adds the links of the form to the page, with m mapping form coordinates to page coordinates.
+func (w *pdfPageWriter) addFormLinks(form pdfForm, m Matrix) {
+	for _, link := range form.links {
+		w.AddLink(link.area.Transform(m), link.link)
+	}
+}
+
+// writeForm writes the layers as a form XObject with the given bounding box and with m mapping form coordinates to the coordinates where it is used, which is a transparency group if group is set.
+func (w *pdfWriter) writeForm(layers []layer, bounds Rect, m Matrix, group bool) pdfForm {
+	resources, b, links := w.formContents(layers, bounds.W, bounds.H)
+	stream := pdfStream{
+		dict: pdfDict{
+			"Type":      pdfName("XObject"),
+			"Subtype":   pdfName("Form"),
+			"BBox":      pdfArray{bounds.X, bounds.Y, bounds.X + bounds.W, bounds.Y + bounds.H},
+			"Resources": resources,
 		},
 		stream: b,
-	})
+	}
+	if m != Identity {
+		stream.dict["Matrix"] = pdfArray{m[0][0], m[1][0], m[0][1], m[1][1], m[0][2], m[1][2]}
+	}
+	if group {
+		stream.dict["Group"] = pdfDict{
+			"Type": pdfName("Group"),
+			"S":    pdfName("Transparency"),
+			"I":    true,
+			"CS":   pdfName("DeviceRGB"),
+		}
+	}
+	if pdfCompress {
+		stream.dict["Filter"] = pdfFilterFlate
+	}
+	return pdfForm{w.writeObject(stream), links}
+}
+
+// formContents writes the layers as the content stream of a form XObject or tiling pattern, and returns its resources, content stream and the links of its contents.
+func (w *pdfWriter) formContents(layers []layer, width, height float64) (pdfDict, []byte, []pdfLink) {
+	form := w.newPageWriter(width, height)
+
+	// the graphics state is inherited from where the form is drawn and thus unknown, invalid values make sure that every state is set when used
+	unknown := color.RGBA{255, 255, 255, 0}
+	form.pdfState = pdfState{
+		alpha:        -1.0,
+		fillColor:    unknown,
+		strokeColor:  unknown,
+		lineWidth:    -1.0,
+		lineCap:      -1,
+		lineJoin:     -1,
+		miterLimit:   -1.0,
+		textPosition: Identity,
+	}
+	writePDFLayers(form, layers)
+
+	b := form.Bytes()
+	if 0 < len(b) && b[0] == ' ' {
+		b = b[1:]
+	}
+	return form.resources, b, form.links
+}
+
+// SetMask sets the soft mask of the graphics state, with m mapping mask coordinates to user coordinates. Use Push and Pop to restore the previous mask.
+func (w *pdfPageWriter) SetMask(mask *Mask, m Matrix) {
+	key := clipPath{mask: mask, m: m}
+	ref, ok := w.pdf.masks[key]
+	if !ok {
+		ref = w.pdf.writeForm(mask.layers, mask.Bounds(), m, true).ref
+		w.pdf.masks[key] = ref
+	}
 
+	name, ok := w.maskStates[ref]
+	if !ok {
+		if _, ok := w.resources["ExtGState"]; !ok {
+			w.resources["ExtGState"] = pdfDict{}
+		}
+		subtype := pdfName("Alpha")
+		if mask.Type == LuminosityMask {
+			subtype = pdfName("Luminosity")
+		}
+		name = pdfName(fmt.Sprintf("M%d", len(w.maskStates)))
+		w.maskStates[ref] = name
+		w.resources["ExtGState"].(pdfDict)[name] = pdfDict{
+			"SMask": pdfDict{
+				"Type": pdfName("Mask"),
+				"S":    subtype,
+				"G":    ref,
+			},
+		}
+	}
+	fmt.Fprintf(w, " /%v gs", name)
+}
+
+// getXObject returns the resource name of the XObject, adding it to the resources of the page with a name of the given prefix if needed.
+func (w *pdfPageWriter) getXObject(ref pdfRef, prefix string) pdfName {
 	if _, ok := w.resources["XObject"]; !ok {
 		w.resources["XObject"] = pdfDict{}
 	}
-	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
+	for name, xref := range w.resources["XObject"].(pdfDict) {
+		if ref == xref {
+			return name
+		}
+	}
+	name := pdfName(fmt.Sprintf("%s%d", prefix, len(w.resources["XObject"].(pdfDict))))
 	w.resources["XObject"].(pdfDict)[name] = ref
 	return name
 }
@@ -672,3 +1634,22 @@ func (w *pdfPageWriter) getOpacityGS(a float64) pdfName {
 	}
 	return name
 }
+
+func (w *pdfPageWriter) getGroupGS(opacity float64, blendMode BlendMode) pdfName {
+	key := pdfGroupState{opacity, blendMode}
+	if name, ok := w.groupStates[key]; ok {
+		return name
+	}
+	name := pdfName(fmt.Sprintf("B%d", len(w.groupStates)))
+	w.groupStates[key] = name
+
+	if _, ok := w.resources["ExtGState"]; !ok {
+		w.resources["ExtGState"] = pdfDict{}
+	}
+	w.resources["ExtGState"].(pdfDict)[name] = pdfDict{
+		"CA": opacity,
+		"ca": opacity,
+		"BM": pdfName(blendMode.String()),
+	}
+	return name
+}
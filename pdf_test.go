@@ -2,8 +2,14 @@ package canvas
 
 import (
 	"bytes"
+	"encoding/base64"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tdewolff/test"
 )
@@ -22,7 +28,7 @@ func TestPDF(t *testing.T) {
 endstream
 endobj
 2 0 obj
-<< /Type /Page /Contents 1 0 R /Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >> /MediaBox [0 0 10 10] /Parent 2 0 R /Resources << >> >>
+<< /Type /Page /Contents 1 0 R /Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >> /MediaBox [0 0 10 10] /Parent 3 0 R /Resources << >> >>
 endobj
 3 0 obj
 << /Type /Pages /Count 1 /Kids [2 0 R] >>
@@ -38,7 +44,7 @@ xref
 0000000241 00000 n
 0000000298 00000 n
 trailer
-<< /Root 4 0 R /Size 4 >>
+<< /Root 4 0 R /Size 5 >>
 starxref
 347
 %%EOF`)
@@ -46,7 +52,7 @@ starxref
 
 func TestPDFPath(t *testing.T) {
 	buf := &bytes.Buffer{}
-	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf := newPDFWriter(buf, false).NewPage(210.0, 297.0)
 	pdf.SetAlpha(0.5)
 	pdf.SetFillColor(Red)
 	pdf.SetStrokeColor(Blue)
@@ -77,16 +83,190 @@ func TestPDFText(t *testing.T) {
 	text := rt.ToText(dejaVu12.TextWidth("glyphspacing")+float64(len("glyphspacing")-1), 100.0, Justify, Top, 0.0, 0.0)
 
 	buf := &bytes.Buffer{}
-	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf := newPDFWriter(buf, false).NewPage(210.0, 297.0)
 	text.WritePDF(pdf, Identity) // this actually gives coverage to PDF font embedding, which we don't test...
-	test.String(t, pdf.String(), " BT /F0 8 Tf 0 -7.421875 Td[(\x00G\x00H\x00M\x00D\x009) 63 (\x00X\x00\x1B)]TJ 1 0 0 rg 1 0 .3 1 0 -20.453125 Tm 1 Tc[(\x00J\x00O\x00\\\x00S\x00K\x00V\x00S\x00D\x00F\x00L\x00Q\x00J)]TJ 0 g 1 0 0 1 0 -29.765625 Tm 0 Tc 2 Tr .27984 w[(\x00G\x00H\x00M\x00D\x009) 63 (\x00X\x00\x14\x00\x15\x00V\x00X\x00E)]TJ /F1 10 Tf 0 -8.734375 Td .4 w[(\x00H\x00B\x00S\x00B\x00N\x00P\x00O\x00E\x00\x12\x00\x11)]TJ ET 1 0 0 rg 0 -22.703125 m 91.71875 -22.703125 l 91.71875 -21.803125 l 0 -21.803125 l 0 -22.703125 l f")
+	test.String(t, pdf.String(), " BT /F0 8 Tf 0 -7.421875 Td[(\x00\x01\x00\x02\x00\x03\x00\x04\x00\x05) 63 (\x00\x06\x00\a)]TJ 1 0 0 rg 1 0 .3 1 0 -20.453125 Tm 1 Tc[(\x00\b\x00\t\x00\n\x00\v\x00\f\x00\\r\x00\v\x00\x04\x00\x0e\x00\x0f\x00\x10\x00\b)]TJ 0 g 1 0 0 1 0 -29.765625 Tm 0 Tc 2 Tr .27984 w[(\x00\x01\x00\x02\x00\x03\x00\x04\x00\x05) 63 (\x00\x06\x00\x11\x00\x12\x00\\r\x00\x06\x00\x13)]TJ /F1 10 Tf 0 -8.734375 Td .4 w[(\x00\x01\x00\x02\x00\x03\x00\x02\x00\x04\x00\x05\x00\x06\x00\a\x00\b\x00\t)]TJ ET 1 0 0 rg 0 -22.703125 m 91.71875 -22.703125 l 91.71875 -21.803125 l 0 -21.803125 l 0 -22.703125 l f")
 }
 
 func TestPDFImage(t *testing.T) {
 	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
 
 	buf := &bytes.Buffer{}
-	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf := newPDFWriter(buf, false).NewPage(210.0, 297.0)
 	pdf.DrawImage(img, Lossless, Identity)
 	test.String(t, pdf.String(), " q 2 0 0 2 0 0 cm /Im0 Do Q")
 }
+
+func TestPDFImageAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 128})
+	opaque := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(Red), image.Point{}, draw.Src)
+
+	doc := NewDocument()
+	for i := 0; i < 2; i++ {
+		c := doc.NewPage(10, 10)
+		c.DrawImage(0.0, 0.0, img, Lossless, 1.0)
+		c.DrawImage(5.0, 0.0, img, Lossless, 1.0)
+		c.DrawImage(0.0, 5.0, opaque, Lossless, 1.0)
+	}
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, doc.WritePDF(buf))
+	s := buf.String()
+	test.T(t, strings.Count(s, "/Subtype /Image"), 3) // image with soft mask and opaque image
+	test.T(t, strings.Count(s, "/SMask"), 1)
+	test.T(t, strings.Count(s, "/ColorSpace /DeviceGray"), 1)
+	test.T(t, strings.Count(s, "/XObject << /Im0 2 0 R /Im1 3 0 R >>"), 2)
+	test.T(t, strings.Count(s, "/Im0 Do"), 4)
+}
+
+func TestPDFImageJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(img, img.Bounds(), image.NewUniform(Red), image.Point{}, draw.Src)
+
+	jpg := &bytes.Buffer{}
+	test.Error(t, jpeg.Encode(jpg, img, nil))
+	jpgImg, err := NewJPEGImage(bytes.NewReader(jpg.Bytes()))
+	test.Error(t, err)
+
+	c := New(10, 10)
+	c.DrawImage(0.0, 0.0, img, Lossy, 1.0)
+	c.DrawImage(0.0, 0.0, jpgImg, Lossless, 1.0)
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WritePDF(buf))
+	test.T(t, strings.Count(buf.String(), "/Filter /DCTDecode"), 2)
+	test.That(t, bytes.Contains(buf.Bytes(), jpg.Bytes()), "JPEG data passed through")
+
+	buf.Reset()
+	c.WriteSVG(buf)
+	test.That(t, strings.Contains(buf.String(), "data:image/jpeg;base64,"+base64.StdEncoding.EncodeToString(jpg.Bytes())+`"`), "JPEG data passed through")
+}
+
+func TestPDFToUnicode(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("./test/DejaVuSerif.ttf", FontRegular)
+	dejaVuSerif.Use(CommonLigatures)
+	face := dejaVuSerif.Face(12.0, Black, FontRegular, FontNormal)
+
+	c := New(100, 20)
+	c.DrawText(0, 10, NewTextLine(face, "final", Left))
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WritePDF(buf))
+	test.That(t, strings.Contains(buf.String(), "/ToUnicode 3 0 R"), "font refers to CMap")
+	test.That(t, strings.Contains(buf.String(), "4 beginbfchar\n<0001> <00660069>\n<0002> <006E>\n<0003> <0061>\n<0004> <006C>\nendbfchar"), "ligature maps to two characters")
+}
+
+func TestPDFLink(t *testing.T) {
+	doc := NewDocument()
+	c := doc.NewPage(10, 10)
+	c.DrawLinkRect(Rect{1, 2, 3, 4}, Link{URI: "https://example.com/(a)"})
+	c = doc.NewPage(10, 10)
+	c.DrawLink(5, 5, MustParseSVG("M0 -2L2 0L0 2L-2 0z"), Link{Page: 0, X: 1, Y: 9})
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, doc.WritePDF(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "/Annots [<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com/\\(a\\)) >> /Border [0 0 0] /F 4 /Rect [1 2 4 6] >>]"), "URI link")
+	test.That(t, strings.Contains(s, "/Annots [<< /Type /Annot /Subtype /Link /Border [0 0 0] /Dest [2 0 R /XYZ 1 9 0] /F 4 /QuadPoints [5 3 7 5 5 7 3 5] /Rect [3 3 7 7] >>]"), "internal link")
+
+	c.DrawLinkRect(Rect{0, 0, 1, 1}, Link{Page: 2})
+	test.That(t, doc.WritePDF(&bytes.Buffer{}) != nil, "link to page out of range")
+}
+
+func TestPDFMetadata(t *testing.T) {
+	doc := NewDocument()
+	doc.NewPage(10, 10)
+	doc.NewPage(10, 10)
+	doc.NewPage(10, 10)
+	doc.Metadata = Metadata{
+		Title:        "Report (draft)",
+		Author:       "Zoë",
+		Keywords:     "a & b",
+		CreationDate: time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", -90*60)),
+	}
+	chapter := doc.AddOutline("Chapter", 1, 0, 10)
+	chapter.Add("Section", 2, 0, 5)
+	doc.AddOutline("Appendix", 2, 0, 10)
+	doc.AddPageLabels(1, DecimalLabels, "", 0)
+	doc.AddPageLabels(2, NoLabelNumbers, "A-", 0)
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, doc.WritePDF(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "<< /Author (\xfe\xff\x00Z\x00o\x00\xeb) /CreationDate (D:20200102030405-01'30') /Keywords (a & b) /Title (Report \\(draft\\)) >>"), "info dictionary")
+	test.That(t, strings.Contains(s, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">Report (draft)</rdf:li></rdf:Alt></dc:title><dc:creator><rdf:Seq><rdf:li>Zoë</rdf:li></rdf:Seq></dc:creator><pdf:Keywords>a &amp; b</pdf:Keywords><xmp:CreateDate>2020-01-02T03:04:05-01:30</xmp:CreateDate>"), "XMP metadata")
+	test.That(t, strings.Contains(s, "8 0 obj\n<< /Type /Outlines /Count 3 /First 9 0 R /Last 10 0 R >>"), "outline root")
+	test.That(t, strings.Contains(s, "11 0 obj\n<< /Dest [6 0 R /XYZ 0 5 0] /Parent 9 0 R /Title (Section) >>"), "nested outline item")
+	test.That(t, strings.Contains(s, "9 0 obj\n<< /Count 1 /Dest [4 0 R /XYZ 0 10 0] /First 11 0 R /Last 11 0 R /Next 10 0 R /Parent 8 0 R /Title (Chapter) >>"), "outline item")
+	test.That(t, strings.Contains(s, "/PageLabels << /Nums [0 << /S /D >> 1 << /S /D >> 2 << /P (A-) >>] >>"), "page labels")
+	test.That(t, strings.Contains(s, "/Info 13 0 R"), "info in trailer")
+}
+
+func TestPDFA(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("./test/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(12.0, Black, FontRegular, FontNormal)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 128})
+
+	c := New(100, 20)
+	c.DrawText(0, 10, NewTextLine(face, "text", Left))
+	c.DrawImage(0, 0, img, Lossless, 1.0)
+
+	doc := NewDocument()
+	doc.PDFA = true
+	doc.AddPage(c)
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, doc.WritePDF(buf))
+	s := buf.String()
+	test.That(t, strings.HasPrefix(s, "%PDF-1.7\n%\xE2\xE3\xCF\xD3\n"), "binary comment")
+	test.That(t, strings.Contains(s, "/FontFile2 "), "TrueType font file")
+	test.That(t, strings.Contains(s, "/Interpolate false"), "no interpolation")
+	test.That(t, strings.Contains(s, "/OutputIntents [<< /Type /OutputIntent /DestOutputProfile "), "output intent")
+	test.That(t, strings.Contains(s, "<pdfaid:part>2</pdfaid:part><pdfaid:conformance>B</pdfaid:conformance>"), "XMP metadata")
+	test.That(t, strings.Contains(s, "/Metadata "), "XMP metadata in catalog")
+	test.That(t, strings.Contains(s, "/ID [<"), "document ID")
+
+	// PDF/A is set per document or per call
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	test.That(t, !strings.Contains(buf.String(), "/OutputIntents"), "no output intent")
+
+	// transparency is allowed in PDF/A-2
+	c.BeginGroup(0.5, MultiplyBlend)
+	c.SetFillColor(color.RGBA{0, 0, 128, 128})
+	c.DrawPath(0, 0, Rectangle(10, 10))
+	c.EndGroup()
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf, PDFOptions{PDFA: true}))
+	s = buf.String()
+	test.That(t, strings.Contains(s, "/OutputIntents"), "output intent")
+	test.That(t, strings.Contains(s, "/BM /Multiply"), "blend mode")
+	test.That(t, strings.Contains(s, "/Group << /Type /Group /CS /DeviceRGB /I true /S /Transparency >>"), "transparency group in the color space of the output intent")
+	pdfCompress = true
+
+	doc = NewDocument()
+	doc.PDFA = true
+	c = doc.NewPage(100, 20)
+	c.DrawText(0, 10, NewTextLine(face, "ࠀ", Left))
+	test.T(t, doc.WritePDF(&bytes.Buffer{}).Error(), `PDF/A: font dejavu-serif has no glyph for 'ࠀ'`)
+	test.T(t, c.WritePDF(&bytes.Buffer{}, PDFOptions{PDFA: true}).Error(), `PDF/A: font dejavu-serif has no glyph for 'ࠀ'`)
+	test.Error(t, c.WritePDF(&bytes.Buffer{}))
+}
+
+func TestSRGBProfile(t *testing.T) {
+	b := sRGBProfile()
+	test.T(t, int(b[0])<<24|int(b[1])<<16|int(b[2])<<8|int(b[3]), len(b))
+	test.T(t, string(b[36:40]), "acsp")
+	test.T(t, len(b)%4, 0)
+}
@@ -1,5 +1,7 @@
 package canvas
 
+import "math"
+
 // Polyline defines a list of points in 2D space that form a polyline. If the last coordinate equals the first coordinate, we assume the polyline to close itself.
 type Polyline struct {
 	coords []Point
@@ -194,3 +196,140 @@ func (p *Polyline) Smoothen() *Path {
 	}
 	return q
 }
+
+// FitCurves returns a path of cubic Béziers that approximates the polyline, where the distance between the points of the polyline and the path is at most tolerance. It uses as few Béziers as it can find, and the Béziers join smoothly except at the points where the fit is split. If the polyline is closed, the path is closed too. See Philip J. Schneider, "An Algorithm for Automatically Fitting Digitized Curves", Graphics Gems, 1990.
+func (p *Polyline) FitCurves(tolerance float64) *Path {
+	// remove consecutive duplicate points
+	K := []Point{}
+	for _, coord := range p.coords {
+		if len(K) == 0 || !coord.Equals(K[len(K)-1]) {
+			K = append(K, coord)
+		}
+	}
+
+	q := &Path{}
+	if len(K) < 2 {
+		return q
+	}
+	q.MoveTo(K[0].X, K[0].Y)
+	if len(K) == 2 {
+		q.LineTo(K[1].X, K[1].Y)
+		return q
+	}
+
+	tangent0 := K[1].Sub(K[0]).Norm(1.0)
+	tangent1 := K[len(K)-2].Sub(K[len(K)-1]).Norm(1.0)
+	closed := 3 < len(K) && K[0].Equals(K[len(K)-1])
+	if closed {
+		tangent0 = K[1].Sub(K[len(K)-2]).Norm(1.0)
+		tangent1 = tangent0.Neg()
+	}
+	fitCubicBeziers(q, K, tangent0, tangent1, tolerance)
+	if closed {
+		q.Close()
+	}
+	return q
+}
+
+// fitCubicBeziers appends cubic Béziers to p that approximate the points within tolerance, with tangent0 the direction at the first point and tangent1 the direction at the last point pointing backwards.
+func fitCubicBeziers(p *Path, K []Point, tangent0, tangent1 Point, tolerance float64) {
+	if len(K) == 2 {
+		dist := K[1].Sub(K[0]).Length() / 3.0
+		cp1 := K[0].Add(tangent0.Mul(dist))
+		cp2 := K[1].Add(tangent1.Mul(dist))
+		p.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, K[1].X, K[1].Y)
+		return
+	}
+
+	// parametrize by chord length
+	ts := make([]float64, len(K))
+	for i := 1; i < len(K); i++ {
+		ts[i] = ts[i-1] + K[i].Sub(K[i-1]).Length()
+	}
+	for i := range ts {
+		ts[i] /= ts[len(ts)-1]
+	}
+
+	cp1, cp2 := fitCubicBezier(K, ts, tangent0, tangent1)
+	dmax, split := fitCubicBezierError(K, ts, cp1, cp2)
+	if tolerance < dmax && dmax < 4.0*tolerance {
+		// improve the parametrization using Newton's method
+		for iteration := 0; iteration < 4; iteration++ {
+			for i := range ts {
+				ts[i] = fitCubicBezierReparametrize(K[0], cp1, cp2, K[len(K)-1], K[i], ts[i])
+			}
+			cp1, cp2 = fitCubicBezier(K, ts, tangent0, tangent1)
+			if dmax, split = fitCubicBezierError(K, ts, cp1, cp2); dmax <= tolerance {
+				break
+			}
+		}
+	}
+	if dmax <= tolerance {
+		p.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, K[len(K)-1].X, K[len(K)-1].Y)
+		return
+	}
+
+	// split at the point of maximum error and fit both halves
+	tangent := K[split-1].Sub(K[split+1]).Norm(1.0)
+	if tangent.IsZero() {
+		tangent = K[split-1].Sub(K[split]).Rot90CW().Norm(1.0)
+	}
+	fitCubicBeziers(p, K[:split+1], tangent0, tangent, tolerance)
+	fitCubicBeziers(p, K[split:], tangent.Neg(), tangent1, tolerance)
+}
+
+// fitCubicBezier returns the control points of the cubic Bézier between the first and last points that best fits the points at the given curve parameters in the least squares sense, with the control points along the given tangents.
+func fitCubicBezier(K []Point, ts []float64, tangent0, tangent1 Point) (Point, Point) {
+	p0, p3 := K[0], K[len(K)-1]
+	var c00, c01, c11, x0, x1 float64
+	for i, t := range ts {
+		b0 := (1.0 - t) * (1.0 - t) * (1.0 - t)
+		b1 := 3.0 * t * (1.0 - t) * (1.0 - t)
+		b2 := 3.0 * t * t * (1.0 - t)
+		b3 := t * t * t
+		a0 := tangent0.Mul(b1)
+		a1 := tangent1.Mul(b2)
+		c00 += a0.Dot(a0)
+		c01 += a0.Dot(a1)
+		c11 += a1.Dot(a1)
+		tmp := K[i].Sub(p0.Mul(b0 + b1)).Sub(p3.Mul(b2 + b3))
+		x0 += a0.Dot(tmp)
+		x1 += a1.Dot(tmp)
+	}
+
+	alpha0, alpha1 := 0.0, 0.0
+	if det := c00*c11 - c01*c01; !equal(det, 0.0) {
+		alpha0 = (x0*c11 - x1*c01) / det
+		alpha1 = (c00*x1 - c01*x0) / det
+	}
+
+	// fall back to a heuristic when the solution is degenerate
+	dist := p3.Sub(p0).Length()
+	if alpha0 < Epsilon*dist || alpha1 < Epsilon*dist {
+		alpha0, alpha1 = dist/3.0, dist/3.0
+	}
+	return p0.Add(tangent0.Mul(alpha0)), p3.Add(tangent1.Mul(alpha1))
+}
+
+// fitCubicBezierError returns the maximum distance between the points and the Bézier at their curve parameters, and the index of the point where it occurs.
+func fitCubicBezierError(K []Point, ts []float64, cp1, cp2 Point) (float64, int) {
+	dmax, split := 0.0, len(K)/2
+	for i := 1; i < len(K)-1; i++ {
+		if d := cubicBezierPos(K[0], cp1, cp2, K[len(K)-1], ts[i]).Sub(K[i]).Length(); dmax < d {
+			dmax, split = d, i
+		}
+	}
+	return dmax, split
+}
+
+// fitCubicBezierReparametrize returns a curve parameter closer to the point on the Bézier nearest to q, using one step of Newton's method.
+func fitCubicBezierReparametrize(p0, p1, p2, p3, q Point, t float64) float64 {
+	d := cubicBezierPos(p0, p1, p2, p3, t).Sub(q)
+	d1 := cubicBezierDeriv(p0, p1, p2, p3, t)
+	d2 := cubicBezierDeriv2(p0, p1, p2, p3, t)
+	denom := d1.Dot(d1) + d.Dot(d2)
+	if equal(denom, 0.0) {
+		return t
+	}
+	return math.Max(0.0, math.Min(1.0, t-d.Dot(d1)/denom))
+}
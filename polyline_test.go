@@ -1,6 +1,8 @@
 package canvas
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -34,3 +36,39 @@ func TestPolylineSmoothen(t *testing.T) {
 	test.T(t, (&Polyline{}).Add(0, 0).Add(5, 10).Add(10, 0).Add(5, -10).Smoothen(), MustParseSVG("M0 0C1.4444 5.1111 2.8889 10.222 5 10C7.1111 9.7778 9.8889 4.2222 10 0C10.111 -4.2222 7.5556 -7.1111 5 -10"))
 	test.T(t, (&Polyline{}).Add(0, 0).Add(5, 10).Add(10, 0).Add(5, -10).Add(0, 0).Smoothen(), MustParseSVG("M0 0C0 5 2.5 10 5 10C7.5 10 10 5 10 0C10 -5 7.5 -10 5 -10C2.5 -10 0 -5 0 0z"))
 }
+
+func TestPolylineFitCurves(t *testing.T) {
+	test.T(t, (&Polyline{}).FitCurves(0.1), MustParseSVG(""))
+	test.T(t, (&Polyline{}).Add(0, 0).Add(0, 0).FitCurves(0.1), MustParseSVG(""))
+	test.T(t, (&Polyline{}).Add(0, 0).Add(10, 0).FitCurves(0.1), MustParseSVG("M0 0L10 0"))
+	test.T(t, (&Polyline{}).Add(0, 0).Add(5, 5).Add(10, 0).FitCurves(0.1), (&Path{}).MoveTo(0.0, 0.0).CubeTo(20.0/3.0, 20.0/3.0, 10.0/3.0, 20.0/3.0, 10.0, 0.0))
+
+	// the distance between the points and the fitted curve is bounded
+	p := &Polyline{}
+	for i := 0; i <= 100; i++ {
+		x := float64(i) / 10.0
+		p.Add(x, math.Sin(x))
+	}
+	q := p.FitCurves(0.01)
+	test.That(t, strings.Count(q.String(), "C") < 10, "few Béziers")
+	for _, coord := range p.Coords() {
+		if d := q.Distance(coord); 0.01 < d {
+			test.Fail(t, coord, "at distance", d)
+		}
+	}
+
+	// closed polylines give closed paths
+	p = &Polyline{}
+	for i := 0; i < 40; i++ {
+		theta := float64(i) / 40.0 * 2.0 * math.Pi
+		p.Add(10.0*math.Cos(theta), 10.0*math.Sin(theta))
+	}
+	p.Add(10.0, 0.0)
+	q = p.FitCurves(0.01)
+	test.That(t, q.Closed(), "closed path")
+	for _, coord := range p.Coords() {
+		if d := q.Distance(coord); 0.01 < d {
+			test.Fail(t, coord, "at distance", d)
+		}
+	}
+}
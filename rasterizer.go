@@ -0,0 +1,265 @@
+package canvas
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// AntiAliasing is the anti-aliasing quality of the rasterizer used by WriteImage, ie. the number of scanlines per pixel that are sampled vertically. The coverage along each scanline is calculated exactly. Higher values give smoother edges but are slower.
+var AntiAliasing = 8
+
+type rasterEdge struct {
+	x0, y0, x1, y1 float64 // y0 < y1
+	dir            int     // +1 when the edge runs downwards, -1 when upwards
+}
+
+type rasterCrossing struct {
+	x   float64
+	dir int
+}
+
+// Rasterizer converts paths into an anti-aliased coverage mask, supporting both the NonZero and EvenOdd fill rules. Lines, Béziers and arcs are added in pixel coordinates with the origin in the top-left corner, and are flattened at a fraction of the scanline distance. Subpaths are closed implicitly.
+type Rasterizer struct {
+	w, h      int
+	quality   int
+	tolerance float64
+	edges     []rasterEdge
+
+	start, pos Point
+	open       bool
+}
+
+// NewRasterizer returns a rasterizer for an image of width w and height h in pixels, with quality the number of scanlines per pixel.
+func NewRasterizer(w, h, quality int) *Rasterizer {
+	if quality < 1 {
+		quality = 1
+	}
+	return &Rasterizer{
+		w:         w,
+		h:         h,
+		quality:   quality,
+		tolerance: 0.25 / float64(quality),
+	}
+}
+
+// Bounds returns the bounds of the coverage mask.
+func (r *Rasterizer) Bounds() image.Rectangle {
+	return image.Rect(0, 0, r.w, r.h)
+}
+
+func (r *Rasterizer) addEdge(a, b Point) {
+	if a.Y == b.Y {
+		return // horizontal edges never cross a scanline
+	} else if a.Y < b.Y {
+		r.edges = append(r.edges, rasterEdge{a.X, a.Y, b.X, b.Y, 1})
+	} else {
+		r.edges = append(r.edges, rasterEdge{b.X, b.Y, a.X, a.Y, -1})
+	}
+}
+
+// MoveTo starts a new subpath at (x,y), closing the previous subpath.
+func (r *Rasterizer) MoveTo(x, y float64) {
+	r.Close()
+	r.start = Point{x, y}
+	r.pos = r.start
+}
+
+// LineTo adds a line to (x,y).
+func (r *Rasterizer) LineTo(x, y float64) {
+	p := Point{x, y}
+	r.addEdge(r.pos, p)
+	r.pos = p
+	r.open = true
+}
+
+// QuadTo adds a quadratic Bézier with control point (cpx,cpy) and end point (x,y).
+func (r *Rasterizer) QuadTo(cpx, cpy, x, y float64) {
+	p0, p1, p2 := r.pos, Point{cpx, cpy}, Point{x, y}
+
+	// Wang's formula gives the number of segments to stay within the tolerance
+	dd := p0.Sub(p1.Mul(2.0)).Add(p2).Length()
+	n := int(math.Ceil(math.Sqrt(0.25 * dd / r.tolerance)))
+	for i := 1; i < n; i++ {
+		p := quadraticBezierPos(p0, p1, p2, float64(i)/float64(n))
+		r.LineTo(p.X, p.Y)
+	}
+	r.LineTo(x, y)
+}
+
+// CubeTo adds a cubic Bézier with control points (cpx1,cpy1) and (cpx2,cpy2) and end point (x,y).
+func (r *Rasterizer) CubeTo(cpx1, cpy1, cpx2, cpy2, x, y float64) {
+	p0, p1, p2, p3 := r.pos, Point{cpx1, cpy1}, Point{cpx2, cpy2}, Point{x, y}
+
+	// Wang's formula gives the number of segments to stay within the tolerance
+	dd := math.Max(p0.Sub(p1.Mul(2.0)).Add(p2).Length(), p1.Sub(p2.Mul(2.0)).Add(p3).Length())
+	n := int(math.Ceil(math.Sqrt(0.75 * dd / r.tolerance)))
+	for i := 1; i < n; i++ {
+		p := cubicBezierPos(p0, p1, p2, p3, float64(i)/float64(n))
+		r.LineTo(p.X, p.Y)
+	}
+	r.LineTo(x, y)
+}
+
+// ArcTo adds an elliptical arc with radii rx and ry, with rot the rotation in degrees, largeArc and sweep booleans, and end point (x,y). See Path.ArcTo for details.
+func (r *Rasterizer) ArcTo(rx, ry, rot float64, largeArc, sweep bool, x, y float64) {
+	start, end := r.pos, Point{x, y}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if start.Equals(end) {
+		return
+	} else if equal(rx, 0.0) || equal(ry, 0.0) {
+		r.LineTo(x, y)
+		return
+	}
+
+	phi := rot * math.Pi / 180.0
+	if lambda := ellipseRadiiCorrection(start, rx, ry, phi, end); lambda > 1.0 {
+		rx *= lambda
+		ry *= lambda
+	}
+	cx, cy, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, largeArc, sweep, end.X, end.Y)
+
+	// the angle step for which the chord stays within the tolerance of the largest radius
+	dtheta := math.Pi / 2.0
+	if radius := math.Max(rx, ry); r.tolerance < radius {
+		dtheta = math.Min(dtheta, 2.0*math.Acos(1.0-r.tolerance/radius))
+	}
+	n := int(math.Ceil(math.Abs(theta1-theta0) / dtheta))
+	for i := 1; i < n; i++ {
+		p := ellipsePos(rx, ry, phi, cx, cy, theta0+(theta1-theta0)*float64(i)/float64(n))
+		r.LineTo(p.X, p.Y)
+	}
+	r.LineTo(x, y)
+}
+
+// Close closes the current subpath.
+func (r *Rasterizer) Close() {
+	if r.open {
+		r.addEdge(r.pos, r.start)
+		r.pos = r.start
+		r.open = false
+	}
+}
+
+// AddPath adds the path transformed by m, which maps path coordinates to pixel coordinates.
+func (r *Rasterizer) AddPath(p *Path, m Matrix) {
+	p = p.Transform(m)
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		switch cmd {
+		case moveToCmd:
+			r.MoveTo(p.d[i+1], p.d[i+2])
+		case lineToCmd:
+			r.LineTo(p.d[i+1], p.d[i+2])
+		case quadToCmd:
+			r.QuadTo(p.d[i+1], p.d[i+2], p.d[i+3], p.d[i+4])
+		case cubeToCmd:
+			r.CubeTo(p.d[i+1], p.d[i+2], p.d[i+3], p.d[i+4], p.d[i+5], p.d[i+6])
+		case arcToCmd:
+			largeArc, sweep := fromArcFlags(p.d[i+4])
+			r.ArcTo(p.d[i+1], p.d[i+2], p.d[i+3]*180.0/math.Pi, largeArc, sweep, p.d[i+5], p.d[i+6])
+		case closeCmd:
+			r.Close()
+		}
+		i += cmdLen(cmd)
+	}
+	r.Close()
+}
+
+// Mask returns the coverage of the added paths, where the interior is determined by the fill rule.
+func (r *Rasterizer) Mask(fillRule FillRuleType) *image.Alpha {
+	r.Close()
+	mask := image.NewAlpha(r.Bounds())
+	if len(r.edges) == 0 || r.w <= 0 || r.h <= 0 {
+		return mask
+	}
+
+	sort.Slice(r.edges, func(i, j int) bool { return r.edges[i].y0 < r.edges[j].y0 })
+
+	coverage := make([]float64, r.w+1) // partial coverage of pixels
+	spans := make([]float64, r.w+1)    // differences of fully covered pixels
+	weight := 1.0 / float64(r.quality)
+	addSpan := func(x0, x1 float64) {
+		x0 = math.Max(0.0, math.Min(float64(r.w), x0))
+		x1 = math.Max(0.0, math.Min(float64(r.w), x1))
+		if x1 <= x0 {
+			return
+		}
+		i0, i1 := int(x0), int(x1)
+		if i0 == i1 {
+			coverage[i0] += (x1 - x0) * weight
+			return
+		}
+		coverage[i0] += (float64(i0+1) - x0) * weight
+		spans[i0+1] += weight
+		spans[i1] -= weight
+		coverage[i1] += (x1 - float64(i1)) * weight
+	}
+
+	active := []rasterEdge{}
+	crossings := []rasterCrossing{}
+	next := 0
+	for y := 0; y < r.h; y++ {
+		// update the active edges for this row of pixels
+		for next < len(r.edges) && r.edges[next].y0 < float64(y+1) {
+			active = append(active, r.edges[next])
+			next++
+		}
+		k := 0
+		for _, edge := range active {
+			if float64(y) < edge.y1 {
+				active[k] = edge
+				k++
+			}
+		}
+		active = active[:k]
+		if len(active) == 0 {
+			if next == len(r.edges) {
+				break
+			}
+			continue
+		}
+
+		for s := 0; s < r.quality; s++ {
+			ys := float64(y) + (float64(s)+0.5)*weight
+			crossings = crossings[:0]
+			for _, edge := range active {
+				if edge.y0 <= ys && ys < edge.y1 {
+					x := edge.x0 + (ys-edge.y0)*(edge.x1-edge.x0)/(edge.y1-edge.y0)
+					crossings = append(crossings, rasterCrossing{x, edge.dir})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i, crossing := range crossings {
+				winding += crossing.dir
+				if i+1 < len(crossings) {
+					if fillRule == EvenOdd && winding%2 != 0 || fillRule != EvenOdd && winding != 0 {
+						addSpan(crossing.x, crossings[i+1].x)
+					}
+				}
+			}
+		}
+
+		full := 0.0
+		row := mask.Pix[y*mask.Stride:]
+		for x := 0; x < r.w; x++ {
+			full += spans[x]
+			a := math.Min(1.0, math.Max(0.0, full+coverage[x]))
+			row[x] = uint8(a*255.0 + 0.5)
+			coverage[x], spans[x] = 0.0, 0.0
+		}
+		spans[r.w] = 0.0
+		coverage[r.w] = 0.0
+	}
+	return mask
+}
+
+// Draw composites src over dst within the coverage of the added paths, where the interior is determined by the fill rule. The origin of the rasterizer is aligned with the top-left corner of the bounds of dst, and src is sampled at the same points as dst.
+func (r *Rasterizer) Draw(dst draw.Image, src image.Image, fillRule FillRuleType) {
+	mask := r.Mask(fillRule)
+	origin := dst.Bounds().Min
+	draw.DrawMask(dst, mask.Bounds().Add(origin), src, origin, mask, image.Point{}, draw.Over)
+}
@@ -0,0 +1,48 @@
+package canvas
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestRasterizer(t *testing.T) {
+	ras := NewRasterizer(4, 4, 4)
+	ras.MoveTo(0.5, 1.0)
+	ras.LineTo(3.0, 1.0)
+	ras.LineTo(3.0, 3.0)
+	ras.LineTo(0.5, 3.0)
+	mask := ras.Mask(NonZero)
+	test.String(t, fmt.Sprint(mask.Pix), fmt.Sprint([]uint8{
+		0, 0, 0, 0,
+		128, 255, 255, 0,
+		128, 255, 255, 0,
+		0, 0, 0, 0,
+	}))
+}
+
+func TestRasterizerFillRule(t *testing.T) {
+	p := MustParseSVG("M0 0H10V10H0zM2 2H8V8H2z")
+	ras := NewRasterizer(10, 10, 4)
+	ras.AddPath(p, Identity)
+	test.T(t, ras.Mask(NonZero).AlphaAt(5, 5).A, uint8(255))
+	test.T(t, ras.Mask(EvenOdd).AlphaAt(5, 5).A, uint8(0))
+	test.T(t, ras.Mask(EvenOdd).AlphaAt(1, 5).A, uint8(255))
+}
+
+func TestRasterizerCurves(t *testing.T) {
+	area := func(p *Path) float64 {
+		ras := NewRasterizer(20, 20, 16)
+		ras.AddPath(p, Identity.Translate(10.0, 10.0))
+		sum := 0.0
+		for _, a := range ras.Mask(NonZero).Pix {
+			sum += float64(a) / 255.0
+		}
+		return sum
+	}
+	test.That(t, math.Abs(area(Circle(8.0))-64.0*math.Pi) < 1.0, "circle from arcs")
+	test.That(t, math.Abs(area(Circle(8.0).Replace(nil, nil, ellipseToBeziers))-64.0*math.Pi) < 1.0, "circle from Béziers")
+	test.That(t, math.Abs(area(MustParseSVG("M-8 0Q0 16 8 0z"))-8.0*16.0*2.0/3.0) < 1.0, "quadratic Bézier")
+}
@@ -0,0 +1,162 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+type svgWriter struct {
+	io.Writer
+	height   float64
+	ids      map[string]int
+	symbols  map[*Symbol]string
+	masks    map[clipPath]string
+	patterns map[svgPattern]string
+}
+
+// svgPattern is a pattern with the matrix that maps pattern coordinates to SVG coordinates.
+type svgPattern struct {
+	pattern *Pattern
+	m       Matrix
+}
+
+func newSVGWriter(writer io.Writer, height float64) *svgWriter {
+	return &svgWriter{
+		Writer:   writer,
+		height:   height,
+		ids:      map[string]int{},
+		symbols:  map[*Symbol]string{},
+		masks:    map[clipPath]string{},
+		patterns: map[svgPattern]string{},
+	}
+}
+
+// subWriter returns a writer for nested contents such as symbols, masks and patterns, which shares the definitions of w.
+func (w *svgWriter) subWriter(height float64) *svgWriter {
+	sub := *w
+	sub.height = height
+	return &sub
+}
+
+// newID returns a unique identifier within the SVG document with the given prefix.
+func (w *svgWriter) newID(prefix string) string {
+	n := w.ids[prefix]
+	w.ids[prefix]++
+	return fmt.Sprintf("%s%d", prefix, n)
+}
+
+// writeGradient writes the gradient definition and returns its identifier. Matrix m maps gradient coordinates to canvas coordinates.
+func (w *svgWriter) writeGradient(g Gradient, m Matrix) string {
+	id := w.newID("g")
+	m = Identity.Translate(0.0, w.height).ReflectY().Mul(m)
+	transform := fmt.Sprintf("matrix(%v,%v,%v,%v,%v,%v)", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+
+	element := ""
+	switch g := g.(type) {
+	case *LinearGradient:
+		element = "linearGradient"
+		fmt.Fprintf(w, `<defs><linearGradient id="%s" gradientUnits="userSpaceOnUse" gradientTransform="%s" x1="%v" y1="%v" x2="%v" y2="%v`, id, transform, dec(g.Start.X), dec(g.Start.Y), dec(g.End.X), dec(g.End.Y))
+	case *RadialGradient:
+		element = "radialGradient"
+		fmt.Fprintf(w, `<defs><radialGradient id="%s" gradientUnits="userSpaceOnUse" gradientTransform="%s" fx="%v" fy="%v`, id, transform, dec(g.C0.X), dec(g.C0.Y))
+		if g.R0 != 0.0 {
+			fmt.Fprintf(w, `" fr="%v`, dec(g.R0))
+		}
+		fmt.Fprintf(w, `" cx="%v" cy="%v" r="%v`, dec(g.C1.X), dec(g.C1.Y), dec(g.R1))
+	default:
+		panic("SVG: gradient not supported")
+	}
+	if spread := g.SpreadMethod(); spread != PadSpread {
+		fmt.Fprintf(w, `" spreadMethod="%s`, strings.ToLower(spread.String()))
+	}
+	fmt.Fprintf(w, `">`)
+	for _, stop := range g.ColorStops() {
+		R, G, B, a := unpremultiply(stop.Color)
+		col := color.RGBA{uint8(R*255.0 + 0.5), uint8(G*255.0 + 0.5), uint8(B*255.0 + 0.5), 255}
+		fmt.Fprintf(w, `<stop offset="%v" stop-color="%v`, dec(stop.Offset), cssColor(col))
+		if a != 1.0 {
+			fmt.Fprintf(w, `" stop-opacity="%v`, dec(a))
+		}
+		fmt.Fprintf(w, `"/>`)
+	}
+	fmt.Fprintf(w, `</%s></defs>`, element)
+	return id
+}
+
+// writeClipPaths writes the definitions of the clipping paths, each being clipped by the previous, and returns the identifier of the last.
+func (w *svgWriter) writeClipPaths(clips []clipPath) string {
+	id := ""
+	fmt.Fprintf(w, `<defs>`)
+	for _, clip := range clips {
+		prev := id
+		id = w.newID("c")
+		fmt.Fprintf(w, `<clipPath id="%s`, id)
+		if prev != "" {
+			fmt.Fprintf(w, `" clip-path="url(#%s)`, prev)
+		}
+		p := clip.path.Transform(Identity.Translate(0.0, w.height).ReflectY())
+		fmt.Fprintf(w, `"><path d="%s`, p.ToSVG())
+		if clip.fillRule == EvenOdd {
+			fmt.Fprintf(w, `" clip-rule="evenodd`)
+		}
+		fmt.Fprintf(w, `"/></clipPath>`)
+	}
+	fmt.Fprintf(w, `</defs>`)
+	return id
+}
+
+// writeSymbol writes the symbol definition when it is first used and returns its identifier. The symbol is written in its own coordinates with the y-axis pointing down.
+func (w *svgWriter) writeSymbol(symbol *Symbol) string {
+	if id, ok := w.symbols[symbol]; ok {
+		return id
+	}
+	id := w.newID("s")
+	w.symbols[symbol] = id
+
+	fmt.Fprintf(w, `<defs><symbol id="%s" overflow="visible">`, id)
+	writeSVGLayers(w.subWriter(0.0), symbol.layers)
+	fmt.Fprintf(w, `</symbol></defs>`)
+	return id
+}
+
+// writeMask writes the mask definition when it is first used with the given transformation and returns its identifier. Matrix m maps mask coordinates to canvas coordinates, the mask region is limited to the bounds of its contents.
+func (w *svgWriter) writeMask(mask *Mask, m Matrix) string {
+	m = Identity.Translate(0.0, w.height).ReflectY().Mul(m)
+	key := clipPath{mask: mask, m: m}
+	if id, ok := w.masks[key]; ok {
+		return id
+	}
+	id := w.newID("m")
+	w.masks[key] = id
+
+	bounds := mask.Bounds().Transform(m)
+	fmt.Fprintf(w, `<defs><mask id="%s" maskUnits="userSpaceOnUse" x="%v" y="%v" width="%v" height="%v"`, id, dec(bounds.X), dec(bounds.Y), dec(bounds.W), dec(bounds.H))
+	if mask.Type == AlphaMask {
+		fmt.Fprintf(w, ` style="mask-type:alpha"`)
+	}
+
+	// the mask contents are written with the y-axis pointing down
+	m = m.ReflectY()
+	fmt.Fprintf(w, `><g transform="matrix(%v,%v,%v,%v,%v,%v)">`, dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	writeSVGLayers(w.subWriter(0.0), mask.layers)
+	fmt.Fprintf(w, `</g></mask></defs>`)
+	return id
+}
+
+// writePattern writes the pattern definition when it is first used with the given transformation and returns its identifier. Matrix m maps pattern coordinates to canvas coordinates.
+func (w *svgWriter) writePattern(pattern *Pattern, m Matrix) string {
+	m = Identity.Translate(0.0, w.height).ReflectY().Mul(m).Translate(0.0, pattern.H).ReflectY()
+	key := svgPattern{pattern, m}
+	if id, ok := w.patterns[key]; ok {
+		return id
+	}
+	id := w.newID("p")
+	w.patterns[key] = id
+
+	fmt.Fprintf(w, `<defs><pattern id="%s" patternUnits="userSpaceOnUse" width="%v" height="%v" patternTransform="matrix(%v,%v,%v,%v,%v,%v)">`, id, dec(pattern.W), dec(pattern.H), dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	writeSVGLayers(w.subWriter(pattern.H), pattern.layers)
+	fmt.Fprintf(w, `</pattern></defs>`)
+	return id
+}
@@ -0,0 +1,911 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	// register decoders of embedded images
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/xml"
+)
+
+// svgNode is an element of an SVG document.
+type svgNode struct {
+	tag      string
+	attrs    map[string]string
+	children []*svgNode
+}
+
+// svgInherited are the properties that are inherited from parent elements, see https://www.w3.org/TR/SVG11/propidx.html
+var svgInherited = []string{
+	"color", "fill", "fill-opacity", "fill-rule", "stroke", "stroke-opacity", "stroke-width", "stroke-linecap", "stroke-linejoin", "stroke-miterlimit", "stroke-dasharray", "stroke-dashoffset", "visibility",
+}
+
+// svgProperties are the presentation attributes that are used, which can also be set by the style attribute.
+var svgProperties = append([]string{"opacity", "display"}, svgInherited...)
+
+// ReadSVG reads an SVG document and draws it onto a new Canvas, so that it can be written to other formats. It supports paths, basic shapes, groups with transformations, fill and stroke properties set by attributes or inline styles, the viewBox, use and defs elements, and images embedded as data URIs. The canvas size is the width and height of the document, where lengths in pixels or without unit are taken as millimeters, which is the inverse of Canvas.WriteSVG. Text, gradients, clipping paths, masks and CSS stylesheets are not supported.
+func ReadSVG(r io.Reader) (*Canvas, error) {
+	root, err := parseSVGNodes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	vbX, vbY, vbW, vbH := 0.0, 0.0, 0.0, 0.0
+	viewBox := svgNumbers(root.attrs["viewBox"])
+	if len(viewBox) == 4 && 0.0 < viewBox[2] && 0.0 < viewBox[3] {
+		vbX, vbY, vbW, vbH = viewBox[0], viewBox[1], viewBox[2], viewBox[3]
+	} else {
+		viewBox = nil
+	}
+
+	// the default size is the size of the viewBox, or 300x150 without a viewBox
+	defaultW, defaultH := 300.0, 150.0
+	if viewBox != nil {
+		defaultW, defaultH = vbW, vbH
+	}
+	width, err := svgSize(root.attrs["width"], defaultW)
+	if err != nil {
+		return nil, err
+	}
+	height, err := svgSize(root.attrs["height"], defaultH)
+	if err != nil {
+		return nil, err
+	}
+
+	c := New(width, height)
+	r2 := &svgReader{
+		c:   c,
+		ids: map[string]*svgNode{},
+	}
+	r2.collectIDs(root)
+
+	m := Identity.Translate(0.0, height).ReflectY()
+	if viewBox != nil {
+		m = m.Mul(svgViewBox(root.attrs["preserveAspectRatio"], Rect{0.0, 0.0, width, height}, Rect{vbX, vbY, vbW, vbH}))
+		r2.viewport = Rect{vbX, vbY, vbW, vbH}
+	} else {
+		// without a viewBox, the user units are pixels of the document size
+		vbW, vbH = svgUserSize(root.attrs["width"], width), svgUserSize(root.attrs["height"], height)
+		m = m.Scale(width/vbW, height/vbH)
+		r2.viewport = Rect{0.0, 0.0, vbW, vbH}
+	}
+
+	style := r2.style(root, map[string]string{})
+	for _, child := range root.children {
+		if err := r2.draw(child, m, style, 1.0); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// parseSVGNodes parses the SVG document into a tree of elements and returns the root svg element.
+func parseSVGNodes(r io.Reader) (*svgNode, error) {
+	var root *svgNode
+	stack := []*svgNode{}
+	l := xml.NewLexer(r)
+	for {
+		tt, data := l.Next()
+		switch tt {
+		case xml.ErrorToken:
+			if l.Err() != io.EOF {
+				return nil, l.Err()
+			} else if root == nil {
+				return nil, fmt.Errorf("bad SVG: missing svg element")
+			}
+			return root, nil
+		case xml.StartTagToken:
+			node := &svgNode{
+				tag:   svgLocalName(string(l.Text())),
+				attrs: map[string]string{},
+			}
+			if root == nil {
+				if node.tag != "svg" {
+					return nil, fmt.Errorf("bad SVG: root element is %s instead of svg", node.tag)
+				}
+				root = node
+			} else if 0 < len(stack) {
+				stack[len(stack)-1].children = append(stack[len(stack)-1].children, node)
+			}
+			stack = append(stack, node)
+		case xml.AttributeToken:
+			val := l.AttrVal()
+			if 1 < len(val) && (val[0] == '\'' || val[0] == '"') && val[0] == val[len(val)-1] {
+				val = val[1 : len(val)-1]
+			}
+			if 0 < len(stack) {
+				stack[len(stack)-1].attrs[string(l.Text())] = html.UnescapeString(string(val))
+			}
+		case xml.StartTagCloseVoidToken:
+			stack = stack[:len(stack)-1]
+		case xml.EndTagToken:
+			tag := svgLocalName(string(data[2 : len(data)-1]))
+			for i := len(stack) - 1; 0 <= i; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// svgLocalName strips the namespace prefix of a tag name.
+func svgLocalName(tag string) string {
+	if i := strings.IndexByte(tag, ':'); i != -1 {
+		return tag[i+1:]
+	}
+	return tag
+}
+
+type svgReader struct {
+	c        *Canvas
+	ids      map[string]*svgNode
+	uses     []*svgNode // elements referenced by use elements being drawn, to prevent cycles
+	viewport Rect       // viewport in user units, used for percentages
+}
+
+func (r *svgReader) collectIDs(node *svgNode) {
+	if id, ok := node.attrs["id"]; ok {
+		if _, ok := r.ids[id]; !ok {
+			r.ids[id] = node
+		}
+	}
+	for _, child := range node.children {
+		r.collectIDs(child)
+	}
+}
+
+// style returns the properties of node, which are the inherited properties of the parent overridden by the presentation attributes and the style attribute.
+func (r *svgReader) style(node *svgNode, parent map[string]string) map[string]string {
+	style := map[string]string{}
+	for _, key := range svgInherited {
+		if val, ok := parent[key]; ok {
+			style[key] = val
+		}
+	}
+	for _, key := range svgProperties {
+		if val, ok := node.attrs[key]; ok {
+			style[key] = strings.TrimSpace(val)
+		}
+	}
+	for _, decl := range strings.Split(node.attrs["style"], ";") {
+		if i := strings.IndexByte(decl, ':'); i != -1 {
+			key := strings.ToLower(strings.TrimSpace(decl[:i]))
+			val := strings.TrimSpace(decl[i+1:])
+			val = strings.TrimSpace(strings.TrimSuffix(val, "!important"))
+			style[key] = val
+		}
+	}
+
+	// inherit explicitly
+	for key, val := range style {
+		if val == "inherit" {
+			if val, ok := parent[key]; ok {
+				style[key] = val
+			} else {
+				delete(style, key)
+			}
+		}
+	}
+	return style
+}
+
+// length parses a length, where percentages are relative to the viewport's width for horizontal lengths (dir is 'x'), height for vertical lengths (dir is 'y') or its normalized diagonal otherwise.
+func (r *svgReader) length(s string, dir byte) (float64, error) {
+	ref := math.Sqrt((r.viewport.W*r.viewport.W + r.viewport.H*r.viewport.H) / 2.0)
+	if dir == 'x' {
+		ref = r.viewport.W
+	} else if dir == 'y' {
+		ref = r.viewport.H
+	}
+	return svgLength(s, ref, 0.0)
+}
+
+func (r *svgReader) draw(node *svgNode, m Matrix, parent map[string]string, opacity float64) error {
+	style := r.style(node, parent)
+	if style["display"] == "none" {
+		return nil
+	}
+	if val, ok := style["opacity"]; ok {
+		opacity *= svgOpacity(val)
+	}
+
+	if transform, ok := node.attrs["transform"]; ok {
+		t, err := svgTransform(transform)
+		if err != nil {
+			return err
+		}
+		m = m.Mul(t)
+	}
+
+	var p *Path
+	switch node.tag {
+	case "g", "a", "switch":
+		for _, child := range node.children {
+			if err := r.draw(child, m, style, opacity); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "use":
+		return r.drawUse(node, m, style, opacity)
+	case "image":
+		if style["visibility"] == "hidden" || style["visibility"] == "collapse" {
+			return nil
+		}
+		return r.drawImage(node, m, opacity)
+	case "path":
+		var err error
+		if p, err = ParseSVG(node.attrs["d"]); err != nil {
+			return fmt.Errorf("bad SVG path: %w", err)
+		}
+	case "rect":
+		x, y, w, h, rx, ry, err := r.rectAttrs(node)
+		if err != nil {
+			return err
+		}
+		p = svgRectangle(w, h, rx, ry).Translate(x, y)
+	case "circle":
+		cx, cy, rx, _, err := r.ellipseAttrs(node, "r", "r")
+		if err != nil {
+			return err
+		}
+		p = Circle(rx).Translate(cx, cy)
+	case "ellipse":
+		cx, cy, rx, ry, err := r.ellipseAttrs(node, "rx", "ry")
+		if err != nil {
+			return err
+		}
+		p = Ellipse(rx, ry).Translate(cx, cy)
+	case "line":
+		vals := [4]float64{}
+		for i, key := range []string{"x1", "y1", "x2", "y2"} {
+			var err error
+			if vals[i], err = r.length(node.attrs[key], key[0]); err != nil {
+				return err
+			}
+		}
+		p = &Path{}
+		p.MoveTo(vals[0], vals[1])
+		p.LineTo(vals[2], vals[3])
+		style["fill"] = "none" // lines have no interior
+	case "polyline", "polygon":
+		points := svgNumbers(node.attrs["points"])
+		p = &Path{}
+		for i := 0; i+1 < len(points); i += 2 {
+			if i == 0 {
+				p.MoveTo(points[i], points[i+1])
+			} else {
+				p.LineTo(points[i], points[i+1])
+			}
+		}
+		if node.tag == "polygon" && !p.Empty() {
+			p.Close()
+		}
+	default:
+		return nil // not rendered, such as defs, symbol, or unsupported
+	}
+	if style["visibility"] == "hidden" || style["visibility"] == "collapse" {
+		return nil
+	}
+	return r.drawPath(p, m, style, opacity)
+}
+
+func (r *svgReader) drawUse(node *svgNode, m Matrix, style map[string]string, opacity float64) error {
+	href, ok := node.attrs["href"]
+	if !ok {
+		href = node.attrs["xlink:href"]
+	}
+	if !strings.HasPrefix(href, "#") {
+		return nil // external references are not supported
+	}
+	ref, ok := r.ids[href[1:]]
+	if !ok {
+		return nil
+	}
+	for _, use := range r.uses {
+		if use == ref {
+			return fmt.Errorf("bad SVG: use element references itself: %s", href)
+		}
+	}
+
+	x, err := r.length(node.attrs["x"], 'x')
+	if err != nil {
+		return err
+	}
+	y, err := r.length(node.attrs["y"], 'y')
+	if err != nil {
+		return err
+	}
+	m = m.Translate(x, y)
+
+	r.uses = append(r.uses, ref)
+	defer func() { r.uses = r.uses[:len(r.uses)-1] }()
+	if ref.tag == "symbol" || ref.tag == "svg" {
+		// draw the contents of the symbol in the viewport given by the use element
+		style = r.style(ref, style)
+		if style["display"] == "none" {
+			return nil
+		}
+		if val, ok := style["opacity"]; ok {
+			opacity *= svgOpacity(val)
+		}
+		if viewBox := svgNumbers(ref.attrs["viewBox"]); len(viewBox) == 4 && 0.0 < viewBox[2] && 0.0 < viewBox[3] {
+			w, err := svgLength(node.attrs["width"], r.viewport.W, viewBox[2])
+			if err != nil {
+				return err
+			}
+			h, err := svgLength(node.attrs["height"], r.viewport.H, viewBox[3])
+			if err != nil {
+				return err
+			}
+			m = m.Mul(svgViewBox(ref.attrs["preserveAspectRatio"], Rect{0.0, 0.0, w, h}, Rect{viewBox[0], viewBox[1], viewBox[2], viewBox[3]}))
+		}
+		for _, child := range ref.children {
+			if err := r.draw(child, m, style, opacity); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return r.draw(ref, m, style, opacity)
+}
+
+func (r *svgReader) drawImage(node *svgNode, m Matrix, opacity float64) error {
+	href, ok := node.attrs["href"]
+	if !ok {
+		href = node.attrs["xlink:href"]
+	}
+	mediatype, data, err := parse.DataURI([]byte(href))
+	if err != nil {
+		return nil // only images embedded as data URIs are supported
+	}
+
+	var img image.Image
+	if bytes.Equal(mediatype, []byte("image/jpeg")) {
+		img, err = NewJPEGImage(bytes.NewReader(data))
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return fmt.Errorf("bad SVG image: %w", err)
+	}
+	size := img.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return nil
+	}
+
+	x, err := r.length(node.attrs["x"], 'x')
+	if err != nil {
+		return err
+	}
+	y, err := r.length(node.attrs["y"], 'y')
+	if err != nil {
+		return err
+	}
+	w, err := svgLength(node.attrs["width"], r.viewport.W, float64(size.X))
+	if err != nil {
+		return err
+	}
+	h, err := svgLength(node.attrs["height"], r.viewport.H, float64(size.Y))
+	if err != nil {
+		return err
+	}
+	if w <= 0.0 || h <= 0.0 {
+		return nil
+	}
+
+	// map the image in pixels to the box, and flip it since images are drawn upwards from their bottom-left corner
+	m = m.Mul(svgViewBox(node.attrs["preserveAspectRatio"], Rect{x, y, w, h}, Rect{0.0, 0.0, float64(size.X), float64(size.Y)}))
+	m = m.Translate(0.0, float64(size.Y)).ReflectY()
+	if opacity < 1.0 {
+		img = svgAlphaImage{img, opacity}
+	}
+	r.c.SetView(m)
+	r.c.DrawImage(0.0, 0.0, img, Lossless, 1.0)
+	r.c.ResetView()
+	return nil
+}
+
+func (r *svgReader) rectAttrs(node *svgNode) (float64, float64, float64, float64, float64, float64, error) {
+	vals := [6]float64{}
+	for i, key := range []string{"x", "y", "width", "height", "rx", "ry"} {
+		dir := key[0]
+		if key == "width" {
+			dir = 'x'
+		} else if key == "height" {
+			dir = 'y'
+		}
+
+		var err error
+		if vals[i], err = r.length(node.attrs[key], dir); err != nil {
+			return 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, err
+		}
+	}
+
+	// a missing radius takes the value of the other radius
+	_, hasRx := node.attrs["rx"]
+	_, hasRy := node.attrs["ry"]
+	if hasRx && !hasRy {
+		vals[5] = vals[4]
+	} else if !hasRx && hasRy {
+		vals[4] = vals[5]
+	}
+	return vals[0], vals[1], vals[2], vals[3], vals[4], vals[5], nil
+}
+
+func (r *svgReader) ellipseAttrs(node *svgNode, rxKey, ryKey string) (float64, float64, float64, float64, error) {
+	cx, err := r.length(node.attrs["cx"], 'x')
+	if err != nil {
+		return 0.0, 0.0, 0.0, 0.0, err
+	}
+	cy, err := r.length(node.attrs["cy"], 'y')
+	if err != nil {
+		return 0.0, 0.0, 0.0, 0.0, err
+	}
+	dirX, dirY := byte('x'), byte('y')
+	if rxKey == "r" {
+		dirX, dirY = 0, 0
+	}
+	rx, err := r.length(node.attrs[rxKey], dirX)
+	if err != nil {
+		return 0.0, 0.0, 0.0, 0.0, err
+	}
+	ry, err := r.length(node.attrs[ryKey], dirY)
+	if err != nil {
+		return 0.0, 0.0, 0.0, 0.0, err
+	}
+	return cx, cy, rx, ry, nil
+}
+
+func (r *svgReader) drawPath(p *Path, m Matrix, style map[string]string, opacity float64) error {
+	if p.Empty() {
+		return nil
+	}
+
+	currentColor := color.NRGBA{0, 0, 0, 255}
+	if val, ok := style["color"]; ok {
+		if col, ok := svgColor(val, currentColor); ok {
+			currentColor = col
+		}
+	}
+
+	fill, hasFill := color.NRGBA{0, 0, 0, 255}, true
+	if val, ok := style["fill"]; ok {
+		fill, hasFill = svgPaint(val, currentColor)
+	}
+	if val, ok := style["fill-opacity"]; ok {
+		fill.A = uint8(float64(fill.A)*svgOpacity(val) + 0.5)
+	}
+	fill.A = uint8(float64(fill.A)*opacity + 0.5)
+
+	stroke, hasStroke := color.NRGBA{}, false
+	if val, ok := style["stroke"]; ok {
+		stroke, hasStroke = svgPaint(val, currentColor)
+	}
+	if val, ok := style["stroke-opacity"]; ok {
+		stroke.A = uint8(float64(stroke.A)*svgOpacity(val) + 0.5)
+	}
+	stroke.A = uint8(float64(stroke.A)*opacity + 0.5)
+
+	strokeWidth := 1.0
+	if val, ok := style["stroke-width"]; ok {
+		var err error
+		if strokeWidth, err = r.length(val, 0); err != nil {
+			return err
+		}
+	}
+	if strokeWidth <= 0.0 {
+		hasStroke = false
+	}
+
+	capper := ButtCapper
+	switch style["stroke-linecap"] {
+	case "round":
+		capper = RoundCapper
+	case "square":
+		capper = SquareCapper
+	}
+	miterLimit := 4.0
+	if limits := svgNumbers(style["stroke-miterlimit"]); len(limits) == 1 && 1.0 <= limits[0] {
+		miterLimit = limits[0]
+	}
+	joiner := MiterClipJoiner(BevelJoiner, miterLimit)
+	switch style["stroke-linejoin"] {
+	case "round":
+		joiner = RoundJoiner
+	case "bevel":
+		joiner = BevelJoiner
+	case "arcs":
+		joiner = ArcsClipJoiner(BevelJoiner, miterLimit)
+	}
+
+	dashes := []float64{}
+	dashOffset := 0.0
+	if val, ok := style["stroke-dasharray"]; ok && val != "none" {
+		for _, dash := range strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' }) {
+			d, err := r.length(dash, 0)
+			if err != nil {
+				return err
+			} else if d < 0.0 {
+				dashes = dashes[:0]
+				break
+			}
+			dashes = append(dashes, d)
+		}
+		if len(dashes)%2 == 1 {
+			dashes = append(dashes, dashes...)
+		}
+		if val, ok := style["stroke-dashoffset"]; ok {
+			var err error
+			if dashOffset, err = r.length(val, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	fillRule := NonZero
+	if style["fill-rule"] == "evenodd" {
+		fillRule = EvenOdd
+	}
+
+	prevFillRule := FillRule
+	FillRule = fillRule
+	defer func() { FillRule = prevFillRule }()
+
+	if !hasFill {
+		fill = color.NRGBA{}
+	}
+	if !hasStroke {
+		stroke = color.NRGBA{}
+	}
+
+	r.c.SetView(m)
+	defer r.c.ResetView()
+	scale, uniform := svgUniformScale(m)
+	if stroke.A == 0 || uniform {
+		// stroke natively with the stroke properties scaled to canvas coordinates
+		for i := range dashes {
+			dashes[i] *= scale
+		}
+		r.c.SetFillColor(fill)
+		r.c.SetStrokeColor(stroke)
+		r.c.SetStrokeWidth(strokeWidth * scale)
+		r.c.SetStrokeCapper(capper)
+		r.c.SetStrokeJoiner(joiner)
+		r.c.SetDashes(dashOffset*scale, dashes...)
+		r.c.DrawPath(0.0, 0.0, p)
+		return nil
+	}
+
+	// the stroke is skewed or scaled non-uniformly, so that it must be stroked in user coordinates and filled
+	r.c.SetStrokeColor(Transparent)
+	if fill.A != 0 {
+		r.c.SetFillColor(fill)
+		r.c.DrawPath(0.0, 0.0, p)
+	}
+	if 0 < len(dashes) {
+		p = p.Dash(dashOffset, dashes...)
+	}
+	p = p.Stroke(strokeWidth, capper, joiner)
+	FillRule = NonZero
+	r.c.SetFillColor(stroke)
+	r.c.DrawPath(0.0, 0.0, p)
+	return nil
+}
+
+// svgUniformScale returns the scale factor of m if it scales uniformly in all directions, ie. if it has no skew and has equal scales in both directions.
+func svgUniformScale(m Matrix) (float64, bool) {
+	if equal(m[0][0], m[1][1]) && equal(m[0][1], -m[1][0]) || equal(m[0][0], -m[1][1]) && equal(m[0][1], m[1][0]) {
+		return math.Sqrt(math.Abs(m.Det())), true
+	}
+	return 0.0, false
+}
+
+// svgRectangle returns a rectangle with corners rounded by the radii rx and ry, which are clamped to half the width and height.
+func svgRectangle(w, h, rx, ry float64) *Path {
+	if w <= 0.0 || h <= 0.0 {
+		return &Path{}
+	} else if rx <= 0.0 || ry <= 0.0 {
+		return Rectangle(w, h)
+	}
+	rx = math.Min(rx, w/2.0)
+	ry = math.Min(ry, h/2.0)
+
+	p := &Path{}
+	p.MoveTo(rx, 0.0)
+	p.LineTo(w-rx, 0.0)
+	p.ArcTo(rx, ry, 0.0, false, true, w, ry)
+	p.LineTo(w, h-ry)
+	p.ArcTo(rx, ry, 0.0, false, true, w-rx, h)
+	p.LineTo(rx, h)
+	p.ArcTo(rx, ry, 0.0, false, true, 0.0, h-ry)
+	p.LineTo(0.0, ry)
+	p.ArcTo(rx, ry, 0.0, false, true, rx, 0.0)
+	p.Close()
+	return p
+}
+
+// svgViewBox returns the transformation that maps the viewBox onto the viewport, respecting the preserveAspectRatio attribute.
+func svgViewBox(preserveAspectRatio string, viewport, viewBox Rect) Matrix {
+	sx, sy := viewport.W/viewBox.W, viewport.H/viewBox.H
+	fields := strings.Fields(preserveAspectRatio)
+	align, meetOrSlice := "xMidYMid", "meet"
+	if 0 < len(fields) {
+		align = fields[0]
+	}
+	if 1 < len(fields) {
+		meetOrSlice = fields[1]
+	}
+
+	tx, ty := viewport.X-viewBox.X*sx, viewport.Y-viewBox.Y*sy
+	if align != "none" && len(align) == 8 {
+		if meetOrSlice == "slice" {
+			sx = math.Max(sx, sy)
+		} else {
+			sx = math.Min(sx, sy)
+		}
+		sy = sx
+		tx, ty = viewport.X-viewBox.X*sx, viewport.Y-viewBox.Y*sy
+		switch align[:4] {
+		case "xMid":
+			tx += (viewport.W - viewBox.W*sx) / 2.0
+		case "xMax":
+			tx += viewport.W - viewBox.W*sx
+		}
+		switch align[4:] {
+		case "YMid":
+			ty += (viewport.H - viewBox.H*sy) / 2.0
+		case "YMax":
+			ty += viewport.H - viewBox.H*sy
+		}
+	}
+	return Identity.Translate(tx, ty).Scale(sx, sy)
+}
+
+// svgTransform parses the transform attribute.
+func svgTransform(s string) (Matrix, error) {
+	m := Identity
+	s = strings.TrimSpace(s)
+	for 0 < len(s) {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open == -1 || end < open {
+			return Identity, fmt.Errorf("bad SVG transform: %s", s)
+		}
+		name := strings.TrimSpace(s[:open])
+		vals := svgNumbers(s[open+1 : end])
+		s = strings.TrimLeft(s[end+1:], ", \t\n\r")
+
+		switch {
+		case name == "matrix" && len(vals) == 6:
+			m = m.Mul(Matrix{
+				{vals[0], vals[2], vals[4]},
+				{vals[1], vals[3], vals[5]},
+			})
+		case name == "translate" && len(vals) == 1:
+			m = m.Translate(vals[0], 0.0)
+		case name == "translate" && len(vals) == 2:
+			m = m.Translate(vals[0], vals[1])
+		case name == "scale" && len(vals) == 1:
+			m = m.Scale(vals[0], vals[0])
+		case name == "scale" && len(vals) == 2:
+			m = m.Scale(vals[0], vals[1])
+		case name == "rotate" && len(vals) == 1:
+			m = m.Rotate(vals[0])
+		case name == "rotate" && len(vals) == 3:
+			m = m.RotateAt(vals[0], vals[1], vals[2])
+		case name == "skewX" && len(vals) == 1:
+			m = m.Shear(math.Tan(vals[0]*math.Pi/180.0), 0.0)
+		case name == "skewY" && len(vals) == 1:
+			m = m.Shear(0.0, math.Tan(vals[0]*math.Pi/180.0))
+		default:
+			return Identity, fmt.Errorf("bad SVG transform: %s(%s)", name, s)
+		}
+	}
+	return m, nil
+}
+
+// svgNumbers parses a list of numbers separated by whitespace and/or commas.
+func svgNumbers(s string) []float64 {
+	b := []byte(s)
+	nums := []float64{}
+	i := skipCommaWhitespace(b)
+	for i < len(b) {
+		f, n := parseNum(b[i:])
+		if n == 0 {
+			break
+		}
+		nums = append(nums, f)
+		i += n
+		i += skipCommaWhitespace(b[i:])
+	}
+	return nums
+}
+
+// svgSize parses the width or height of the document in millimeters, where pixels are taken as millimeters and def is returned for an empty string or a percentage.
+func svgSize(s string, def float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "auto" || strings.HasSuffix(s, "%") {
+		return def, nil
+	}
+	f, n := parseNum([]byte(s))
+	if n == 0 {
+		return 0.0, fmt.Errorf("bad SVG length: %s", s)
+	}
+
+	switch unit := strings.TrimSpace(s[n:]); unit {
+	case "", "px", "mm":
+		return f, nil
+	case "cm":
+		return f * 10.0, nil
+	case "in":
+		return f * mmPerInch, nil
+	case "pt":
+		return f * mmPerPt, nil
+	case "pc":
+		return f * 12.0 * mmPerPt, nil
+	default:
+		return 0.0, fmt.Errorf("bad SVG length: unknown unit %s", unit)
+	}
+}
+
+// svgUserSize returns the width or height of a document without a viewBox in user units, with s its attribute and size the size in millimeters as returned by svgSize. Since svgSize takes pixels as millimeters, only sizes with absolute units are converted.
+func svgUserSize(s string, size float64) float64 {
+	s = strings.TrimSpace(s)
+	if f, n := parseNum([]byte(s)); n != 0 && f != 0.0 {
+		switch strings.TrimSpace(s[n:]) {
+		case "mm", "cm", "in", "pt", "pc":
+			return size * 96.0 / mmPerInch
+		}
+	}
+	return size
+}
+
+// svgLength parses a length with an optional unit, where percentages are relative to ref and def is returned for an empty string. Lengths are in user units, which are pixels.
+func svgLength(s string, ref, def float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "auto" {
+		return def, nil
+	}
+	f, n := parseNum([]byte(s))
+	if n == 0 {
+		return 0.0, fmt.Errorf("bad SVG length: %s", s)
+	}
+
+	switch unit := strings.TrimSpace(s[n:]); unit {
+	case "", "px":
+		return f, nil
+	case "%":
+		return f * ref / 100.0, nil
+	case "mm":
+		return f * 96.0 / mmPerInch, nil
+	case "cm":
+		return f * 960.0 / mmPerInch, nil
+	case "in":
+		return f * 96.0, nil
+	case "pt":
+		return f * 96.0 / 72.0, nil
+	case "pc":
+		return f * 16.0, nil
+	case "em":
+		return f * 16.0, nil // default font size
+	case "ex":
+		return f * 8.0, nil
+	default:
+		return 0.0, fmt.Errorf("bad SVG length: unknown unit %s", unit)
+	}
+}
+
+// svgOpacity parses an opacity as a number or percentage, clamped between zero and one.
+func svgOpacity(s string) float64 {
+	f, n := parseNum([]byte(s))
+	if n == 0 {
+		return 1.0
+	} else if strings.HasSuffix(strings.TrimSpace(s), "%") {
+		f /= 100.0
+	}
+	return math.Max(0.0, math.Min(1.0, f))
+}
+
+// svgPaint parses the fill or stroke property and returns false when nothing is painted. Paint servers such as gradients are not supported and use the fallback color if given.
+func svgPaint(s string, currentColor color.NRGBA) (color.NRGBA, bool) {
+	if strings.HasPrefix(s, "url(") {
+		end := strings.IndexByte(s, ')')
+		if end == -1 {
+			return color.NRGBA{}, false
+		}
+		s = strings.TrimSpace(s[end+1:])
+	}
+	return svgColor(s, currentColor)
+}
+
+// svgColor parses a CSS color, being a named color, a hexadecimal color or the rgb/rgba functional notation.
+func svgColor(s string, currentColor color.NRGBA) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return color.NRGBA{}, false
+	} else if s == "currentcolor" {
+		return currentColor, true
+	} else if s == "transparent" {
+		return color.NRGBA{}, true
+	} else if col, ok := cssColors[s]; ok {
+		return color.NRGBA{col.R, col.G, col.B, col.A}, true
+	}
+
+	if s[0] == '#' {
+		hex := s[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			long := make([]byte, 0, 8)
+			for i := 0; i < len(hex); i++ {
+				long = append(long, hex[i], hex[i])
+			}
+			hex = string(long)
+		}
+		if len(hex) != 6 && len(hex) != 8 {
+			return color.NRGBA{}, false
+		}
+		vals := [4]uint8{0, 0, 0, 255}
+		for i := 0; i < len(hex)/2; i++ {
+			var v uint8
+			if _, err := fmt.Sscanf(hex[2*i:2*i+2], "%02x", &v); err != nil {
+				return color.NRGBA{}, false
+			}
+			vals[i] = v
+		}
+		return color.NRGBA{vals[0], vals[1], vals[2], vals[3]}, true
+	}
+
+	if strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba(") {
+		end := strings.IndexByte(s, ')')
+		if end == -1 {
+			return color.NRGBA{}, false
+		}
+		args := strings.FieldsFunc(s[strings.IndexByte(s, '(')+1:end], func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+		if len(args) != 3 && len(args) != 4 {
+			return color.NRGBA{}, false
+		}
+		vals := [4]uint8{0, 0, 0, 255}
+		for i, arg := range args {
+			f, n := parseNum([]byte(arg))
+			if n == 0 {
+				return color.NRGBA{}, false
+			}
+			if i == 3 {
+				f = svgOpacity(arg) * 255.0
+			} else if strings.HasSuffix(arg, "%") {
+				f *= 255.0 / 100.0
+			}
+			vals[i] = uint8(math.Max(0.0, math.Min(255.0, f)) + 0.5)
+		}
+		return color.NRGBA{vals[0], vals[1], vals[2], vals[3]}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// svgAlphaImage applies an opacity to an image.
+type svgAlphaImage struct {
+	image.Image
+	opacity float64
+}
+
+func (img svgAlphaImage) At(x, y int) color.Color {
+	r, g, b, a := img.Image.At(x, y).RGBA()
+	f := img.opacity
+	return color.RGBA64{uint16(float64(r) * f), uint16(float64(g) * f), uint16(float64(b) * f), uint16(float64(a) * f)}
+}
+
+func (img svgAlphaImage) ColorModel() color.Model {
+	return color.RGBA64Model
+}
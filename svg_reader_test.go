@@ -0,0 +1,95 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestReadSVG(t *testing.T) {
+	s := `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="20mm" height="10mm" viewBox="0 0 40 20">
+<defs><rect id="r" width="4" height="2" rx="1"/></defs>
+<!-- comment -->
+<g transform="translate(10,0)" fill="red" style="stroke:#00f;stroke-width:2">
+  <path d="M0 0L10 0L10 10z"/>
+  <circle cx="5" cy="5" r="2" fill="none"/>
+  <use xlink:href="#r" x="1" y="1" style="fill:rgb(0,128,0)"/>
+</g>
+<line x1="0" y1="0" x2="0" y2="10" stroke="black" transform="scale(2,1)"/>
+<polygon points="0,0 1,0 1,1" opacity="0.5"/>
+<rect width="5" height="5" display="none"/>
+</svg>`
+	c, err := ReadSVG(strings.NewReader(s))
+	test.Error(t, err)
+	test.Float(t, c.W, 20.0)
+	test.Float(t, c.H, 10.0)
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="20" height="10" viewBox="0 0 20 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><path d="M5 0H10V5z" style="fill:#f00;stroke:#00f"/><path d="M8.5 2.5A1 1 0 0 1 6.5 2.5A1 1 0 0 1 8.5 2.5z" style="fill:none;stroke:#00f"/><path d="M6 .5H7A.5 .5 0 0 1 7.5 1A.5 .5 0 0 1 7 1.5H6A.5 .5 0 0 1 5.5 1A.5 .5 0 0 1 6 .5z" style="fill:#008000;stroke:#00f"/><path d="M.5 0V5H-.5V0H.5z"/><path d="M0 0H.5V.5z" fill="rgba(0,0,0,.50196078)"/></svg>`)
+
+	// reading written SVG gives the same canvas
+	c2, err := ReadSVG(bytes.NewReader(buf.Bytes()))
+	test.Error(t, err)
+	buf2 := &bytes.Buffer{}
+	c2.WriteSVG(buf2)
+	test.String(t, buf2.String(), buf.String())
+
+	// without a viewBox, user units are pixels when the size has absolute units
+	c, err = ReadSVG(strings.NewReader(`<svg width="20mm" height="20mm"><rect width="10mm" height="10mm"/><rect width="1in" height="1in"/></svg>`))
+	test.Error(t, err)
+	test.Float(t, c.W, 20.0)
+	test.T(t, c.layers[0].Bounds(), Rect{0.0, 10.0, 10.0, 10.0})
+	test.T(t, c.layers[1].Bounds(), Rect{0.0, 20.0 - mmPerInch, mmPerInch, mmPerInch})
+	c, err = ReadSVG(strings.NewReader(`<svg width="20" height="20"><rect width="10" height="10"/></svg>`))
+	test.Error(t, err)
+	test.T(t, c.layers[0].Bounds(), Rect{0.0, 10.0, 10.0, 10.0})
+
+	_, err = ReadSVG(strings.NewReader(`<html></html>`))
+	test.T(t, err.Error(), "bad SVG: root element is html instead of svg")
+	_, err = ReadSVG(strings.NewReader(`<svg><g id="a"><use href="#a"/></g></svg>`))
+	test.T(t, err.Error(), "bad SVG: use element references itself: #a")
+}
+
+func TestReadSVGImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, Red)
+	pngBuf := &bytes.Buffer{}
+	test.Error(t, png.Encode(pngBuf, img))
+	href := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngBuf.Bytes())
+
+	c, err := ReadSVG(strings.NewReader(`<svg width="10" height="10"><image x="1" y="1" width="8" height="8" xlink:href="` + href + `"/></svg>`))
+	test.Error(t, err)
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].Bounds(), Rect{1.0, 3.0, 8.0, 4.0}) // centered in the box, keeping the aspect ratio
+}
+
+func TestSVGColor(t *testing.T) {
+	var tests = []struct {
+		s   string
+		col string
+	}{
+		{"red", "{255 0 0 255} true"},
+		{"#0f08", "{0 255 0 136} true"},
+		{"#102030", "{16 32 48 255} true"},
+		{"rgb(100%, 0, 50)", "{255 0 50 255} true"},
+		{"rgba(0,0,0,0.5)", "{0 0 0 128} true"},
+		{"currentColor", "{1 2 3 4} true"},
+		{"none", "{0 0 0 0} false"},
+		{"#12", "{0 0 0 0} false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			col, ok := svgColor(tt.s, color.NRGBA{1, 2, 3, 4})
+			test.String(t, fmt.Sprint(col, ok), tt.col)
+		})
+	}
+}
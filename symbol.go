@@ -0,0 +1,89 @@
+package canvas
+
+import (
+	"fmt"
+	"image/draw"
+)
+
+// Symbol is a group of drawing operations that is defined once and drawn many times with different transformations using Canvas.DrawSymbol, such as the markers of a scatter plot. Its contents are written only once, as a symbol in SVG and as a form XObject in PDF. Draw onto the symbol using the methods of its Canvas, with the origin of the symbol at (0,0). The size of the canvas is not used.
+type Symbol struct {
+	*Canvas
+}
+
+// NewSymbol returns a new empty symbol.
+func NewSymbol() *Symbol {
+	return &Symbol{New(0.0, 0.0)}
+}
+
+// Bounds returns the bounding box of the contents of the symbol in symbol coordinates.
+func (s *Symbol) Bounds() Rect {
+	return layersBounds(s.layers)
+}
+
+// DrawSymbol draws the symbol at position (x,y) using the current affine transformation matrix, which transforms the symbol including its stroke widths. The contents of the symbol must be drawn before writing the canvas.
+func (c *Canvas) DrawSymbol(x, y float64, symbol *Symbol) {
+	for font := range symbol.fonts {
+		c.fonts[font] = true
+	}
+	c.layers = append(c.layers, symbolLayer{symbol, Identity.Translate(x, y).Mul(c.m), c.clips})
+}
+
+////////////////////////////////////////////////////////////////
+
+type symbolLayer struct {
+	symbol *Symbol
+	m      Matrix
+	clips  []clipPath
+}
+
+func (l symbolLayer) Bounds() Rect {
+	return l.symbol.Bounds().Transform(l.m)
+}
+
+func (l symbolLayer) clipPaths() []clipPath {
+	return l.clips
+}
+
+func (l symbolLayer) WriteSVG(w *svgWriter) {
+	if len(l.symbol.layers) == 0 {
+		return
+	}
+	id := w.writeSymbol(l.symbol)
+
+	// symbols are written with the y-axis pointing down
+	m := Identity.Translate(0.0, w.height).ReflectY().Mul(l.m).ReflectY()
+	if m.IsTranslation() {
+		x, y := m.Pos()
+		fmt.Fprintf(w, `<use xlink:href="#%s`, id)
+		if x != 0.0 {
+			fmt.Fprintf(w, `" x="%v`, dec(x))
+		}
+		if y != 0.0 {
+			fmt.Fprintf(w, `" y="%v`, dec(y))
+		}
+		fmt.Fprintf(w, `"/>`)
+	} else {
+		fmt.Fprintf(w, `<use xlink:href="#%s" transform="matrix(%v,%v,%v,%v,%v,%v)"/>`, id, dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	}
+}
+
+func (l symbolLayer) WritePDF(w *pdfPageWriter) {
+	if len(l.symbol.layers) == 0 {
+		return
+	}
+	w.DrawSymbol(l.symbol, l.m)
+}
+
+func (l symbolLayer) WriteEPS(w *epsWriter) {
+	if len(l.symbol.layers) == 0 {
+		return
+	}
+	w.Push()
+	fmt.Fprintf(w, " [%v %v %v %v %v %v] concat", dec(l.m[0][0]), dec(l.m[1][0]), dec(l.m[0][1]), dec(l.m[1][1]), dec(l.m[0][2]), dec(l.m[1][2]))
+	writeEPSLayers(w, l.symbol.layers)
+	w.Pop()
+}
+
+func (l symbolLayer) WriteImage(img draw.Image, m Matrix) {
+	writeImageLayers(img, l.symbol.layers, m.Mul(l.m))
+}
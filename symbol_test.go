@@ -0,0 +1,73 @@
+package canvas
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSymbol(t *testing.T) {
+	sym := NewSymbol()
+	sym.SetFillColor(Red)
+	sym.DrawPath(0, 0, Rectangle(2, 1))
+	test.T(t, sym.Bounds(), Rect{0.0, 0.0, 2.0, 1.0})
+
+	c := New(10, 10)
+	c.DrawSymbol(1, 1, sym)
+	c.SetView(Identity.Rotate(90))
+	c.DrawSymbol(5, 0, sym)
+	test.T(t, c.layers[1].Bounds(), Rect{4.0, 0.0, 1.0, 2.0})
+
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.String(t, buf.String(), `<svg version="1.1" width="10" height="10" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><symbol id="s0" overflow="visible"><path d="M0 0H2V-1H0z" fill="#f00"/></symbol></defs><use xlink:href="#s0" x="1" y="9"/><use xlink:href="#s0" transform="matrix(0,-1,1,0,5,10)"/></svg>`)
+
+	pdfCompress = false
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	s := buf.String()
+	test.T(t, strings.Count(s, "/Subtype /Form /BBox [0 0 2 1]"), 1)
+	test.That(t, strings.Contains(s, "q 1 0 0 1 1 1 cm /Sy0 Do Q q 0 1 -1 0 5 0 cm /Sy0 Do Q"), "symbol drawn twice")
+	test.That(t, strings.Contains(s, "stream\n1 0 0 rg /A0 gs 0 0 m"), "form sets inherited graphics state")
+
+	buf.Reset()
+	test.Error(t, c.writeEPS(buf))
+	test.T(t, strings.Count(buf.String(), "0 0 moveto 2 0 lineto 2 1 lineto 0 1 lineto closepath"), 2)
+}
+
+func TestSymbolLink(t *testing.T) {
+	sym := NewSymbol()
+	sym.SetFillColor(Red)
+	sym.DrawPath(0, 0, Rectangle(2, 1))
+	sym.DrawLinkRect(Rect{0, 0, 2, 1}, Link{URI: "https://example.com"})
+
+	c := New(10, 10)
+	c.DrawSymbol(1, 1, sym)
+	c.DrawSymbol(5, 5, sym)
+
+	pdfCompress = false
+	buf := &bytes.Buffer{}
+	test.Error(t, c.WritePDF(buf))
+	s := buf.String()
+	test.That(t, strings.Contains(s, "/Annots [<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com) >> /Border [0 0 0] /F 4 /Rect [1 1 3 2] >> << /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com) >> /Border [0 0 0] /F 4 /Rect [5 5 7 6] >>]"), "link for each placement")
+	pdfCompress = true
+}
+
+func TestSymbolWriteImage(t *testing.T) {
+	sym := NewSymbol()
+	sym.SetStrokeColor(Blue)
+	sym.DrawPath(0, 0, Circle(1))
+
+	c := New(10, 10)
+	c.SetView(Identity.Scale(2, 2))
+	c.DrawSymbol(5.0, 5.0, sym)
+
+	// the stroke width scales with the symbol
+	ref := New(10, 10)
+	ref.SetStrokeColor(Blue)
+	ref.SetStrokeWidth(2.0)
+	ref.DrawPath(5, 5, Circle(2))
+	test.That(t, bytes.Equal(c.WriteImage(2.0).Pix, ref.WriteImage(2.0).Pix), "symbol renders like the path")
+}
@@ -73,7 +73,7 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 			j := boundary.pos + boundary.size
 			if i < j {
 				l := line{y: y}
-				span := newTextSpan(ff, s[:j], i)
+				span := newTextSpan(ff, s[:j], i, nil)
 				if halign == Center {
 					span.dx = -span.width / 2.0
 				} else if halign == Right {
@@ -115,6 +115,15 @@ func NewRichText() *RichText {
 
 // Add adds a new text span element.
 func (rt *RichText) Add(ff FontFace, s string) *RichText {
+	return rt.add(ff, s, nil)
+}
+
+// AddLink adds a new text span element that follows link when clicked, see Canvas.DrawLink.
+func (rt *RichText) AddLink(ff FontFace, s string, link Link) *RichText {
+	return rt.add(ff, s, &link)
+}
+
+func (rt *RichText) add(ff FontFace, s string, link *Link) *RichText {
 	if 0 < len(s) {
 		rPrev := ' '
 		rNext, size := utf8.DecodeRuneInString(s)
@@ -136,7 +145,7 @@ func (rt *RichText) Add(ff FontFace, s string) *RichText {
 			j := boundary.pos + boundary.size
 			if i < j {
 				extendPrev := false
-				if i == 0 && boundary.kind != lineBoundary && 0 < len(rt.spans) && rt.spans[len(rt.spans)-1].ff.Equals(ff) {
+				if i == 0 && boundary.kind != lineBoundary && 0 < len(rt.spans) && rt.spans[len(rt.spans)-1].ff.Equals(ff) && rt.spans[len(rt.spans)-1].link == link {
 					prevSpan := rt.spans[len(rt.spans)-1]
 					if 1 < len(prevSpan.boundaries) {
 						prevBoundaryKind := prevSpan.boundaries[len(prevSpan.boundaries)-2].kind
@@ -150,9 +159,9 @@ func (rt *RichText) Add(ff FontFace, s string) *RichText {
 
 				if extendPrev {
 					diff := len(rt.spans[len(rt.spans)-1].altText)
-					rt.spans[len(rt.spans)-1] = newTextSpan(ff, rt.text[:start+j], start+i-diff)
+					rt.spans[len(rt.spans)-1] = newTextSpan(ff, rt.text[:start+j], start+i-diff, link)
 				} else {
-					rt.spans = append(rt.spans, newTextSpan(ff, rt.text[:start+j], start+i))
+					rt.spans = append(rt.spans, newTextSpan(ff, rt.text[:start+j], start+i, link))
 				}
 			}
 			i = j
@@ -581,6 +590,25 @@ func (t *Text) writeSVGFontStyle(w io.Writer, ff, ffMain FontFace) {
 	}
 }
 
+// writeSVGMainFontStyle writes the style attribute of a text element, which sets the font that is used by most of the text.
+func writeSVGMainFontStyle(w io.Writer, ffMain FontFace) {
+	fmt.Fprintf(w, ` style="font:`)
+	if ffMain.style&FontItalic != 0 {
+		fmt.Fprintf(w, ` italic`)
+	}
+	if boldness := ffMain.boldness(); boldness != 400 {
+		fmt.Fprintf(w, ` %d`, boldness)
+	}
+	if ffMain.variant&FontSmallcaps != 0 {
+		fmt.Fprintf(w, ` small-caps`)
+	}
+	fmt.Fprintf(w, ` %vpx %s`, num(ffMain.size*ffMain.scale), ffMain.font.name)
+	if ffMain.color != Black {
+		fmt.Fprintf(w, `;fill:%v`, cssColor(ffMain.color))
+	}
+	fmt.Fprintf(w, `"`)
+}
+
 // WriteSVG will write out the text in the SVG file format.
 func (t *Text) WriteSVG(w io.Writer, h float64, m Matrix) {
 	if len(t.lines) == 0 || len(t.lines[0].spans) == 0 {
@@ -593,29 +621,20 @@ func (t *Text) WriteSVG(w io.Writer, h float64, m Matrix) {
 	if m.IsTranslation() {
 		x0, y0 = m.Pos()
 		y0 = h - y0
-		fmt.Fprintf(w, `<text x="%v" y="%v`, num(x0), num(y0))
+		fmt.Fprintf(w, `<text x="%v" y="%v"`, num(x0), num(y0))
 	} else {
-		fmt.Fprintf(w, `<text transform="%s`, m.ToSVG(h))
+		fmt.Fprintf(w, `<text transform="%s"`, m.ToSVG(h))
 	}
-	fmt.Fprintf(w, `" style="font:`)
-	if ffMain.style&FontItalic != 0 {
-		fmt.Fprintf(w, ` italic`)
-	}
-	if boldness := ffMain.boldness(); boldness != 400 {
-		fmt.Fprintf(w, ` %d`, boldness)
-	}
-	if ffMain.variant&FontSmallcaps != 0 {
-		fmt.Fprintf(w, ` small-caps`)
-	}
-	fmt.Fprintf(w, ` %vpx %s`, num(ffMain.size*ffMain.scale), ffMain.font.name)
-	if ffMain.color != Black {
-		fmt.Fprintf(w, `;fill:%v`, cssColor(ffMain.color))
-	}
-	fmt.Fprintf(w, `">`)
+	writeSVGMainFontStyle(w, ffMain)
+	fmt.Fprintf(w, `>`)
 
 	decorations := []pathLayer{}
 	for _, line := range t.lines {
 		for _, span := range line.spans {
+			link := span.link != nil
+			if link {
+				fmt.Fprintf(w, `<a xlink:href="%s">`, svgLinkHref(*span.link))
+			}
 			fmt.Fprintf(w, `<tspan x="%v" y="%v`, num(x0+span.dx), num(y0-line.y-span.ff.voffset))
 			if span.wordSpacing > 0.0 {
 				fmt.Fprintf(w, `" word-spacing="%v`, num(span.wordSpacing))
@@ -627,6 +646,9 @@ func (t *Text) WriteSVG(w io.Writer, h float64, m Matrix) {
 			s := span.text
 			s = strings.ReplaceAll(s, `"`, `&quot;`)
 			fmt.Fprintf(w, `">%s</tspan>`, s)
+			if link {
+				fmt.Fprintf(w, `</a>`)
+			}
 		}
 		for _, deco := range line.decos {
 			p := deco.ff.Decorate(deco.x1 - deco.x0)
@@ -635,8 +657,12 @@ func (t *Text) WriteSVG(w io.Writer, h float64, m Matrix) {
 		}
 	}
 	fmt.Fprintf(w, `</text>`)
+	svg, ok := w.(*svgWriter)
+	if !ok {
+		svg = newSVGWriter(w, h)
+	}
 	for _, l := range decorations {
-		l.WriteSVG(w, h)
+		l.WriteSVG(svg)
 	}
 }
 
@@ -682,6 +708,46 @@ func (t *Text) WritePDF(w *pdfPageWriter, m Matrix) {
 	for _, l := range decorations {
 		l.WritePDF(w)
 	}
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			if span.link != nil {
+				metrics := span.ff.Metrics()
+				rect := Rect{span.dx, line.y - metrics.Descent, span.width, metrics.Ascent + metrics.Descent}
+				w.AddLink(rect.ToPath().Transform(m), *span.link)
+			}
+		}
+	}
+}
+
+// WriteEPS will write out the text in the EPS file format, embedding TrueType fonts as Type 42 fonts. Text in other fonts is written as paths.
+func (t *Text) WriteEPS(w *epsWriter, m Matrix) {
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			w.Push()
+			w.SetColor(span.ff.color)
+			if span.ff.font.mimetype == "font/truetype" {
+				tm := m.Translate(span.dx, line.y+span.ff.voffset).Shear(span.ff.fauxItalic, 0.0)
+				fmt.Fprintf(w, " [%v %v %v %v %v %v] concat", dec(tm[0][0]), dec(tm[1][0]), dec(tm[0][1]), dec(tm[1][1]), dec(tm[0][2]), dec(tm[1][2]))
+				w.WriteGlyphs(span.ff.font, span.ff.size*span.ff.scale, span.text, span.advances(), span.ff.fauxBold*2.0)
+			} else {
+				p, _, _ := span.ToPath(span.width)
+				fmt.Fprintf(w, " %v fill", p.Transform(m.Translate(span.dx, line.y)).ToPS())
+			}
+			w.Pop()
+		}
+		for _, deco := range line.decos {
+			p := deco.ff.Decorate(deco.x1 - deco.x0)
+			p = p.Transform(Identity.Mul(m).Translate(deco.x0, line.y+deco.ff.voffset))
+			pathLayer{p, drawState{fillColor: deco.ff.color}}.WriteEPS(w)
+		}
+		for _, span := range line.spans {
+			if span.link != nil {
+				metrics := span.ff.Metrics()
+				rect := Rect{span.dx, line.y - metrics.Descent, span.width, metrics.Ascent + metrics.Descent}
+				w.AddLink(rect.ToPath().Transform(m), *span.link)
+			}
+		}
+	}
 }
 
 ////////////////////////////////////////////////////////////////
@@ -699,6 +765,7 @@ type textSpan struct {
 	altText       string
 	altWidth      float64
 	altBoundaries []textBoundary
+	link          *Link
 
 	dx              float64
 	sentenceSpacing float64
@@ -706,7 +773,7 @@ type textSpan struct {
 	glyphSpacing    float64
 }
 
-func newTextSpan(ff FontFace, text string, i int) textSpan {
+func newTextSpan(ff FontFace, text string, i int, link *Link) textSpan {
 	altText := text[i:]
 	altWidth := ff.TextWidth(text[i:])
 	altBoundaries := calcTextBoundaries(text, i, len(text))
@@ -720,6 +787,7 @@ func newTextSpan(ff FontFace, text string, i int) textSpan {
 		altText:         altText,
 		altWidth:        altWidth,
 		altBoundaries:   altBoundaries,
+		link:            link,
 		dx:              0.0,
 		sentenceSpacing: 0.0,
 		wordSpacing:     0.0,
@@ -763,6 +831,7 @@ func (span textSpan) split(i int) (textSpan, textSpan) {
 	span0.altText = span.altText[:span.altBoundaries[i].pos] + dash
 	span0.altWidth = span.ff.TextWidth(span0.altText)
 	span0.altBoundaries = append(span.altBoundaries[:i:i], textBoundary{eofBoundary, len(span0.altText), 0})
+	span0.link = span.link
 	span0.dx = span.dx
 
 	span1 := textSpan{}
@@ -775,6 +844,7 @@ func (span textSpan) split(i int) (textSpan, textSpan) {
 	span1.altWidth = span.ff.TextWidth(span1.altText)
 	span1.altBoundaries = make([]textBoundary, len(span.altBoundaries)-i-1)
 	copy(span1.altBoundaries, span.altBoundaries[i+1:])
+	span1.link = span.link
 	span1.dx = span.dx
 	for j := range span1.boundaries {
 		span1.boundaries[j].pos -= span.boundaries[i].pos + span.boundaries[i].size
@@ -834,6 +904,33 @@ func (span textSpan) ToPath(width float64) (*Path, *Path, color.RGBA) {
 	return p, span.ff.Decorate(width), span.ff.color
 }
 
+// advances returns the displacement after each rune of the span, including kerning and spacing, as used by ToPath.
+func (span textSpan) advances() []float64 {
+	iBoundary := 0
+
+	advances := []float64{}
+	var rPrev rune
+	for i, r := range span.text {
+		if i > 0 {
+			advances[len(advances)-1] += span.ff.Kerning(rPrev, r)
+		}
+
+		advance := span.ff.TextWidth(string(r)) + span.glyphSpacing
+		if iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos == i {
+			boundary := span.boundaries[iBoundary]
+			if boundary.kind == sentenceBoundary {
+				advance += span.sentenceSpacing
+			} else if boundary.kind == wordBoundary {
+				advance += span.wordSpacing
+			}
+			iBoundary++
+		}
+		advances = append(advances, advance)
+		rPrev = r
+	}
+	return advances
+}
+
 ////////////////////////////////////////////////////////////////
 
 type textBoundaryKind int
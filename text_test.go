@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -194,3 +195,37 @@ func TestTextWriteSVG(t *testing.T) {
 	text.WriteSVG(buf, 0.0, Identity)
 	test.String(t, buf.String(), `<text x="0" y="0" style="font: 12px dejavu-serif"><tspan x="0" y="7.421875" style="font:8px dejavu-serif">dejaVu8</tspan><tspan x="0" y="20.453125" letter-spacing="1" style="font-style:italic;fill:#f00">glyphspacing</tspan><tspan x="0" y="33.725625" style="font:700 6.996px dejavu-serif">dejaVu12sub</tspan><tspan x="0" y="38.5" style="font:700 10px eb-garamond">garamond10</tspan></text><path d="M0 22.703125H91.71875V21.803125H0V22.703125z" fill="#f00"/>`)
 }
+
+func TestRichTextLink(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("./test/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	rt := NewRichText()
+	rt.Add(face, "see ")
+	rt.AddLink(face, "link", Link{URI: "https://example.com/?a&b"})
+	text := rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines[0].spans), 2)
+
+	buf := &bytes.Buffer{}
+	text.WriteSVG(buf, 0.0, Identity)
+	test.That(t, strings.Contains(buf.String(), `<a xlink:href="https://example.com/?a&amp;b"><tspan`), "link wraps span")
+	test.That(t, strings.HasSuffix(buf.String(), `>link</tspan></a></text>`), "link wraps span")
+
+	c := New(100, 20)
+	c.DrawText(0, 20, text)
+	pdfCompress = false
+	buf = &bytes.Buffer{}
+	test.Error(t, c.WritePDF(buf))
+	test.That(t, strings.Contains(buf.String(), "/URI (https://example.com/?a&b)"), "link annotation")
+
+	buf.Reset()
+	c.WriteEPS(buf)
+	test.That(t, strings.Contains(buf.String(), "/URI (https://example.com/?a&b) >> /Subtype /Link /ANN pdfmark"), "link pdfmark")
+
+	rt = NewRichText()
+	rt.AddLink(face, "page", Link{Page: 2})
+	buf.Reset()
+	rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0).WriteSVG(buf, 0.0, Identity)
+	test.That(t, strings.Contains(buf.String(), `<a xlink:href="#page2"><tspan`), "page link wraps span")
+}
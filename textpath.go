@@ -0,0 +1,270 @@
+package canvas
+
+import (
+	"bytes"
+	"fmt"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// DrawTextOnPath draws text along the path using the current affine transformation matrix, such that the baseline of the text follows the path. Each glyph is rotated to the direction of the path at its center. The text is placed at distance offset along the path and is aligned to it by align, which is Left, Center or Right, and lines that follow the first are placed parallel to the path. Glyphs whose center falls outside of the path are not drawn.
+func (c *Canvas) DrawTextOnPath(path *Path, text *Text, offset float64, align TextAlign) {
+	if text.Empty() || path.Empty() {
+		return
+	}
+	for font := range text.fonts {
+		c.fonts[font] = true
+	}
+	c.layers = append(c.layers, newTextPathLayer(path, text, offset, align, c.m, c.clips))
+}
+
+////////////////////////////////////////////////////////////////
+
+// textPathGlyph is a glyph placed along the path, m maps the glyph from its origin on the baseline to path coordinates.
+type textPathGlyph struct {
+	ff FontFace
+	r  string
+	m  Matrix
+}
+
+type textPathLayer struct {
+	path   *Path
+	text   *Text
+	starts []float64 // distance along the path of the start of each line
+	glyphs []textPathGlyph
+	decos  []pathLayer // in path coordinates
+	m      Matrix
+	clips  []clipPath
+}
+
+func newTextPathLayer(path *Path, text *Text, offset float64, align TextAlign, m Matrix, clips []clipPath) textPathLayer {
+	segs := path.arcSegments()
+	length := 0.0
+	for _, seg := range segs {
+		length += seg.length
+	}
+
+	l := textPathLayer{path: path, text: text, m: m, clips: clips}
+	for _, line := range text.lines {
+		if len(line.spans) == 0 {
+			l.starts = append(l.starts, offset)
+			continue
+		}
+		x0 := line.spans[0].dx
+		x1 := line.spans[len(line.spans)-1].dx + line.spans[len(line.spans)-1].width
+		start := offset
+		if align == Center {
+			start -= (x1 - x0) / 2.0
+		} else if align == Right {
+			start -= x1 - x0
+		}
+		l.starts = append(l.starts, start)
+		if len(segs) == 0 {
+			continue
+		}
+
+		for _, span := range line.spans {
+			k := 0
+			advances := span.advances()
+			x := start + span.dx - x0
+			for _, r := range span.text {
+				w := span.ff.TextWidth(string(r))
+				if mid := x + w/2.0; !isWhitespace(r) && 0.0 <= mid && mid <= length {
+					pos, tangent := textPathPos(segs, length, mid)
+					gm := Identity.Translate(pos.X, pos.Y).Rotate(tangent.Angle()*180.0/math.Pi).Translate(-w/2.0, line.y)
+					l.glyphs = append(l.glyphs, textPathGlyph{span.ff, string(r), gm})
+				}
+				x += advances[k]
+				k++
+			}
+		}
+		for _, deco := range line.decos {
+			p := deco.ff.Decorate(deco.x1-deco.x0).Translate(start+deco.x0-x0, line.y+deco.ff.voffset)
+			p = warpTextPath(p, segs, length, deco.ff.Metrics().XHeight/4.0)
+			l.decos = append(l.decos, pathLayer{p, drawState{fillColor: deco.ff.color}})
+		}
+	}
+	return l
+}
+
+// textPathPos returns the position and the unit tangent at distance d along the path. Beyond the ends of the path it extends the path along the tangent at its ends.
+func textPathPos(segs []arcSegment, length, d float64) (Point, Point) {
+	seg, t := arcSegmentAt(segs, d)
+	pos, tangent := seg.pos(t), seg.direction(t)
+	if d < 0.0 {
+		pos = pos.Add(tangent.Mul(d))
+	} else if length < d {
+		pos = pos.Add(tangent.Mul(d - length))
+	}
+	return pos, tangent
+}
+
+// warpTextPath maps a path from text coordinates, where x is the distance along the path and y the distance perpendicular to it, to path coordinates. The path is flattened and its lines are subdivided to be at most maxLength long so that they follow the curvature of the path.
+func warpTextPath(p *Path, segs []arcSegment, length, maxLength float64) *Path {
+	warp := func(q Point) Point {
+		pos, tangent := textPathPos(segs, length, q.X)
+		return pos.Add(tangent.Rot90CCW().Mul(q.Y))
+	}
+
+	p = p.Flatten()
+	r := &Path{}
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		end := Point{p.d[i-2], p.d[i-1]}
+		if cmd == moveToCmd {
+			q := warp(end)
+			r.MoveTo(q.X, q.Y)
+		} else {
+			n := math.Max(1.0, math.Ceil(end.Sub(start).Length()/maxLength))
+			for k := 1.0; k < n; k++ {
+				q := warp(start.Interpolate(end, k/n))
+				r.LineTo(q.X, q.Y)
+			}
+			if cmd == closeCmd {
+				r.Close()
+			} else {
+				q := warp(end)
+				r.LineTo(q.X, q.Y)
+			}
+		}
+		start = end
+	}
+	return r
+}
+
+// paths returns the glyphs and decorations as filled path layers in canvas coordinates.
+func (l textPathLayer) paths() []pathLayer {
+	layers := []pathLayer{}
+	for _, glyph := range l.glyphs {
+		p, _ := glyph.ff.ToPath(glyph.r)
+		state := defaultDrawState
+		state.fillColor = glyph.ff.color
+		layers = append(layers, pathLayer{p.Transform(l.m.Mul(glyph.m)), state})
+	}
+	for _, deco := range l.decos {
+		state := defaultDrawState
+		state.fillColor = deco.fillColor
+		layers = append(layers, pathLayer{deco.path.Transform(l.m), state})
+	}
+	return layers
+}
+
+func (l textPathLayer) Bounds() Rect {
+	r := Rect{}
+	for i, layer := range l.paths() {
+		if i == 0 {
+			r = layer.Bounds()
+		} else {
+			r = r.Add(layer.Bounds())
+		}
+	}
+	return r
+}
+
+func (l textPathLayer) clipPaths() []clipPath {
+	return l.clips
+}
+
+// svgNative is true if the text can be written as a textPath element in SVG, which is the case for a single line of text without vertical offsets or sentence spacing.
+func (l textPathLayer) svgNative() bool {
+	if len(l.text.lines) != 1 || l.text.lines[0].y != 0.0 {
+		return false
+	}
+	for _, span := range l.text.lines[0].spans {
+		if span.ff.voffset != 0.0 || span.sentenceSpacing != 0.0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (l textPathLayer) WriteSVG(w *svgWriter) {
+	if !l.svgNative() {
+		for _, layer := range l.paths() {
+			layer.WriteSVG(w)
+		}
+		return
+	}
+
+	// text is written with the y-axis pointing down
+	m := Identity.Translate(0.0, w.height).ReflectY().Mul(l.m).ReflectY()
+	p := l.path.Transform(Identity.ReflectY())
+	if m.IsTranslation() {
+		p = p.Transform(m)
+	}
+	id := w.newID("t")
+	fmt.Fprintf(w, `<defs><path id="%s" d="%s"/></defs><text`, id, p.ToSVG())
+	if !m.IsTranslation() {
+		fmt.Fprintf(w, ` transform="matrix(%v,%v,%v,%v,%v,%v)"`, dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	}
+	ffMain := l.text.mostCommonFontFace()
+	writeSVGMainFontStyle(w, ffMain)
+	fmt.Fprintf(w, `><textPath xlink:href="#%s"`, id)
+	if l.starts[0] != 0.0 {
+		fmt.Fprintf(w, ` startOffset="%v"`, num(l.starts[0]))
+	}
+	fmt.Fprintf(w, `>`)
+	for _, span := range l.text.lines[0].spans {
+		// attributes are written as `" name="value` by writeSVGFontStyle
+		attrs := &bytes.Buffer{}
+		if span.wordSpacing > 0.0 {
+			fmt.Fprintf(attrs, `" word-spacing="%v`, num(span.wordSpacing))
+		}
+		if span.glyphSpacing > 0.0 {
+			fmt.Fprintf(attrs, `" letter-spacing="%v`, num(span.glyphSpacing))
+		}
+		l.text.writeSVGFontStyle(attrs, span.ff, ffMain)
+
+		s := strings.ReplaceAll(span.text, `"`, `&quot;`)
+		if attrs.Len() == 0 {
+			fmt.Fprintf(w, `%s`, s)
+		} else {
+			fmt.Fprintf(w, `<tspan%s">%s</tspan>`, attrs.String()[1:], s)
+		}
+	}
+	fmt.Fprintf(w, `</textPath></text>`)
+	for _, deco := range l.decos {
+		pathLayer{deco.path.Transform(l.m), deco.drawState}.WriteSVG(w)
+	}
+}
+
+func (l textPathLayer) WritePDF(w *pdfPageWriter) {
+	if len(l.glyphs) != 0 {
+		fmt.Fprintf(w, ` BT`)
+		for _, glyph := range l.glyphs {
+			ff := glyph.ff
+			w.SetFillColor(ff.color)
+			w.SetFont(ff.font, ff.size*ff.scale)
+			w.SetTextPosition(l.m.Mul(glyph.m).Translate(0.0, ff.voffset).Shear(ff.fauxItalic, 0.0))
+			w.SetTextCharSpace(0.0)
+
+			if 0.0 < ff.fauxBold {
+				w.SetTextRenderMode(2)
+				fmt.Fprintf(w, " %v w", dec(ff.fauxBold*2.0))
+			} else {
+				w.SetTextRenderMode(0)
+			}
+			w.WriteText(glyph.r)
+		}
+		fmt.Fprintf(w, ` ET`)
+	}
+	for _, deco := range l.decos {
+		pathLayer{deco.path.Transform(l.m), deco.drawState}.WritePDF(w)
+	}
+}
+
+// WriteEPS writes the text as paths.
+func (l textPathLayer) WriteEPS(w *epsWriter) {
+	for _, layer := range l.paths() {
+		layer.WriteEPS(w)
+	}
+}
+
+func (l textPathLayer) WriteImage(img draw.Image, m Matrix) {
+	for _, layer := range l.paths() {
+		layer.WriteImage(img, m)
+	}
+}
@@ -0,0 +1,71 @@
+package canvas
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestDrawTextOnPath(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("./test/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontUnderline)
+	text := NewTextLine(face, "a b", Left)
+	width := text.lines[0].spans[0].width
+
+	c := New(100, 100)
+	c.DrawTextOnPath(MustParseSVG("M0 10H100"), text, 10.0, Left)
+	l := c.layers[0].(textPathLayer)
+	test.T(t, len(l.glyphs), 2) // spaces are skipped
+	test.T(t, l.glyphs[0].m, Identity.Translate(10.0, 10.0))
+	test.T(t, l.glyphs[1].m, Identity.Translate(10.0+width-face.TextWidth("b"), 10.0))
+	test.T(t, len(l.decos), 1)
+	test.T(t, l.decos[0].path.Bounds().W, width)
+
+	// the second glyph is placed on the vertical part of the path and its decoration bends around the corner
+	c = New(100, 100)
+	wb := face.TextWidth("b")
+	c.DrawTextOnPath(MustParseSVG("M0 0H10V100"), text, 10.0+wb, Right)
+	l = c.layers[0].(textPathLayer)
+	test.Float(t, l.starts[0], 10.0+wb-width)
+	test.T(t, l.glyphs[1].m, Identity.Translate(10.0, wb/2.0).Rotate(90.0).Translate(-wb/2.0, 0.0))
+	test.That(t, 1.0 < l.decos[0].path.Bounds().H, "decoration follows the path")
+
+	// glyphs beyond the path are dropped
+	c = New(100, 100)
+	c.DrawTextOnPath(MustParseSVG("M0 0H10"), text, 0.0, Left)
+	test.T(t, len(c.layers[0].(textPathLayer).glyphs), 1)
+}
+
+func TestTextOnPathWrite(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("./test/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	c := New(100, 100)
+	c.DrawTextOnPath(MustParseSVG("M0 50H100"), NewTextLine(face, "text", Left), 10.0, Center)
+	buf := &bytes.Buffer{}
+	c.WriteSVG(buf)
+	test.That(t, strings.Contains(buf.String(), `<defs><path id="t0" d="M0 50H100"/></defs><text style="font: 12px dejavu-serif"><textPath xlink:href="#t0" startOffset="`), "textPath element")
+	test.That(t, strings.Contains(buf.String(), `">text</textPath></text>`), "text in textPath")
+
+	// multiple lines are written as paths
+	c = New(100, 100)
+	c.DrawTextOnPath(MustParseSVG("M0 50H100"), NewTextLine(face, "text\nline", Left), 10.0, Left)
+	buf.Reset()
+	c.WriteSVG(buf)
+	test.That(t, !strings.Contains(buf.String(), `<textPath`), "no textPath element")
+	test.T(t, strings.Count(buf.String(), `<path`), 8)
+
+	pdfCompress = false
+	buf.Reset()
+	test.Error(t, c.WritePDF(buf))
+	test.That(t, strings.Contains(buf.String(), "BT /F0 12 Tf 10 50 Td"), "PDF text")
+	test.T(t, strings.Count(buf.String(), "]TJ"), 8)
+	pdfCompress = true
+
+	img := c.WriteImage(1.0)
+	test.That(t, img.RGBAAt(12, 48).A != 0 || img.RGBAAt(13, 48).A != 0, "glyph rasterized")
+}